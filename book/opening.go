@@ -3,6 +3,8 @@ package book
 import (
 	"errors"
 	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position"
+	"math/rand"
 )
 
 // Opening is an  opening to a chess game.
@@ -20,8 +22,45 @@ func Apply(opening Opening, g *game.Game) error {
 	return nil
 }
 
-// RandomOpening picks an opening from the book at random.
+// RandomOpening picks an opening from the book at random. Starting from the
+// initial position it follows book moves, chosen proportionally to their
+// weight, for up to 'halfmoves' plies. The opening is cut short when the
+// book runs out of moves for the position reached.
 func (b *Book) RandomOpening(halfmoves int) (Opening, error) {
+	var opening Opening
+	p := position.New()
+	for len(opening) < halfmoves {
+		entries := b.Positions[p.Polyglot()]
+		if len(entries) == 0 {
+			break
+		}
+		entry := pickWeighted(entries)
+		if _, legal := p.LegalMoves()[entry.Move]; !legal {
+			return opening, errors.New("book move " + entry.Move.String() + " is illegal")
+		}
+		opening = append(opening, entry)
+		p = p.MakeMove(entry.Move)
+	}
+	if len(opening) == 0 {
+		return nil, errors.New("book has no moves for the starting position")
+	}
+	return opening, nil
+}
 
-	return Opening{}, nil
+func pickWeighted(entries []Entry) Entry {
+	var total int
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return entries[rand.Intn(len(entries))]
+	}
+	n := rand.Intn(total)
+	for _, e := range entries {
+		n -= int(e.Weight)
+		if n < 0 {
+			return e
+		}
+	}
+	return entries[len(entries)-1]
 }
@@ -0,0 +1,24 @@
+package book
+
+import (
+	"github.com/andrewbackes/chess/pgn"
+	"strings"
+	"testing"
+)
+
+func TestRandomOpening(t *testing.T) {
+	input := `[Event "one"]
+[Result "1-0"]
+
+1. e2e4 e7e5 2. d1h5 e8e7 3. h5e5 1-0
+`
+	pgns, _ := pgn.Read(strings.NewReader(input))
+	book, _ := FromPGN(pgns, 4)
+	opening, err := book.RandomOpening(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opening) != 4 || opening[3].Move.String() != "e8e7" {
+		t.Error(opening)
+	}
+}
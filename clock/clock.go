@@ -0,0 +1,235 @@
+// Package clock is a chess clock. It supports time controls of several
+// stages with Fischer increments, Bronstein or simple delays and hourglass
+// timing, and runs against a Source that can be faked in tests.
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andrewbackes/chess/piece"
+)
+
+var (
+	// ErrFlagFell is returned when pressing the clock of a player who ran
+	// out of time.
+	ErrFlagFell = errors.New("clock: flag fell")
+	// ErrNotStarted is returned when pressing a clock that was never
+	// started.
+	ErrNotStarted = errors.New("clock: not started")
+)
+
+// FlagFall is sent when a player runs out of time.
+type FlagFall struct {
+	Player piece.Color
+	At     time.Time
+}
+
+// Clock keeps the time of both players. It is safe to use from several
+// goroutines.
+type Clock struct {
+	mu       sync.Mutex
+	source   Source
+	controls [2]Control
+	stage    [2]int // the stage each player is in
+	moves    [2]int // the moves each player made in the stage
+	left     [2]time.Duration
+	flagged  [2]bool
+	flags    chan FlagFall
+	// turn is the player whose move is being timed, or piece.Neither
+	// before the clock is started.
+	turn    piece.Color
+	running bool
+	since   time.Time     // when the clock was last started
+	spent   time.Duration // time spent on the move before the clock was last started
+	timer   Timer
+	gen     int // tells the flag timer whether it is still the current one
+}
+
+// New returns a stopped clock for the controls. A player with an empty
+// control is not timed.
+func New(source Source, white, black Control) *Clock {
+	c := &Clock{
+		source:   source,
+		controls: [2]Control{white, black},
+		flags:    make(chan FlagFall, 2),
+		turn:     piece.Neither,
+	}
+	for p, control := range c.controls {
+		if len(control) > 0 {
+			c.left[p] = control[0].Time
+		}
+	}
+	return c
+}
+
+// Flags returns the channel flag falls are sent on. It receives at most one
+// event per player.
+func (c *Clock) Flags() <-chan FlagFall {
+	return c.flags
+}
+
+// Start starts timing the player's move. It is used to begin the game and
+// to resume after Stop.
+func (c *Clock) Start(player piece.Color) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		c.pause(c.source.Now())
+	}
+	if player != c.turn {
+		c.turn, c.spent = player, 0
+	}
+	c.start(c.source.Now())
+}
+
+// Stop pauses the clock. The move being timed continues when the clock is
+// started again for the same player.
+func (c *Clock) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		c.pause(c.source.Now())
+	}
+}
+
+// Press ends the move being timed and starts timing the opponent. It returns
+// how long the move took.
+func (c *Clock) Press() (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.turn == piece.Neither {
+		return 0, ErrNotStarted
+	}
+	now := c.source.Now()
+	if c.running {
+		c.pause(now)
+	}
+	player, used := c.turn, c.spent
+	if c.flagged[player] {
+		return used, ErrFlagFell
+	}
+	control := c.controls[player]
+	if len(control) > 0 {
+		stage := control[c.stage[player]]
+		c.left[player] -= charge(stage, used)
+		if c.left[player] < 0 {
+			c.flag(player, now)
+			return used, ErrFlagFell
+		}
+		switch stage.Method {
+		case Fischer:
+			c.left[player] += stage.Increment
+		case Bronstein:
+			c.left[player] += min(used, stage.Increment)
+		case Hourglass:
+			c.left[1-player] += used
+		}
+		c.moves[player]++
+		if stage.Moves > 0 && c.moves[player] >= stage.Moves {
+			c.stage[player], c.moves[player] = control.next(c.stage[player]), 0
+			c.left[player] += control[c.stage[player]].Time
+		}
+	}
+	c.turn, c.spent = 1-player, 0
+	c.start(now)
+	return used, nil
+}
+
+// Remaining returns the time the player has left.
+func (c *Clock) Remaining(player piece.Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.controls[player]) == 0 {
+		return 0
+	}
+	left := c.left[player]
+	if player == c.turn && !c.flagged[player] {
+		left -= charge(c.controls[player][c.stage[player]], c.elapsed(c.source.Now()))
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Running returns whose clock is running, or piece.Neither if it is stopped.
+func (c *Clock) Running() piece.Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return piece.Neither
+	}
+	return c.turn
+}
+
+// Flagged returns whether the player ran out of time.
+func (c *Clock) Flagged(player piece.Color) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flagged[player]
+}
+
+// elapsed returns the time spent on the move being timed.
+func (c *Clock) elapsed(now time.Time) time.Duration {
+	if !c.running {
+		return c.spent
+	}
+	return c.spent + now.Sub(c.since)
+}
+
+func (c *Clock) start(now time.Time) {
+	c.running, c.since = true, now
+	c.gen++
+	player, gen := c.turn, c.gen
+	if len(c.controls[player]) == 0 || c.flagged[player] {
+		return
+	}
+	// The flag falls once the time charged for the move uses up what is left:
+	until := c.left[player] - c.spent
+	if stage := c.controls[player][c.stage[player]]; stage.Method == SimpleDelay {
+		until += stage.Increment
+	}
+	c.timer = c.source.AfterFunc(until, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.running && c.gen == gen && !c.flagged[player] {
+			now := c.source.Now()
+			c.pause(now)
+			c.flag(player, now)
+		}
+	})
+}
+
+func (c *Clock) pause(now time.Time) {
+	c.spent, c.running = c.elapsed(now), false
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// flag marks the player as out of time and sends the event.
+func (c *Clock) flag(player piece.Color, now time.Time) {
+	c.flagged[player], c.left[player] = true, 0
+	c.flags <- FlagFall{Player: player, At: now}
+}
+
+// charge returns how much a move that took the given time costs the player.
+func charge(stage Stage, used time.Duration) time.Duration {
+	if stage.Method == SimpleDelay {
+		used -= stage.Increment
+		if used < 0 {
+			return 0
+		}
+	}
+	return used
+}
+
+func min(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
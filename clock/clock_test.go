@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+)
+
+// moves lets the players alternate, each taking the given time per move.
+func moves(t *testing.T, f *Fake, c *Clock, n int, white, black time.Duration) {
+	for i := 0; i < n; i++ {
+		for _, d := range []time.Duration{white, black} {
+			f.Advance(d)
+			if _, err := c.Press(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMultiStage(t *testing.T) {
+	// 40 moves in 90 minutes, then 30 minutes for the rest, with 30 seconds
+	// added per move throughout:
+	control := Control{
+		{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second},
+		{Time: 30 * time.Minute, Increment: 30 * time.Second},
+	}
+	f := NewFake(time.Time{})
+	c := New(f, control, control)
+	c.Start(piece.White)
+	moves(t, f, c, 39, 2*time.Minute, time.Minute)
+	if left := c.Remaining(piece.White); left != 90*time.Minute-39*90*time.Second {
+		t.Error("white has", left)
+	}
+	moves(t, f, c, 1, 2*time.Minute, time.Minute)
+	if left := c.Remaining(piece.White); left != 30*time.Minute+30*time.Minute {
+		t.Error("white should have been given the second stage, has", left)
+	}
+	if left := c.Remaining(piece.Black); left != 90*time.Minute-40*30*time.Second+30*time.Minute {
+		t.Error("black has", left)
+	}
+}
+
+func TestRepeatingStage(t *testing.T) {
+	c := New(NewFake(time.Time{}), FromTimeControl(game.NewTimeControl(time.Minute, 2, 0, true)), nil)
+	c.Start(piece.White)
+	for i := 0; i < 4; i++ {
+		c.Press()
+	}
+	if left := c.Remaining(piece.White); left != 2*time.Minute {
+		t.Error("white has", left)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		method       Method
+		white, black time.Duration
+	}{
+		{Fischer, 57 * time.Second, 60 * time.Second},
+		{Bronstein, 57 * time.Second, 60 * time.Second},
+		{SimpleDelay, 57 * time.Second, 60 * time.Second},
+		{Hourglass, 52 * time.Second, 68 * time.Second},
+	}
+	for _, test := range tests {
+		control := Control{{Time: time.Minute, Increment: 5 * time.Second, Method: test.method}}
+		f := NewFake(time.Time{})
+		c := New(f, control, control)
+		c.Start(piece.White)
+		f.Advance(8 * time.Second)
+		c.Press()
+		if white, black := c.Remaining(piece.White), c.Remaining(piece.Black); white != test.white || black != test.black {
+			t.Error(test.method, "got", white, black, "wanted", test.white, test.black)
+		}
+	}
+}
+
+func TestSimpleDelayRunning(t *testing.T) {
+	control := Control{{Time: time.Minute, Increment: 5 * time.Second, Method: SimpleDelay}}
+	f := NewFake(time.Time{})
+	c := New(f, control, control)
+	c.Start(piece.White)
+	f.Advance(3 * time.Second)
+	if left := c.Remaining(piece.White); left != time.Minute {
+		t.Error("the delay should not count, has", left)
+	}
+	f.Advance(62 * time.Second)
+	if !c.Flagged(piece.White) {
+		t.Error("flag should fall once the delay and the time are used up")
+	}
+}
+
+func TestFlagFall(t *testing.T) {
+	control := Control{{Time: time.Minute}}
+	f := NewFake(time.Time{})
+	c := New(f, control, control)
+	c.Start(piece.White)
+	f.Advance(30 * time.Second)
+	c.Stop()
+	f.Advance(time.Hour)
+	if c.Flagged(piece.White) || c.Running() != piece.Neither {
+		t.Fatal("a stopped clock should not run")
+	}
+	c.Start(piece.White)
+	f.Advance(31 * time.Second)
+	select {
+	case flag := <-c.Flags():
+		if flag.Player != piece.White || !flag.At.Equal(time.Time{}.Add(time.Hour+time.Minute)) {
+			t.Error("got", flag)
+		}
+	default:
+		t.Fatal("no flag fall")
+	}
+	if _, err := c.Press(); err != ErrFlagFell {
+		t.Error("pressing after the flag fell should fail, got", err)
+	}
+}
+
+func TestRealTime(t *testing.T) {
+	c := New(RealTime(), Control{{Time: 10 * time.Millisecond}}, nil)
+	c.Start(piece.White)
+	select {
+	case flag := <-c.Flags():
+		if flag.Player != piece.White {
+			t.Error("got", flag)
+		}
+	case <-time.After(time.Second):
+		t.Error("no flag fall")
+	}
+}
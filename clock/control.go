@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Method is how a stage gives players time back for their moves.
+type Method int
+
+const (
+	// Fischer adds the increment after every move.
+	Fischer Method = iota
+	// Bronstein gives back the time used for a move, up to the increment.
+	Bronstein
+	// SimpleDelay, also called US delay, waits for the increment before the
+	// clock starts counting down.
+	SimpleDelay
+	// Hourglass adds the time a player uses to the opponent's clock.
+	Hourglass
+)
+
+func (m Method) String() string {
+	return []string{"Fischer", "Bronstein", "Simple delay", "Hourglass"}[m]
+}
+
+// Stage is one period of a time control, like 40 moves in 90 minutes.
+type Stage struct {
+	// Moves is how many moves have to be made in the stage. Zero means the
+	// rest of the game.
+	Moves int
+	// Time is added to the clock when the stage starts.
+	Time time.Duration
+	// Increment is the increment or the delay, depending on the method.
+	Increment time.Duration
+	Method    Method
+}
+
+// Control is a time control made of stages played one after the other.
+// Once every stage was played, the last one starts over, unless it is for
+// the rest of the game.
+type Control []Stage
+
+// FromTimeControl returns the control of a game time control.
+func FromTimeControl(tc game.TimeControl) Control {
+	var c Control
+	for p := &tc; p != nil; p = p.Then {
+		stage := Stage{Moves: p.Moves, Time: p.Time, Increment: p.Increment}
+		if p.Hourglass {
+			stage.Method = Hourglass
+		}
+		c = append(c, stage)
+		if p.Then == nil && p.Moves > 0 && !p.Repeating {
+			// No more time is given after the last period:
+			c = append(c, Stage{Increment: p.Increment})
+		}
+	}
+	return c
+}
+
+// next returns the index of the stage that follows the one given.
+func (c Control) next(stage int) int {
+	if stage+1 < len(c) {
+		return stage + 1
+	}
+	return stage
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Source tells the time. It lets clocks run against something other than
+// the wall clock, like a Fake in tests.
+type Source interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has passed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled with a Source.
+type Timer interface {
+	// Stop cancels the call. It returns false if the call already happened
+	// or was stopped before.
+	Stop() bool
+}
+
+// RealTime returns the source backed by the system clock.
+func RealTime() Source {
+	return realTime{}
+}
+
+type realTime struct{}
+
+func (realTime) Now() time.Time {
+	return time.Now()
+}
+
+func (realTime) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Fake is a source whose time only moves when told to.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a fake source that starts at the given time.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// AfterFunc schedules f to be called when the fake time has moved on by d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{fake: f, at: f.now.Add(d), f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the fake time forward, calling the scheduled functions that
+// come due in the order they were scheduled for. Unlike real timers, the
+// functions are called before Advance returns.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].at.Before(f.timers[j].at) })
+		if len(f.timers) == 0 || f.timers[0].at.After(end) {
+			break
+		}
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		if t.at.After(f.now) {
+			f.now = t.at
+		}
+		f.mu.Unlock()
+		t.f()
+		f.mu.Lock()
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+type fakeTimer struct {
+	fake *Fake
+	at   time.Time
+	f    func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	for i, timer := range t.fake.timers {
+		if timer == t {
+			t.fake.timers = append(t.fake.timers[:i], t.fake.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package diag
 
 import (
+	"github.com/andrewbackes/chess/fen"
 	"github.com/andrewbackes/chess/piece"
 	"github.com/andrewbackes/chess/position"
 	"github.com/andrewbackes/chess/position/move"
@@ -32,3 +33,24 @@ func TestDivideOutput(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// TestPerftPromotions runs even in short mode, since promotions put pieces
+// of new types on the board that the legality check has to take back.
+func TestPerftPromotions(t *testing.T) {
+	tests := map[string][]uint64{
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1": {6, 264, 9467},
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8":        {44, 1486, 62379},
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1":                          {24, 496, 9483},
+	}
+	for f, nodes := range tests {
+		p, err := fen.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for d, want := range nodes {
+			if got := Perft(p, d+1); got != want {
+				t.Errorf("%s: perft %d is %d, want %d", f, d+1, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package diag
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/epd"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// TestSANRoundTrip checks that every legal move in the positions of the
+// perft suite is written in SAN that no other move shares and that parses
+// back to the move, as do its other notations. The full suite also checks the positions one move in.
+func TestSANRoundTrip(t *testing.T) {
+	f, err := os.Open("perftsuite.epd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tests, err := epd.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	depth := 0
+	if strings.ToLower(os.Getenv("TEST_FULL_PERFT_SUITE")) == "true" {
+		depth = 1
+	}
+	for _, test := range tests {
+		checkSAN(t, test.Position, depth)
+	}
+}
+
+func checkSAN(t *testing.T, p *position.Position, depth int) {
+	seen := make(map[string]bool)
+	for m := range p.LegalMoves() {
+		san := p.SAN(m)
+		if seen[san] {
+			t.Errorf("%s is written %s like another move in %v", m, san, p)
+		}
+		seen[san] = true
+		if parsed, err := p.ParseSAN(san); err != nil || parsed != m {
+			t.Errorf("%s written %s parsed strictly as %s: %v", m, san, parsed, err)
+		}
+		if parsed, err := p.ParseMove(san); err != nil || parsed != m {
+			t.Errorf("%s written %s parsed as %s: %v", m, san, parsed, err)
+		}
+		notations := []struct {
+			write func(move.Move) string
+			parse func(string) (move.Move, error)
+		}{{p.LAN, p.ParseLAN}, {p.ICCF, p.ParseICCF}, {p.Descriptive, p.ParseDescriptive}}
+		for _, n := range notations {
+			written := n.write(m)
+			if parsed, err := n.parse(written); err != nil || parsed != m {
+				t.Errorf("%s written %q parsed as %s: %v", m, written, parsed, err)
+			}
+		}
+		if depth > 0 {
+			checkSAN(t, p.MakeMove(m), depth-1)
+		}
+	}
+}
@@ -7,12 +7,20 @@ import (
 	"github.com/andrewbackes/chess/game"
 	"github.com/andrewbackes/chess/position"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 var (
+	// ErrTimedOut is returned when the engine does not answer in time.
 	ErrTimedOut = errors.New("timed out")
+	// ErrEngineCrashed is returned when the engine exits or stops
+	// communicating while a command is still waiting for its answer.
+	ErrEngineCrashed = errors.New("engine crashed")
 )
 
 // Engine is an interface for using different types of engines (UCI or WinBoard)
@@ -43,68 +51,176 @@ type SearchInfo struct {
 	Analysis []map[string]string
 }
 
+// MateScore is the centipawn score given to mating in zero moves. Mate in n
+// moves scores MateScore-n.
+const MateScore = 100000
+
+// Score returns the last exact score the engine reported, in centipawns from
+// the point of view of the player to move. The boolean is false if the
+// engine did not report one.
+func (si *SearchInfo) Score() (int, bool) {
+	for i := len(si.Analysis) - 1; i >= 0; i-- {
+		a := si.Analysis[i]
+		if _, bound := a["lowerbound"]; bound {
+			continue
+		}
+		if _, bound := a["upperbound"]; bound {
+			continue
+		}
+		words := strings.Fields(a["score"])
+		if len(words) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(words[1])
+		if err != nil {
+			continue
+		}
+		switch {
+		case words[0] == "cp":
+			return n, true
+		case words[0] == "mate" && n < 0:
+			return -MateScore - n, true
+		case words[0] == "mate":
+			return MateScore - n, true
+		}
+	}
+	return 0, false
+}
+
 // Exec executes the engine executable and wires up the input and output as Readers and Writers.
-func execEngine(enginePath string) (*bufio.Reader, *bufio.Writer, error) {
-	fullpath, _ := filepath.Abs(enginePath)
-	cmd := exec.Command(fullpath)
-	cmd.Dir, _ = filepath.Abs(filepath.Dir(enginePath))
+// Anything the engine writes to stderr is written to the provided writer.
+func execEngine(enginePath string, opts Options, stderr io.Writer) (*exec.Cmd, *bufio.Reader, *bufio.Writer, error) {
+	fullpath, err := lookPath(enginePath)
+	if err != nil {
+		return nil, nil, nil, errors.New("couldnt find " + enginePath + " - " + err.Error())
+	}
+	cmd := exec.Command(fullpath, opts.Args...)
+	cmd.Dir = opts.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = filepath.Dir(fullpath)
+	}
+	cmd.Env = opts.Env
+	cmd.Stderr = stderr
 
 	// Setup the pipes to communicate with the engine:
 	StdinPipe, errIn := cmd.StdinPipe()
 	if errIn != nil {
-		return nil, nil, errors.New("can not establish inward pipe")
+		return nil, nil, nil, errors.New("can not establish inward pipe")
 	}
 	StdoutPipe, errOut := cmd.StdoutPipe()
 	if errOut != nil {
-		return nil, nil, errors.New("can not establish outward pipe")
+		return nil, nil, nil, errors.New("can not establish outward pipe")
 	}
 	r, w := bufio.NewReader(StdoutPipe), bufio.NewWriter(StdinPipe)
 
 	if err := cmd.Start(); err != nil {
-		return nil, nil, errors.New("couldnt execute " + enginePath + " - " + err.Error())
+		return nil, nil, nil, errors.New("couldnt execute " + enginePath + " - " + err.Error())
 	}
+	return cmd, r, w, nil
+}
 
-	// Setup up for when the engine exits:
-	go func() {
-		cmd.Wait()
-		//TODO: add some confirmation that the engine has terminated correctly.
-	}()
-
-	return r, w, nil
+// lookPath finds the absolute path of the engine executable. Paths are
+// relative to the current directory and bare names that do not exist there
+// are searched for in PATH.
+func lookPath(enginePath string) (string, error) {
+	if !strings.ContainsRune(enginePath, filepath.Separator) {
+		if _, err := os.Stat(enginePath); os.IsNotExist(err) {
+			return exec.LookPath(enginePath)
+		}
+	}
+	return filepath.Abs(enginePath)
 }
 
-func pub(source chan []byte, dest *bufio.Writer, stop chan struct{}) {
+// pub writes each message from source to dest on its own line. It returns
+// when stop is closed or when writing to dest fails. When record is not nil
+// it is called with every message before it is written.
+func pub(source chan []byte, dest *bufio.Writer, stop chan struct{}, record func([]byte)) error {
 	for {
 		select {
 		case message := <-source:
-			dest.Write(message) // TODO: error handling
+			if record != nil {
+				record(message)
+			}
+			dest.Write(message)
 			dest.WriteByte('\n')
-			dest.Flush()
+			if err := dest.Flush(); err != nil {
+				return err
+			}
 		case <-stop:
-			return
+			return nil
 		}
 	}
 }
 
-func sub(source *bufio.Reader, dest chan []byte, stop chan struct{}) {
+// sub reads lines from source and sends them to dest without their line
+// endings. It returns when source is exhausted, when reading fails or when
+// stop is closed. Reaching the end of source is not considered an error.
+// When record is not nil it is called with every line that was read.
+func sub(source *bufio.Reader, dest chan []byte, stop chan struct{}, record func([]byte)) error {
 	for {
 		line, err := source.ReadBytes('\n')
+		if len(line) > 0 {
+			if len(line) >= 2 && line[len(line)-2] == '\r' {
+				line = line[:len(line)-2]
+			} else if line[len(line)-1] == '\n' {
+				line = line[:len(line)-1]
+			}
+			if record != nil {
+				record(line)
+			}
+			select {
+			case dest <- line:
+			case <-stop:
+				return nil
+			}
+		}
 		if err == io.EOF {
-			return
+			return nil
 		}
-		if len(line) >= 2 && line[len(line)-2] == '\r' {
-			dest <- line[:len(line)-2]
-		} else if len(line) >= 1 && line[len(line)-1] == '\n' {
-			dest <- line[:len(line)-1]
-		} else {
-			dest <- line
+		if err != nil {
+			return err
 		}
 		if should(stop) {
-			return
+			return nil
 		}
 	}
 }
 
+// lineLog is an io.Writer that keeps the most recent lines written to it.
+type lineLog struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string
+}
+
+func (l *lineLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	text := l.partial + string(p)
+	lines := strings.Split(text, "\n")
+	l.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		l.lines = append(l.lines, strings.TrimRight(line, "\r"))
+	}
+	if len(l.lines) > l.max {
+		l.lines = l.lines[len(l.lines)-l.max:]
+	}
+	return len(p), nil
+}
+
+// Lines returns a copy of the logged lines, including a trailing line that
+// has not been terminated yet.
+func (l *lineLog) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := append([]string(nil), l.lines...)
+	if l.partial != "" {
+		lines = append(lines, l.partial)
+	}
+	return lines
+}
+
 func IsUCI(filename string) bool {
 	return false
 }
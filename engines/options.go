@@ -0,0 +1,77 @@
+package engines
+
+import (
+	"strconv"
+	"time"
+)
+
+// Options control how an engine is started and talked to. The zero value runs
+// the executable without arguments, from the folder it is in, with the
+// current environment.
+type Options struct {
+	// Args are the command line arguments passed to the executable.
+	Args []string
+	// Env is the environment of the engine's process in "key=value" form.
+	// When nil the engine inherits the current environment.
+	Env []string
+	// Dir is the working directory of the engine's process. When empty the
+	// folder the executable is in is used.
+	Dir string
+	// Init are commands sent to the engine once it has identified itself,
+	// for example "setoption name Hash value 128".
+	Init []string
+	// QuitTimeout is how long the engine gets to exit after Close before
+	// it is killed. When zero a default of 3 seconds is used.
+	QuitTimeout time.Duration
+	// SearchTimeout is how long BestMove lets the engine search in a game
+	// without a clock before telling it to stop. If it has not answered
+	// QuitTimeout after that, ErrTimedOut is returned. When zero a default of
+	// 10 seconds is used.
+	SearchTimeout time.Duration
+	// Recorder, when set, is told about every line sent to and received
+	// from the engine.
+	Recorder Recorder
+}
+
+func (o Options) quitTimeout() time.Duration {
+	if o.QuitTimeout > 0 {
+		return o.QuitTimeout
+	}
+	return quitTimeout
+}
+
+func (o Options) searchTimeout() time.Duration {
+	if o.SearchTimeout > 0 {
+		return o.SearchTimeout
+	}
+	return searchTimeout
+}
+
+// UCIOption is an option a UCI engine announces after the "uci" command, and
+// that can be changed with "setoption".
+type UCIOption struct {
+	Name string
+	// Type is one of check, spin, combo, button or string.
+	Type    string
+	Default string
+	// Min and Max bound spin options.
+	Min, Max int
+	// Vars are the values a combo option can take.
+	Vars []string
+}
+
+// String returns the option the way an engine announces it, for example
+// "option name Hash type spin default 16 min 1 max 1024".
+func (o UCIOption) String() string {
+	s := "option name " + o.Name + " type " + o.Type
+	if o.Type != "button" {
+		s += " default " + o.Default
+	}
+	if o.Type == "spin" {
+		s += " min " + strconv.Itoa(o.Min) + " max " + strconv.Itoa(o.Max)
+	}
+	for _, v := range o.Vars {
+		s += " var " + v
+	}
+	return s
+}
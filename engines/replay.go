@@ -0,0 +1,144 @@
+package engines
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Replay is a fake engine that plays back a recorded transcript. Every line
+// written to it has to match the next line that was sent to the engine in the
+// transcript, after which it answers with the lines the engine sent back.
+// Once the transcript is exhausted the replay reports end of file, the same
+// way an engine that exited would.
+//
+// It is meant to be used with AttachUCIEngine so that code talking to engines
+// can be tested without engine executables:
+//
+//	e, err := AttachUCIEngine("replay", NewReplay(transcript), Options{})
+type Replay struct {
+	// Match decides if a line written to the replay is the line that was
+	// expected. When nil the lines have to be identical.
+	Match func(expected, got string) bool
+
+	mu      sync.Mutex
+	ready   *sync.Cond
+	lines   []Line
+	next    int
+	partial []byte
+	out     bytes.Buffer
+	closed  bool
+	err     error
+}
+
+// NewReplay makes a fake engine out of the transcript.
+func NewReplay(t *Transcript) *Replay {
+	r := &Replay{lines: t.Lines()}
+	r.ready = sync.NewCond(&r.mu)
+	r.answer()
+	return r
+}
+
+// answer queues up the engine's lines until it is waiting on input again.
+// The caller has to hold the lock.
+func (r *Replay) answer() {
+	for r.next < len(r.lines) && r.lines[r.next].Direction == FromEngine {
+		r.out.WriteString(r.lines[r.next].Text + "\n")
+		r.next++
+	}
+	r.ready.Broadcast()
+}
+
+// exhausted returns whether all of the transcript has been played. The caller
+// has to hold the lock.
+func (r *Replay) exhausted() bool {
+	return r.next >= len(r.lines)
+}
+
+// Read returns what the engine in the transcript answered.
+func (r *Replay) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.out.Len() == 0 {
+		if r.closed || r.err != nil || r.exhausted() {
+			return 0, io.EOF
+		}
+		r.ready.Wait()
+	}
+	return r.out.Read(p)
+}
+
+// Write checks the commands sent to the engine against the transcript.
+func (r *Replay) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.partial = append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(r.partial, '\n')
+		if i < 0 {
+			break
+		}
+		got := strings.TrimRight(string(r.partial[:i]), "\r")
+		r.partial = r.partial[i+1:]
+		if err := r.expect(got); err != nil {
+			r.err = err
+			r.ready.Broadcast()
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// expect checks the line against the transcript. The caller has to hold the lock.
+func (r *Replay) expect(got string) error {
+	if r.exhausted() {
+		// An engine that already exited would not have heard it anyway.
+		if got == "quit" {
+			return nil
+		}
+		return fmt.Errorf("replay: got %q after the end of the transcript", got)
+	}
+	expected := r.lines[r.next].Text
+	match := r.Match
+	if match == nil {
+		match = func(expected, got string) bool { return expected == got }
+	}
+	if !match(expected, got) {
+		return fmt.Errorf("replay: got %q but the transcript has %q", got, expected)
+	}
+	r.next++
+	r.answer()
+	return nil
+}
+
+// Close stops the replay.
+func (r *Replay) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.ready.Broadcast()
+	r.mu.Unlock()
+	return nil
+}
+
+// Err returns the first mismatch between what was written to the replay and
+// the transcript, or nil if everything matched so far.
+func (r *Replay) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Done returns whether the whole transcript has been played back.
+func (r *Replay) Done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exhausted()
+}
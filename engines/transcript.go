@@ -0,0 +1,139 @@
+package engines
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Direction is which way a line traveled between the engine and us.
+type Direction uint8
+
+// Possible directions of a line.
+const (
+	ToEngine Direction = iota
+	FromEngine
+)
+
+func (d Direction) String() string {
+	if d == ToEngine {
+		return ">"
+	}
+	return "<"
+}
+
+// Line is a timestamped line of the protocol communication with an engine.
+type Line struct {
+	Time      time.Time
+	Direction Direction
+	Text      string
+}
+
+func (l Line) String() string {
+	return l.Time.Format(time.RFC3339Nano) + " " + l.Direction.String() + " " + l.Text
+}
+
+// Recorder is told about every line sent to or received from an engine. Lines
+// are recorded from more than one goroutine so implementations need to be safe
+// for concurrent use.
+type Recorder interface {
+	Record(Line)
+}
+
+// Transcript is a Recorder that keeps the lines in memory.
+type Transcript struct {
+	mu    sync.Mutex
+	lines []Line
+}
+
+// Record adds the line to the transcript.
+func (t *Transcript) Record(l Line) {
+	t.mu.Lock()
+	t.lines = append(t.lines, l)
+	t.mu.Unlock()
+}
+
+// Lines returns a copy of the lines recorded so far.
+func (t *Transcript) Lines() []Line {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Line(nil), t.lines...)
+}
+
+// WriteTo writes the transcript in the same format NewTranscriptWriter uses,
+// one line per line.
+func (t *Transcript) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, l := range t.Lines() {
+		n, err := io.WriteString(w, l.String()+"\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ReadTranscript loads a transcript that was written by WriteTo or by a
+// Recorder made with NewTranscriptWriter.
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		l, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("transcript line %d: %v", n, err)
+		}
+		t.lines = append(t.lines, l)
+	}
+	return t, scanner.Err()
+}
+
+func parseLine(s string) (Line, error) {
+	fields := strings.SplitN(s, " ", 3)
+	if len(fields) < 2 {
+		return Line{}, errors.New("missing direction")
+	}
+	ts, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return Line{}, err
+	}
+	l := Line{Time: ts}
+	switch fields[1] {
+	case ">":
+		l.Direction = ToEngine
+	case "<":
+		l.Direction = FromEngine
+	default:
+		return Line{}, errors.New("unknown direction " + fields[1])
+	}
+	if len(fields) == 3 {
+		l.Text = fields[2]
+	}
+	return l, nil
+}
+
+// transcriptWriter records lines by writing them out as they happen.
+type transcriptWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTranscriptWriter returns a Recorder that writes every line to w as soon
+// as it is sent or received, which is useful for logging to a file.
+func NewTranscriptWriter(w io.Writer) Recorder {
+	return &transcriptWriter{w: w}
+}
+
+func (t *transcriptWriter) Record(l Line) {
+	t.mu.Lock()
+	io.WriteString(t.w, l.String()+"\n")
+	t.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package engines
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	client, server := net.Pipe()
+	go pipeEngine(server)
+	recorded := &Transcript{}
+	e, err := AttachUCIEngine("pipe", client, Options{Recorder: recorded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.BestMove(game.New(), nil)
+	e.Close()
+	var texts []string
+	for _, l := range recorded.Lines() {
+		texts = append(texts, l.Direction.String()+" "+l.Text)
+	}
+	expected := []string{
+		"> uci", "< id name pipe", "< uciok",
+		"> position startpos moves ", "> go",
+		"< info depth 1 score cp 20 pv e2e4", "< bestmove e2e4",
+		"> quit",
+	}
+	if strings.Join(texts, "\n") != strings.Join(expected, "\n") {
+		t.Error("got", texts, "wanted", expected)
+	}
+
+	var buf bytes.Buffer
+	recorded.WriteTo(&buf)
+	read, err := ReadTranscript(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Lines()) != len(recorded.Lines()) {
+		t.Fatal("got", len(read.Lines()), "lines but wanted", len(recorded.Lines()))
+	}
+	for i, l := range read.Lines() {
+		r := recorded.Lines()[i]
+		if !l.Time.Equal(r.Time) || l.Direction != r.Direction || l.Text != r.Text {
+			t.Error(l, "!=", r)
+		}
+	}
+}
+
+const replayTranscript = `2017-11-22T10:00:00Z > uci
+2017-11-22T10:00:00.1Z < id name replayed
+2017-11-22T10:00:00.1Z < uciok
+2017-11-22T10:00:01Z > position startpos moves 
+2017-11-22T10:00:01Z > go
+2017-11-22T10:00:02Z < info depth 8 score cp 31 pv d2d4 d7d5
+2017-11-22T10:00:02Z < bestmove d2d4 ponder d7d5
+2017-11-22T10:00:03Z > quit
+`
+
+func TestReplay(t *testing.T) {
+	transcript, err := ReadTranscript(strings.NewReader(replayTranscript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewReplay(transcript)
+	e, err := AttachUCIEngine("replay", replay, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := e.BestMove(game.New(), nil)
+	if err != nil || sr.BestMove != "d2d4" || sr.Ponder != "d7d5" || len(sr.Analysis) != 1 {
+		t.Error(sr, err)
+	}
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := replay.Err(); err != nil {
+		t.Error(err)
+	}
+	if !replay.Done() {
+		t.Error("transcript was not played to the end")
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	transcript, _ := ReadTranscript(strings.NewReader(replayTranscript))
+	replay := NewReplay(transcript)
+	e, err := AttachUCIEngine("replay", replay, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.NewGame(); err != ErrEngineCrashed {
+		t.Error("got", err, "wanted", ErrEngineCrashed)
+	}
+	if replay.Err() == nil {
+		t.Error("expected a mismatch")
+	}
+	e.Close()
+}
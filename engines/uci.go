@@ -7,8 +7,12 @@ import (
 	"github.com/andrewbackes/chess/piece"
 	"github.com/andrewbackes/chess/position"
 	"github.com/andrewbackes/chess/position/move"
+	"io"
+	"net"
+	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,43 +25,183 @@ type UCIEngine struct {
 	input        chan []byte
 	stop         chan struct{}
 	lastGameUsed *game.Game
+
+	// Lifecycle of the engine:
+	cmd         *exec.Cmd
+	closer      io.Closer
+	stderr      *lineLog
+	quitTimeout time.Duration
+	eof         chan struct{} // closed once the engine's output has been read to the end.
+	dead        chan struct{} // closed once the engine can no longer be talked to.
+	done        chan struct{} // closed once the engine has exited.
+	lost        sync.Once
+	lostErr     error
+	exitErr     error
+	closing     sync.Once
+	closeErr    error
+
+	// searchTimeout is how long a search of a game without a clock lasts.
+	searchTimeout time.Duration
 }
 
 const (
 	initTimeout    = 10 * time.Second
 	newGameTimeout = 1 * time.Second
+	// quitTimeout is how long an engine gets to exit after being told to
+	// quit before its process is killed, unless the options say otherwise.
+	quitTimeout = 3 * time.Second
+	// searchTimeout is how long an engine searches a game without a clock,
+	// unless the options say otherwise.
+	searchTimeout = 10 * time.Second
+	// stderrLines is how many lines of the engine's stderr are kept.
+	stderrLines = 1000
 )
 
 // NewUCIEngine execs an engine and allows interaction with the engine through its methods.
 func NewUCIEngine(filepath string) (*UCIEngine, error) {
-	r, w, err := execEngine(filepath)
+	return NewUCIEngineWithOptions(filepath, Options{})
+}
+
+// NewUCIEngineWithOptions execs an engine the same way NewUCIEngine does, but
+// with the arguments, environment, working directory and initial commands
+// given in the options.
+func NewUCIEngineWithOptions(filepath string, opts Options) (*UCIEngine, error) {
+	stderr := &lineLog{max: stderrLines}
+	cmd, r, w, err := execEngine(filepath, opts, stderr)
 	if err != nil {
 		return nil, err
 	}
-	e, err := newUCIEngine(filepath, r, w)
+	return newUCIEngine(filepath, connection{reader: r, writer: w, cmd: cmd, stderr: stderr}, opts)
+}
+
+// AttachUCIEngine talks UCI to an engine over an already established connection,
+// such as a TCP socket, the pipes of a process started elsewhere, or an engine
+// running in this process. The name is only used to identify the engine.
+// Closing the engine closes the connection.
+func AttachUCIEngine(name string, conn io.ReadWriteCloser, opts Options) (*UCIEngine, error) {
+	c := connection{
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+		closer: conn,
+	}
+	return newUCIEngine(name, c, opts)
+}
+
+// DialUCIEngine connects to an engine served over the network, for example
+// DialUCIEngine("tcp", "localhost:4000", Options{}).
+func DialUCIEngine(network, address string, opts Options) (*UCIEngine, error) {
+	conn, err := net.Dial(network, address)
 	if err != nil {
 		return nil, err
 	}
-	return e, nil
+	return AttachUCIEngine(address, conn, opts)
+}
+
+// connection is the way an engine is reached.
+type connection struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+	// cmd is the process the reader and writer belong to, if any.
+	cmd *exec.Cmd
+	// closer closes the connection, if it needs closing.
+	closer io.Closer
+	stderr *lineLog
 }
 
-func newUCIEngine(filepath string, reader *bufio.Reader, writer *bufio.Writer) (*UCIEngine, error) {
+func newUCIEngine(filepath string, conn connection, opts Options) (*UCIEngine, error) {
+	if conn.stderr == nil {
+		conn.stderr = &lineLog{max: stderrLines}
+	}
 	e := UCIEngine{
-		filepath: filepath,
-		output:   make(chan []byte, 1024),
-		input:    make(chan []byte, 1024),
-		stop:     make(chan struct{}),
-	}
-	e.reader, e.writer = reader, writer
-	go sub(reader, e.output, e.stop)
-	go pub(e.input, writer, e.stop)
-	err := e.initialize()
+		filepath:    filepath,
+		output:      make(chan []byte, 1024),
+		input:       make(chan []byte, 1024),
+		stop:        make(chan struct{}),
+		cmd:         conn.cmd,
+		closer:      conn.closer,
+		stderr:      conn.stderr,
+		quitTimeout: opts.quitTimeout(),
+		eof:         make(chan struct{}),
+		dead:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	e.searchTimeout = opts.searchTimeout()
+	e.reader, e.writer = conn.reader, conn.writer
+	stop := e.stop
+	var sent, received func([]byte)
+	if opts.Recorder != nil {
+		sent = recordAs(opts.Recorder, ToEngine)
+		received = recordAs(opts.Recorder, FromEngine)
+	}
+	go func() {
+		err := sub(e.reader, e.output, stop, received)
+		close(e.eof)
+		e.disconnect(err)
+	}()
+	go func() {
+		if err := pub(e.input, e.writer, stop, sent); err != nil {
+			e.disconnect(err)
+		}
+	}()
+	go e.wait()
+	err := e.initialize(opts.Init)
 	if err != nil {
+		e.Close()
 		return nil, err
 	}
 	return &e, nil
 }
 
+// recordAs returns a function that records lines going in the direction.
+func recordAs(r Recorder, d Direction) func([]byte) {
+	return func(line []byte) {
+		r.Record(Line{Time: time.Now(), Direction: d, Text: string(line)})
+	}
+}
+
+// disconnect marks the engine as no longer reachable. Only the first reason
+// given is kept.
+func (e *UCIEngine) disconnect(reason error) {
+	e.lost.Do(func() {
+		e.lostErr = reason
+		close(e.dead)
+	})
+}
+
+// wait reaps the engine's process once all of its output has been read.
+func (e *UCIEngine) wait() {
+	if e.cmd == nil {
+		<-e.dead
+		e.exitErr = e.lostErr
+	} else {
+		<-e.eof
+		e.exitErr = e.cmd.Wait()
+	}
+	close(e.done)
+}
+
+// Done returns a channel that is closed once the engine has exited.
+func (e *UCIEngine) Done() <-chan struct{} {
+	return e.done
+}
+
+// ExitStatus returns the error the engine exited with. It is nil while the
+// engine is still running or when it exited cleanly. For engines that were
+// started as a process the error is usually an *exec.ExitError.
+func (e *UCIEngine) ExitStatus() error {
+	select {
+	case <-e.done:
+		return e.exitErr
+	default:
+	}
+	return nil
+}
+
+// Stderr returns the most recent lines the engine wrote to its standard error.
+func (e *UCIEngine) Stderr() []string {
+	return e.stderr.Lines()
+}
+
 // should is a helper to determing if the channel is passing or not.
 func should(stop chan struct{}) bool {
 	select {
@@ -76,53 +220,120 @@ func (e *UCIEngine) resetStop() {
 	}
 }
 
-func (e *UCIEngine) initialize() error {
+func (e *UCIEngine) initialize(commands []string) error {
 	_, err := e.sendAndWait([]byte("uci"), "uciok", initTimeout, func([]byte) {})
-	return err
+	if err != nil || len(commands) == 0 {
+		return err
+	}
+	for _, command := range commands {
+		if err := e.send([]byte(command)); err != nil {
+			return err
+		}
+	}
+	return e.isReady()
+}
+
+// send queues a command for the engine. It fails if the engine has gone away.
+func (e *UCIEngine) send(command []byte) error {
+	select {
+	case e.input <- command:
+		return nil
+	case <-e.dead:
+		return ErrEngineCrashed
+	}
 }
 
+// sendAndWait sends a command and passes every line the engine outputs to parse
+// until a line starting with expected is seen. A timeout of zero waits forever.
 func (e *UCIEngine) sendAndWait(send []byte, expected string, timeout time.Duration, parse func([]byte)) (time.Duration, error) {
-	e.input <- send
 	start := time.Now()
+	// Even if the engine has gone away, its answer may already have been read.
+	e.send(send)
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	found := func(line []byte) bool {
+		parse(line)
+		return len(line) >= len(expected) && string(line[:len(expected)]) == expected
+	}
 	for {
 		select {
 		case line := <-e.output:
-			parse(line)
-			if len(line) >= len(expected) && string(line[:len(expected)]) == expected {
+			if found(line) {
 				return time.Since(start), nil
 			}
 		case <-e.stop:
 			return time.Since(start), nil
-		case <-time.After(timeout):
+		case <-e.dead:
+			// Whatever the engine said before going away is still worth reading:
+			for {
+				select {
+				case line := <-e.output:
+					if found(line) {
+						return time.Since(start), nil
+					}
+				default:
+					return time.Since(start), ErrEngineCrashed
+				}
+			}
+		case <-expired:
 			return time.Since(start), ErrTimedOut
 		}
 	}
 }
 
-func (e *UCIEngine) isReady() bool {
+func (e *UCIEngine) isReady() error {
 	_, err := e.sendAndWait([]byte("isready"), "readyok", initTimeout, func([]byte) {})
-	return err == nil
+	return err
 }
 
-// Close shuts down the engine.
+// Close tells the engine to quit and waits for it to exit. If it has not exited
+// after a grace period its process is killed, or its connection closed, and
+// ErrTimedOut is returned.
 func (e *UCIEngine) Close() error {
-	e.input <- []byte("quit")
-	close(e.stop)
-	// TODO: need a way to kill the process if it doesnt close on its own.
-	return nil
+	e.closing.Do(func() {
+		e.send([]byte("quit"))
+		select {
+		case <-e.done:
+		case <-time.After(e.quitTimeout):
+			e.closeErr = ErrTimedOut
+			e.kill()
+			select {
+			case <-e.done:
+			case <-time.After(e.quitTimeout):
+			}
+		}
+		if e.closer != nil {
+			e.closer.Close()
+		}
+		close(e.stop)
+	})
+	return e.closeErr
+}
+
+// kill forcefully ends the engine.
+func (e *UCIEngine) kill() {
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	} else if e.closer != nil {
+		e.closer.Close()
+	}
 }
 
 // NewGame tells the engine that we will be passing positions and thinking on a new game.
 func (e *UCIEngine) NewGame() error {
-	e.input <- []byte("ucinewgame")
-	e.isReady()
-	return nil
+	if err := e.send([]byte("ucinewgame")); err != nil {
+		return err
+	}
+	return e.isReady()
 }
 
 // Stop sends a command to the engine to stop what it is doing.
 func (e *UCIEngine) Stop() error {
-	e.input <- []byte("stop\n")
-	return nil
+	return e.send([]byte("stop"))
 }
 
 // SetBoard sets the engines internal board to that of the games.
@@ -134,7 +345,7 @@ func (e *UCIEngine) SetBoard(g *game.Game) {
 	*/
 	pos := "startpos"
 	if g.Tags["FEN"] != "" && g.Tags["FEN"] != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1" {
-		pos = g.Tags["FEN"]
+		pos = "fen " + g.Tags["FEN"]
 	}
 	moves := ""
 	if len(g.Positions) > 0 {
@@ -146,7 +357,7 @@ func (e *UCIEngine) SetBoard(g *game.Game) {
 		}
 	}
 	command := "position " + pos + moves
-	e.input <- []byte(command)
+	e.send([]byte(command))
 }
 
 func (e *UCIEngine) setPosition(p *position.Position) error {
@@ -155,12 +366,12 @@ func (e *UCIEngine) setPosition(p *position.Position) error {
 		return err
 	}
 	command := "position fen " + f
-	e.input <- []byte(command)
-	return nil
+	return e.send([]byte(command))
 }
 
 // Think will do an infinite search on the provided position. It is non-blocking
-// and returns a buffered channel where the engine's output is streamed.
+// and returns a buffered channel where the engine's output is streamed. The
+// channel is closed once the search ends or the engine goes away.
 func (e *UCIEngine) Think(p *position.Position) (output chan string, err error) {
 	e.resetStop()
 	err = e.setPosition(p)
@@ -173,7 +384,10 @@ func (e *UCIEngine) Think(p *position.Position) (output chan string, err error)
 			output <- string(info)
 		}
 	}
-	go e.sendAndWait([]byte("go infinite"), "bestmove ", 8760*time.Hour, parse)
+	go func() {
+		e.sendAndWait([]byte("go infinite"), "bestmove ", 0, parse)
+		close(output)
+	}()
 	return
 }
 
@@ -190,16 +404,24 @@ func infoToMap(commands map[string]int, line []byte) map[string]string {
 }
 
 // BestMove tells the engine to return what it things is the best move for the current game.
+// If the engine exits before answering ErrEngineCrashed is returned, and if it takes
+// longer than its clock allows ErrTimedOut is returned. In a game without a clock
+// the engine is told to stop after its search timeout, and ErrTimedOut is returned
+// if it still does not answer.
 func (e *UCIEngine) BestMove(g *game.Game, rawOutput chan []byte) (*SearchInfo, error) {
 	e.resetStop()
 	e.SetBoard(g)
 	command := "go"
 
 	s := []string{" wtime ", " btime "}
+	inc := []string{" winc ", " binc "}
 	for c := piece.White; c <= piece.Black; c++ {
 		if g.Clock(c) > 0 {
 			command += s[c] + roundToMilliseconds(g.Clock(c))
 		}
+		if i := g.Stage(c).Increment; i > 0 {
+			command += inc[c] + roundToMilliseconds(i)
+		}
 	}
 	m := g.MovesLeft(g.ActiveColor())
 	if m > 0 {
@@ -214,6 +436,14 @@ func (e *UCIEngine) BestMove(g *game.Game, rawOutput chan []byte) (*SearchInfo,
 		}
 		parseAnalysis(&si, commands, info)
 	}
+	if timeout == 0 {
+		// Without a clock the engine searches until it is told to stop:
+		_, err := e.sendAndWait([]byte(command), "bestmove ", e.searchTimeout, parse)
+		if err == ErrTimedOut {
+			_, err = e.sendAndWait([]byte("stop"), "bestmove ", e.quitTimeout, parse)
+		}
+		return &si, err
+	}
 	_, err := e.sendAndWait([]byte(command), "bestmove ", timeout, parse)
 	return &si, err
 }
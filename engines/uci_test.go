@@ -3,9 +3,14 @@ package engines
 import (
 	"bufio"
 	"github.com/andrewbackes/chess/game"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSub(t *testing.T) {
@@ -13,7 +18,7 @@ func TestSub(t *testing.T) {
 	c := make(chan []byte, 10)
 	s := make(chan struct{})
 	// just make sure we dont block. should get eof and bail out.
-	sub(r, c, s)
+	sub(r, c, s, nil)
 }
 
 func readAll(c chan []byte) []string {
@@ -90,7 +95,7 @@ func TestUCIBestMove(t *testing.T) {
 	}
 	r := bufio.NewReader(strings.NewReader(strings.Join(output, "")))
 	w := bufio.NewWriter(&mockWriter{})
-	e, err := newUCIEngine("", r, w)
+	e, err := newUCIEngine("", connection{reader: r, writer: w}, Options{})
 	if err != nil {
 		t.Fail()
 	}
@@ -101,3 +106,229 @@ func TestUCIBestMove(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestUCIBestMoveCrashed(t *testing.T) {
+	output := []string{
+		"uciok\n",
+		"info depth 2 seldepth 5 score cp 100 lowerbound pv e2e4 d7d5\n",
+	}
+	r := bufio.NewReader(strings.NewReader(strings.Join(output, "")))
+	w := bufio.NewWriter(&mockWriter{})
+	e, err := newUCIEngine("", connection{reader: r, writer: w}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.BestMove(game.New(), nil)
+	if err != ErrEngineCrashed {
+		t.Error("got", err, "wanted", ErrEngineCrashed)
+	}
+	select {
+	case <-e.Done():
+	case <-time.After(time.Second):
+		t.Error("engine should be done")
+	}
+}
+
+// scriptEngine writes a shell script that acts as an engine.
+func scriptEngine(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	path := filepath.Join(t.TempDir(), "engine.sh")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUCIEngineExit(t *testing.T) {
+	path := scriptEngine(t, `read cmd
+echo uciok
+echo "something went wrong" >&2
+read cmd
+exit 3
+`)
+	e, err := NewUCIEngine(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.BestMove(game.New(), nil)
+	if err != ErrEngineCrashed {
+		t.Error("got", err, "wanted", ErrEngineCrashed)
+	}
+	<-e.Done()
+	if e.ExitStatus() == nil {
+		t.Error("expected a non-zero exit status")
+	}
+	if s := e.Stderr(); len(s) != 1 || s[0] != "something went wrong" {
+		t.Error("got stderr", s)
+	}
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUCIEngineKilledOnHang(t *testing.T) {
+	path := scriptEngine(t, `read cmd
+echo uciok
+while true; do read cmd; done
+`)
+	e, err := NewUCIEngineWithOptions(path, Options{QuitTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != ErrTimedOut {
+		t.Error("got", err, "wanted", ErrTimedOut)
+	}
+	select {
+	case <-e.Done():
+	default:
+		t.Error("engine should have been killed")
+	}
+}
+
+func TestUCIEngineHangsWithoutClock(t *testing.T) {
+	path := scriptEngine(t, `read cmd
+echo uciok
+while true; do read cmd; done
+`)
+	e, err := NewUCIEngineWithOptions(path, Options{QuitTimeout: 100 * time.Millisecond, SearchTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if _, err := e.BestMove(game.New(), nil); err != ErrTimedOut {
+		t.Error("got", err, "wanted", ErrTimedOut)
+	}
+}
+
+func TestUCIEngineStoppedWithoutClock(t *testing.T) {
+	path := scriptEngine(t, `read cmd
+echo uciok
+while read cmd; do
+	case "$cmd" in
+	stop) echo "bestmove e2e4";;
+	quit) exit;;
+	esac
+done
+`)
+	e, err := NewUCIEngineWithOptions(path, Options{SearchTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	info, err := e.BestMove(game.New(), nil)
+	if err != nil || info.BestMove != "e2e4" {
+		t.Error("got", info, err)
+	}
+}
+
+func TestUCIEngineOptions(t *testing.T) {
+	path := scriptEngine(t, `echo "$1 $ENGINE_TEST $(pwd)" >&2
+read cmd
+echo uciok
+read cmd
+echo "$cmd" >&2
+read cmd
+echo readyok
+read cmd
+`)
+	dir := t.TempDir()
+	opts := Options{
+		Args: []string{"arg"},
+		Env:  []string{"ENGINE_TEST=env"},
+		Dir:  dir,
+		Init: []string{"setoption name Hash value 16"},
+	}
+	e, err := NewUCIEngineWithOptions(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+	want := []string{"arg env " + dir, "setoption name Hash value 16"}
+	if got := e.Stderr(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Error("got", got, "wanted", want)
+	}
+}
+
+// pipeEngine answers like a UCI engine on the other end of a connection.
+func pipeEngine(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch strings.TrimSpace(line) {
+		case "uci":
+			conn.Write([]byte("id name pipe\nuciok\n"))
+		case "isready":
+			conn.Write([]byte("readyok\n"))
+		case "go":
+			conn.Write([]byte("info depth 1 score cp 20 pv e2e4\nbestmove e2e4\n"))
+		case "quit":
+			return
+		}
+	}
+}
+
+func TestAttachUCIEngine(t *testing.T) {
+	client, server := net.Pipe()
+	go pipeEngine(server)
+	e, err := AttachUCIEngine("pipe", client, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.NewGame(); err != nil {
+		t.Error(err)
+	}
+	sr, err := e.BestMove(game.New(), nil)
+	if err != nil || sr.BestMove != "e2e4" {
+		t.Error(sr, err)
+	}
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSearchInfoScore(t *testing.T) {
+	tests := []struct {
+		analysis []map[string]string
+		score    int
+		ok       bool
+	}{
+		{nil, 0, false},
+		{[]map[string]string{{"score": "cp 35"}}, 35, true},
+		{[]map[string]string{{"score": "cp 35"}, {"score": "cp 50", "lowerbound": ""}}, 35, true},
+		{[]map[string]string{{"score": "mate 3"}}, MateScore - 3, true},
+		{[]map[string]string{{"score": "mate -2"}}, -MateScore + 2, true},
+		{[]map[string]string{{"depth": "5"}}, 0, false},
+	}
+	for _, test := range tests {
+		si := SearchInfo{Analysis: test.analysis}
+		score, ok := si.Score()
+		if score != test.score || ok != test.ok {
+			t.Error(test.analysis, "got", score, ok, "wanted", test.score, test.ok)
+		}
+	}
+}
+
+func TestUCIOptionString(t *testing.T) {
+	tests := []struct {
+		option UCIOption
+		want   string
+	}{
+		{UCIOption{Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024}, "option name Hash type spin default 16 min 1 max 1024"},
+		{UCIOption{Name: "Clear Hash", Type: "button"}, "option name Clear Hash type button"},
+		{UCIOption{Name: "Ponder", Type: "check", Default: "false"}, "option name Ponder type check default false"},
+		{UCIOption{Name: "Style", Type: "combo", Default: "Normal", Vars: []string{"Solid", "Normal"}}, "option name Style type combo default Normal var Solid var Normal"},
+	}
+	for _, test := range tests {
+		if got := test.option.String(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
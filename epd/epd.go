@@ -1,6 +1,7 @@
 // Package epd is for working with Extended Position Description.
 // You can decode and/or open edp files. Combine this with the engines
-// package to really get some cool stuff going on.
+// package to really get some cool stuff going on, or use the suite package
+// to run test suites against engines.
 package epd
 
 import (
@@ -10,7 +11,9 @@ import (
 	"fmt"
 	"github.com/andrewbackes/chess/fen"
 	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -43,11 +46,65 @@ import (
 //     tcri - telecommunication receiver identification
 //     tcsi - telecommunication sender identification
 //     v0 - variation name (primary, also v1 though v9)
+//
+// Operand holds the operands as they are written, with strings in quotes.
+// The EPD methods read them as moves, numbers or text.
 type Operation struct {
 	Code    string
 	Operand string
 }
 
+// stringOpcodes are the opcodes whose operand is a string, which is written
+// in quotes.
+var stringOpcodes = map[string]bool{
+	"id": true, "c0": true, "c1": true, "c2": true, "c3": true, "c4": true,
+	"c5": true, "c6": true, "c7": true, "c8": true, "c9": true,
+	"v0": true, "v1": true, "v2": true, "v3": true, "v4": true,
+	"v5": true, "v6": true, "v7": true, "v8": true, "v9": true,
+}
+
+// ErrNoOperation is returned when asking for an operation the EPD does not
+// have.
+var ErrNoOperation = errors.New("epd: no such operation")
+
+// Operands returns the operands of the operation, without the quotes around
+// strings.
+func (o Operation) Operands() []string {
+	var operands []string
+	scanOperands(o.Operand, func(operand string) {
+		operands = append(operands, strings.Trim(operand, "\""))
+	})
+	return operands
+}
+
+// scanOperands calls found with each operand in turn, quotes included. It
+// stops at the semicolon that ends the operation and returns what follows.
+func scanOperands(s string, found func(string)) string {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case s == "":
+			return ""
+		case s[0] == ';':
+			return s[1:]
+		case s[0] == '"':
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				end = len(s) - 2
+			}
+			found(s[:end+2])
+			s = s[end+2:]
+		default:
+			end := strings.IndexAny(s, " \t;")
+			if end < 0 {
+				end = len(s)
+			}
+			found(s[:end])
+			s = s[end:]
+		}
+	}
+}
+
 // EPD is an Extended Position Description. Position is a FEN like representation
 // of the board. Operations are the operations to perform on that position.
 type EPD struct {
@@ -59,30 +116,208 @@ func (e EPD) String() string {
 	return fmt.Sprint("Position:   ", e.Position, "\nOperations: ", e.Operations)
 }
 
-// Decode turns a string representation of an epd into an object.
+// Decode turns a string representation of an epd into an object. The
+// halfmove clock and fullmove number of the position come from the hmvc
+// and fmvn operations.
 func Decode(epd string) (*EPD, error) {
-	s := strings.Split(epd, " ")
+	s := strings.Fields(epd)
 	if len(s) < 4 {
 		return nil, errors.New("incomplete epd")
 	}
-	posStr := strings.Join(s[:4], " ")
-	p, err := fen.Decode(posStr)
-	if len(s) <= 4 {
-		return &EPD{Position: p, Operations: nil}, err
+	// The operations start after the fourth field:
+	rest := epd
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[len(s[i]):]
 	}
-	opsStr := strings.TrimRight(strings.Join(s[4:], " "), ";")
-	ops := strings.Split(opsStr, ";")
 	var opers []Operation
-	for _, op := range ops {
-		pair := strings.Split(strings.TrimSpace(op), " ")
-		if len(pair) < 2 {
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		end := strings.IndexAny(rest, " \t;")
+		if end < 0 {
+			end = len(rest)
+		}
+		o := Operation{Code: rest[:end]}
+		var operands []string
+		rest = scanOperands(rest[end:], func(operand string) {
+			operands = append(operands, operand)
+		})
+		o.Operand = strings.Join(operands, " ")
+		if o.Code == "" {
 			return nil, errors.New("epd: could not parse operation")
 		}
-		o := pair[0]
-		v := strings.Join(pair[1:], " ")
-		opers = append(opers, Operation{Code: o, Operand: v})
+		opers = append(opers, o)
+	}
+	e := &EPD{Operations: opers}
+	hmvc, fmvn := "0", "1"
+	if o, ok := e.Operation("hmvc"); ok {
+		hmvc = o.Operand
+	}
+	if o, ok := e.Operation("fmvn"); ok {
+		fmvn = o.Operand
+	}
+	p, err := fen.Decode(strings.Join(append(s[:4:4], hmvc, fmvn), " "))
+	if err != nil {
+		return nil, err
+	}
+	e.Position = p
+	return e, nil
+}
+
+// Encode returns the EPD as a string.
+func Encode(e *EPD) (string, error) {
+	f, err := fen.Encode(e.Position)
+	if err != nil {
+		return "", err
+	}
+	epd := strings.Join(strings.Fields(f)[:4], " ")
+	for _, o := range e.Operations {
+		epd += " " + o.Code
+		if o.Operand != "" {
+			epd += " " + o.Operand
+		}
+		epd += ";"
+	}
+	return epd, nil
+}
+
+// FEN returns the FEN of the position, with its halfmove clock and fullmove
+// number taken from the hmvc and fmvn operations when it has them.
+func (e *EPD) FEN() (string, error) {
+	f, err := fen.Encode(e.Position)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(f)
+	for i, code := range []string{"hmvc", "fmvn"} {
+		n, err := e.Int(code)
+		if err == ErrNoOperation {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fields[4+i] = strconv.Itoa(n)
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// Operation returns the first operation with the opcode.
+func (e *EPD) Operation(code string) (Operation, bool) {
+	for _, o := range e.Operations {
+		if o.Code == code {
+			return o, true
+		}
+	}
+	return Operation{}, false
+}
+
+// Set replaces the operation with the opcode, or adds it. Operands of string
+// opcodes, like id and c0, and operands with spaces or semicolons are quoted.
+// EPD has no way to escape a quote inside a quoted operand, so any quotes in
+// the operands are dropped.
+func (e *EPD) Set(code string, operands ...string) {
+	quoted := make([]string, len(operands))
+	for i, operand := range operands {
+		operand = strings.Replace(operand, "\"", "", -1)
+		quoted[i] = operand
+		if stringOpcodes[code] || strings.ContainsAny(operand, " \t;") {
+			quoted[i] = "\"" + operand + "\""
+		}
+	}
+	o := Operation{Code: code, Operand: strings.Join(quoted, " ")}
+	for i := range e.Operations {
+		if e.Operations[i].Code == code {
+			e.Operations[i] = o
+			return
+		}
+	}
+	e.Operations = append(e.Operations, o)
+}
+
+// Text returns the operand of a string operation, like id or c0, without
+// its quotes.
+func (e *EPD) Text(code string) (string, error) {
+	o, ok := e.Operation(code)
+	if !ok {
+		return "", ErrNoOperation
+	}
+	return strings.Join(o.Operands(), " "), nil
+}
+
+// ID returns the id of the position, or an empty string if it has none.
+func (e *EPD) ID() string {
+	id, _ := e.Text("id")
+	return id
+}
+
+// Int returns the operand of an integer operation, like ce, dm, acn, acs,
+// hmvc or fmvn.
+func (e *EPD) Int(code string) (int, error) {
+	o, ok := e.Operation(code)
+	if !ok {
+		return 0, ErrNoOperation
+	}
+	return strconv.Atoi(o.Operand)
+}
+
+// Moves returns the operands of a move operation, like bm, am, pm or sm,
+// as legal moves in the position. The moves are written in SAN.
+func (e *EPD) Moves(code string) ([]move.Move, error) {
+	o, ok := e.Operation(code)
+	if !ok {
+		return nil, ErrNoOperation
+	}
+	var moves []move.Move
+	for _, san := range o.Operands() {
+		m, err := e.Position.ParseLegalMove(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// BestMoves returns the moves of the bm operation.
+func (e *EPD) BestMoves() ([]move.Move, error) {
+	return e.Moves("bm")
+}
+
+// AvoidMoves returns the moves of the am operation.
+func (e *EPD) AvoidMoves() ([]move.Move, error) {
+	return e.Moves("am")
+}
+
+// PredictedMove returns the move of the pm operation.
+func (e *EPD) PredictedMove() (move.Move, error) {
+	moves, err := e.Moves("pm")
+	if err != nil {
+		return move.Null, err
+	}
+	if len(moves) == 0 {
+		return move.Null, ErrNoOperation
+	}
+	return moves[0], nil
+}
+
+// PredictedVariation returns the moves of the pv operation, each of which
+// is played from the position the previous one leads to.
+func (e *EPD) PredictedVariation() ([]move.Move, error) {
+	o, ok := e.Operation("pv")
+	if !ok {
+		return nil, ErrNoOperation
+	}
+	var moves []move.Move
+	p := e.Position
+	for _, san := range o.Operands() {
+		m, err := p.ParseLegalMove(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+		p = p.MakeMove(m)
 	}
-	return &EPD{Position: p, Operations: opers}, nil
+	return moves, nil
 }
 
 // ToGame returns a game based on the position in the EPD provided.
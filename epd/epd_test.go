@@ -1,7 +1,12 @@
 package epd
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
 )
 
 func TestParseEPD(t *testing.T) {
@@ -18,3 +23,104 @@ func TestParseEPD(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDecodeQuotedOperands(t *testing.T) {
+	e, err := Decode(`4k3/8/8/8/8/8/8/4K2R w K - id "semi;colon test"; c0 "two  spaces"; noop; ce -35;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Operations) != 4 || e.ID() != "semi;colon test" || e.Operations[2].Code != "noop" {
+		t.Fatal(e.Operations)
+	}
+	if c, _ := e.Text("c0"); c != "two  spaces" {
+		t.Error("got", c)
+	}
+	if ce, err := e.Int("ce"); err != nil || ce != -35 {
+		t.Error("got", ce, err)
+	}
+	if _, err := e.Int("dm"); err != ErrNoOperation {
+		t.Error("got", err)
+	}
+}
+
+func TestMoveOperations(t *testing.T) {
+	e, err := Decode(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4 d4; am g4; pm Nf3; pv e4 e5 Nf3;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm, err := e.BestMoves()
+	if err != nil || len(bm) != 2 || bm[0] != move.Parse("e2e4") || bm[1] != move.Parse("d2d4") {
+		t.Error("got", bm, err)
+	}
+	if am, err := e.AvoidMoves(); err != nil || am[0] != move.Parse("g2g4") {
+		t.Error("got", am, err)
+	}
+	if pm, err := e.PredictedMove(); err != nil || pm != move.Parse("g1f3") {
+		t.Error("got", pm, err)
+	}
+	pv, err := e.PredictedVariation()
+	if err != nil || len(pv) != 3 || pv[1] != move.Parse("e7e5") || pv[2] != move.Parse("g1f3") {
+		t.Error("got", pv, err)
+	}
+	e.Set("bm", "e5")
+	if _, err := e.BestMoves(); !errors.Is(err, position.ErrIllegalMove) {
+		t.Error("got", err)
+	}
+}
+
+func TestEncodeEPD(t *testing.T) {
+	test := `1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01"; hmvc 3; fmvn 40;`
+	e, err := Decode(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Encode(e); err != nil || s != test {
+		t.Error("got", s, err)
+	}
+	if f, _ := e.FEN(); f != "1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - 3 40" {
+		t.Error("got", f)
+	}
+	e.Set("id", "BK 01")
+	e.Set("c0", "best")
+	if s, _ := Encode(e); !strings.HasSuffix(s, ` id "BK 01"; hmvc 3; fmvn 40; c0 "best";`) {
+		t.Error("got", s)
+	}
+	e.Set("fmvn", "41")
+	if f, _ := e.FEN(); f != "1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - 3 41" {
+		t.Error("got", f)
+	}
+}
+
+func TestSetText(t *testing.T) {
+	e, err := Decode(`4k3/8/8/8/8/8/8/4K2R w K -`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		code, operand, want string
+	}{
+		{"id", "BK.01", "BK.01"},
+		{"c0", "two words", "two words"},
+		{"c1", "semi;colon", "semi;colon"},
+		{"id", `"quoted"`, "quoted"},
+		{"c2", `say "hi" twice`, "say hi twice"},
+		{"c3", `"`, ""},
+	}
+	for _, test := range tests {
+		e.Set(test.code, test.operand)
+		if got, err := e.Text(test.code); err != nil || got != test.want {
+			t.Errorf("%s %s: got %q, %v", test.code, test.operand, got, err)
+		}
+		s, err := Encode(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Decode(s)
+		if err != nil {
+			t.Fatal(s, err)
+		}
+		if got, _ := d.Text(test.code); got != test.want {
+			t.Errorf("%s %s: decoded %q from %s", test.code, test.operand, got, s)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package eval is a static evaluation of chess positions. It scores
+// material, piece-square tables, mobility, pawn structure, king safety and
+// the bishop pair, each with a middlegame and an endgame weight that are
+// blended by how much material is left on the board.
+//
+// Scores are in centipawns from white's point of view. The weights can be
+// tuned and stored as JSON, and Explain shows how each term adds up to the
+// score.
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// MaxPhase is the phase of a game with all of its knights, bishops, rooks
+// and queens on the board. Knights and bishops count 1, rooks 2 and queens 4.
+// A phase of 0 is a pure endgame.
+const MaxPhase = 24
+
+const (
+	material = iota
+	pieceSquares
+	mobility
+	passedPawns
+	isolatedPawns
+	doubledPawns
+	kingSafety
+	bishopPair
+	terms
+)
+
+var termNames = [terms]string{"Material", "Piece squares", "Mobility", "Passed pawns", "Isolated pawns", "Doubled pawns", "King safety", "Bishop pair"}
+
+// Evaluate scores the position with the default weights.
+func Evaluate(p *position.Position) int {
+	return defaults.Evaluate(p)
+}
+
+// Explain breaks down the score of the position with the default weights.
+func Explain(p *position.Position) Breakdown {
+	return defaults.Explain(p)
+}
+
+// Evaluate returns the score of the position in centipawns. Positive scores
+// favor white and negative scores favor black, whoever is to move.
+func (w *Weights) Evaluate(p *position.Position) int {
+	s, phase := w.score(p)
+	total := 0
+	for i := range s {
+		total += blend(s[i][piece.White].add(s[i][piece.Black], -1), phase)
+	}
+	return total
+}
+
+// Term is one part of a breakdown.
+type Term struct {
+	Name string
+	// White and Black are what the term is worth to each side before the
+	// middlegame and endgame values are blended.
+	White, Black Pair
+	// Score is the blended difference between White and Black.
+	Score int
+}
+
+// Breakdown explains how a score is made up.
+type Breakdown struct {
+	Phase int
+	Terms []Term
+	// Score is the sum of the scores of the terms, which is what Evaluate
+	// returns.
+	Score int
+}
+
+// Explain returns the score of the position broken down into its terms.
+func (w *Weights) Explain(p *position.Position) Breakdown {
+	s, phase := w.score(p)
+	b := Breakdown{Phase: phase}
+	for i := range s {
+		t := Term{Name: termNames[i], White: s[i][piece.White], Black: s[i][piece.Black]}
+		t.Score = blend(t.White.add(t.Black, -1), phase)
+		b.Terms = append(b.Terms, t)
+		b.Score += t.Score
+	}
+	return b
+}
+
+// String returns the breakdown as a table.
+func (b Breakdown) String() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Term\tWhite MG\tWhite EG\tBlack MG\tBlack EG\tScore\t")
+	for _, t := range b.Terms {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t\n", t.Name, t.White.MG, t.White.EG, t.Black.MG, t.Black.EG, t.Score)
+	}
+	fmt.Fprintf(tw, "Total\t\t\t\t\t%d\t\n", b.Score)
+	tw.Flush()
+	fmt.Fprintf(&sb, "Phase %d/%d\n", b.Phase, MaxPhase)
+	return sb.String()
+}
+
+func blend(p Pair, phase int) int {
+	return (p.MG*phase + p.EG*(MaxPhase-phase)) / MaxPhase
+}
+
+// score returns what each term is worth to each side, and the phase.
+func (w *Weights) score(p *position.Position) (s [terms][2]Pair, phase int) {
+	for _, c := range piece.Colors {
+		enemy := piece.Colors[1-c]
+		own := p.Occupied(c)
+		kingZone := position.BitBoard(0)
+		if king := p.Pieces(enemy, piece.King); king != 0 {
+			kingZone = king | p.Attacks(king.LSB())
+		}
+		for t := piece.Pawn; t <= piece.King; t++ {
+			pieces := p.Pieces(c, t)
+			n := pieces.Count()
+			s[material][c] = s[material][c].add(w.Material.value(t), n)
+			phase += n * [...]int{piece.Knight: 1, piece.Bishop: 1, piece.Rook: 2, piece.Queen: 4, piece.King: 0}[t]
+			table := w.PieceSquare.table(t)
+			for pieces != 0 {
+				var sq square.Square
+				sq, pieces = pieces.PopLSB()
+				i := tableIndex(sq, c)
+				s[pieceSquares][c] = s[pieceSquares][c].add(Pair{table.MG[i], table.EG[i]}, 1)
+				if t == piece.Pawn || t == piece.King {
+					continue
+				}
+				attacks := p.Attacks(sq)
+				s[mobility][c] = s[mobility][c].add(w.Mobility.value(t), (attacks &^ own).Count())
+				s[kingSafety][enemy] = s[kingSafety][enemy].add(w.KingAttack, (attacks & kingZone).Count())
+			}
+		}
+		if p.Pieces(c, piece.Bishop).Count() >= 2 {
+			s[bishopPair][c] = s[bishopPair][c].add(w.BishopPair, 1)
+		}
+		w.pawns(p, c, &s)
+	}
+	if phase > MaxPhase {
+		phase = MaxPhase
+	}
+	return s, phase
+}
+
+// pawns scores the pawn structure and pawn shield of one side.
+func (w *Weights) pawns(p *position.Position, c piece.Color, s *[terms][2]Pair) {
+	pawns := p.Pieces(c, piece.Pawn)
+	enemyPawns := p.Pieces(piece.Colors[1-c], piece.Pawn)
+	for i := 0; i < 8; i++ {
+		if n := (pawns & (position.FileH << i)).Count(); n > 1 {
+			s[doubledPawns][c] = s[doubledPawns][c].add(w.DoubledPawn, n-1)
+		}
+	}
+	for b := pawns; b != 0; {
+		var sq square.Square
+		sq, b = b.PopLSB()
+		file := position.FileMask(sq)
+		adjacent := file.East() | file.West()
+		if pawns&adjacent == 0 {
+			s[isolatedPawns][c] = s[isolatedPawns][c].add(w.IsolatedPawn, 1)
+		}
+		if enemyPawns&(file|adjacent)&ahead(sq, c) == 0 {
+			s[passedPawns][c] = s[passedPawns][c].add(w.PassedPawn[relativeRank(sq, c)], 1)
+		}
+	}
+	if king := p.Pieces(c, piece.King); king != 0 {
+		front := king.North() | king.NorthEast() | king.NorthWest()
+		shield := front | front.North()
+		if c == piece.Black {
+			front = king.South() | king.SouthEast() | king.SouthWest()
+			shield = front | front.South()
+		}
+		s[kingSafety][c] = s[kingSafety][c].add(w.KingShield, (shield & pawns).Count())
+	}
+}
+
+// ahead returns the squares on the ranks in front of the square, as seen by
+// the specified color.
+func ahead(s square.Square, c piece.Color) position.BitBoard {
+	r := uint(s / 8)
+	if c == piece.White {
+		return ^position.BitBoard(0) << (8 * (r + 1))
+	}
+	return position.BitBoard(1)<<(8*r) - 1
+}
+
+func relativeRank(s square.Square, c piece.Color) int {
+	if c == piece.White {
+		return int(s / 8)
+	}
+	return 7 - int(s/8)
+}
+
+// tableIndex returns where the square is in a piece-square table for the
+// specified color.
+func tableIndex(s square.Square, c piece.Color) int {
+	file := 7 - int(s%8)
+	return (7-relativeRank(s, c))*8 + file
+}
@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/position"
+)
+
+func decode(t *testing.T, f string) *position.Position {
+	p, err := fen.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestStartingPositionIsEven(t *testing.T) {
+	if got := Evaluate(position.New()); got != 0 {
+		t.Errorf("got %d, want 0\n%v", got, Explain(position.New()))
+	}
+}
+
+func TestMirroredPositions(t *testing.T) {
+	// Each pair is the same position with the colors swapped.
+	pairs := [][2]string{
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "rnbqkb1r/pppp1ppp/5n2/4p3/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq - 2 3"},
+		{"8/5k2/8/3P4/8/2K5/8/8 w - - 0 1", "8/8/2k5/8/3p4/8/5K2/8 b - - 0 1"},
+		{"r4rk1/pp3ppp/2n5/3p4/3P4/2PB1N2/P4PPP/R4RK1 w - - 0 1", "r4rk1/p4ppp/2pb1n2/3p4/3P4/2N5/PP3PPP/R4RK1 b - - 0 1"},
+	}
+	for _, pair := range pairs {
+		if a, b := Evaluate(decode(t, pair[0])), Evaluate(decode(t, pair[1])); a != -b {
+			t.Errorf("%s scores %d but its mirror scores %d", pair[0], a, b)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		fen  string
+		term string
+		// sign of the term's score
+		want int
+	}{
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 0 1", "Material", 1},
+		{"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", "Passed pawns", 1},
+		{"4k3/p7/8/8/8/8/P1P5/4K3 w - - 0 1", "Isolated pawns", -1},
+		{"4k3/pp6/8/8/8/P7/P7/4K3 w - - 0 1", "Doubled pawns", -1},
+		{"4k3/8/8/8/8/8/8/2BBK3 w - - 0 1", "Bishop pair", 1},
+		{"4k3/8/8/8/8/8/8/N3K3 w - - 0 1", "Piece squares", -1},
+		{"r3k3/8/8/8/3R4/8/8/4K3 w - - 0 1", "Mobility", 1},
+		{"r5k1/5ppp/8/8/8/8/8/6K1 w - - 0 1", "King safety", -1},
+	}
+	for _, test := range tests {
+		b := Explain(decode(t, test.fen))
+		for _, term := range b.Terms {
+			if term.Name == test.term && sign(term.Score) != test.want {
+				t.Errorf("%s: %s scored %d\n%v", test.fen, test.term, term.Score, b)
+			}
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+func TestExplainAddsUp(t *testing.T) {
+	p := decode(t, "r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N2N2/PP2BPPP/R2QKB1R w KQ - 0 8")
+	b := Explain(p)
+	total := 0
+	for _, term := range b.Terms {
+		total += term.Score
+	}
+	if total != b.Score || b.Score != Evaluate(p) {
+		t.Errorf("terms add up to %d, breakdown says %d and Evaluate says %d", total, b.Score, Evaluate(p))
+	}
+	if b.Phase != MaxPhase {
+		t.Errorf("phase %d, want %d", b.Phase, MaxPhase)
+	}
+	if s := b.String(); !strings.Contains(s, "Bishop pair") || !strings.Contains(s, "Total") {
+		t.Errorf("breakdown is missing rows:\n%s", s)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	w, err := Load(strings.NewReader(`{"material": {"queen": [1200, 1300]}, "bishopPair": [0, 0]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Material.Queen != (Pair{1200, 1300}) || w.BishopPair != (Pair{}) {
+		t.Errorf("loaded weights were not applied: %+v", w)
+	}
+	if w.Material.Rook != defaults.Material.Rook || w.PieceSquare != defaults.PieceSquare {
+		t.Error("weights missing from the JSON lost their defaults")
+	}
+	if _, err := Load(strings.NewReader(`{"bishopPair": [1, 2, 3]}`)); err == nil {
+		t.Error("a pair with three values was accepted")
+	}
+	b, err := json.Marshal(Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err := Load(strings.NewReader(string(b))); err != nil || *w != defaults {
+		t.Errorf("weights did not survive a round trip: %v", err)
+	}
+}
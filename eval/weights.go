@@ -0,0 +1,248 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/andrewbackes/chess/piece"
+)
+
+// Pair is a value for the middlegame and one for the endgame. The two are
+// blended by the phase of the game. In JSON it is written as [mg, eg].
+type Pair struct {
+	MG, EG int
+}
+
+// MarshalJSON writes the pair as a two element array.
+func (p Pair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.MG, p.EG})
+}
+
+// UnmarshalJSON reads a pair written as a two element array.
+func (p *Pair) UnmarshalJSON(b []byte) error {
+	var a []int
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	if len(a) != 2 {
+		return fmt.Errorf("eval: a pair needs a middlegame and an endgame value, got %d values", len(a))
+	}
+	p.MG, p.EG = a[0], a[1]
+	return nil
+}
+
+func (p Pair) add(q Pair, times int) Pair {
+	return Pair{MG: p.MG + q.MG*times, EG: p.EG + q.EG*times}
+}
+
+// Table is a piece-square table. It is laid out as the board is seen from
+// white's side, a8 first and h1 last, and mirrored for black.
+type Table struct {
+	MG [64]int `json:"mg"`
+	EG [64]int `json:"eg"`
+}
+
+// Material holds the value of each kind of piece.
+type Material struct {
+	Pawn   Pair `json:"pawn"`
+	Knight Pair `json:"knight"`
+	Bishop Pair `json:"bishop"`
+	Rook   Pair `json:"rook"`
+	Queen  Pair `json:"queen"`
+}
+
+func (m *Material) value(t piece.Type) Pair {
+	return [...]Pair{piece.Pawn: m.Pawn, piece.Knight: m.Knight, piece.Bishop: m.Bishop, piece.Rook: m.Rook, piece.Queen: m.Queen, piece.King: {}}[t]
+}
+
+// PieceSquare holds a piece-square table for each kind of piece.
+type PieceSquare struct {
+	Pawn   Table `json:"pawn"`
+	Knight Table `json:"knight"`
+	Bishop Table `json:"bishop"`
+	Rook   Table `json:"rook"`
+	Queen  Table `json:"queen"`
+	King   Table `json:"king"`
+}
+
+func (ps *PieceSquare) table(t piece.Type) *Table {
+	return [...]*Table{piece.Pawn: &ps.Pawn, piece.Knight: &ps.Knight, piece.Bishop: &ps.Bishop, piece.Rook: &ps.Rook, piece.Queen: &ps.Queen, piece.King: &ps.King}[t]
+}
+
+// Mobility holds what each square a piece attacks, and is not occupied by
+// its own side, is worth.
+type Mobility struct {
+	Knight Pair `json:"knight"`
+	Bishop Pair `json:"bishop"`
+	Rook   Pair `json:"rook"`
+	Queen  Pair `json:"queen"`
+}
+
+func (m *Mobility) value(t piece.Type) Pair {
+	return [...]Pair{piece.Pawn: {}, piece.Knight: m.Knight, piece.Bishop: m.Bishop, piece.Rook: m.Rook, piece.Queen: m.Queen, piece.King: {}}[t]
+}
+
+// Weights are the terms of the evaluation. Penalties are negative.
+type Weights struct {
+	Material    Material    `json:"material"`
+	PieceSquare PieceSquare `json:"pieceSquare"`
+	Mobility    Mobility    `json:"mobility"`
+	// PassedPawn is indexed by the rank of the pawn counted from its own
+	// side, 0 being the first rank.
+	PassedPawn   [8]Pair `json:"passedPawn"`
+	IsolatedPawn Pair    `json:"isolatedPawn"`
+	// DoubledPawn is counted once for each pawn more than one on a file.
+	DoubledPawn Pair `json:"doubledPawn"`
+	// KingShield is counted for each friendly pawn on the three files around
+	// the king, one or two ranks in front of it.
+	KingShield Pair `json:"kingShield"`
+	// KingAttack is counted for each attack by an enemy knight, bishop, rook
+	// or queen on the king or the squares next to it.
+	KingAttack Pair `json:"kingAttack"`
+	BishopPair Pair `json:"bishopPair"`
+}
+
+// Load reads weights written as JSON. Terms missing from the JSON keep their
+// default values, so a file only needs the terms being tuned.
+func Load(r io.Reader) (*Weights, error) {
+	w := Default()
+	if err := json.NewDecoder(r).Decode(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Default returns a copy of the built in weights.
+func Default() *Weights {
+	w := defaults
+	return &w
+}
+
+var defaults = Weights{
+	Material: Material{
+		Pawn:   Pair{100, 120},
+		Knight: Pair{320, 300},
+		Bishop: Pair{330, 320},
+		Rook:   Pair{500, 550},
+		Queen:  Pair{950, 1000},
+	},
+	PieceSquare: PieceSquare{
+		Pawn: Table{
+			MG: [64]int{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				50, 50, 50, 50, 50, 50, 50, 50,
+				10, 10, 20, 30, 30, 20, 10, 10,
+				5, 5, 10, 25, 25, 10, 5, 5,
+				0, 0, 0, 20, 20, 0, 0, 0,
+				5, -5, -10, 0, 0, -10, -5, 5,
+				5, 10, 10, -20, -20, 10, 10, 5,
+				0, 0, 0, 0, 0, 0, 0, 0,
+			},
+			EG: [64]int{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				40, 40, 40, 40, 40, 40, 40, 40,
+				25, 25, 25, 25, 25, 25, 25, 25,
+				15, 15, 15, 15, 15, 15, 15, 15,
+				8, 8, 8, 8, 8, 8, 8, 8,
+				3, 3, 3, 3, 3, 3, 3, 3,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 0,
+			},
+		},
+		Knight: Table{
+			MG: knightTable,
+			EG: knightTable,
+		},
+		Bishop: Table{
+			MG: bishopTable,
+			EG: bishopTable,
+		},
+		Rook: Table{
+			MG: rookTable,
+			EG: [64]int{},
+		},
+		Queen: Table{
+			MG: queenTable,
+			EG: queenTable,
+		},
+		King: Table{
+			MG: [64]int{
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-20, -30, -30, -40, -40, -30, -30, -20,
+				-10, -20, -20, -20, -20, -20, -20, -10,
+				20, 20, 0, 0, 0, 0, 20, 20,
+				20, 30, 10, 0, 0, 10, 30, 20,
+			},
+			EG: [64]int{
+				-50, -40, -30, -20, -20, -30, -40, -50,
+				-30, -20, -10, 0, 0, -10, -20, -30,
+				-30, -10, 20, 30, 30, 20, -10, -30,
+				-30, -10, 30, 40, 40, 30, -10, -30,
+				-30, -10, 30, 40, 40, 30, -10, -30,
+				-30, -10, 20, 30, 30, 20, -10, -30,
+				-30, -30, 0, 0, 0, 0, -30, -30,
+				-50, -30, -30, -30, -30, -30, -30, -50,
+			},
+		},
+	},
+	Mobility: Mobility{
+		Knight: Pair{4, 4},
+		Bishop: Pair{5, 5},
+		Rook:   Pair{2, 4},
+		Queen:  Pair{1, 2},
+	},
+	PassedPawn:   [8]Pair{{0, 0}, {5, 10}, {10, 20}, {15, 35}, {30, 60}, {50, 100}, {80, 150}, {0, 0}},
+	IsolatedPawn: Pair{-10, -15},
+	DoubledPawn:  Pair{-10, -20},
+	KingShield:   Pair{10, 0},
+	KingAttack:   Pair{-8, 0},
+	BishopPair:   Pair{30, 50},
+}
+
+var knightTable = [64]int{
+	-50, -40, -30, -30, -30, -30, -40, -50,
+	-40, -20, 0, 0, 0, 0, -20, -40,
+	-30, 0, 10, 15, 15, 10, 0, -30,
+	-30, 5, 15, 20, 20, 15, 5, -30,
+	-30, 0, 15, 20, 20, 15, 0, -30,
+	-30, 5, 10, 15, 15, 10, 5, -30,
+	-40, -20, 0, 5, 5, 0, -20, -40,
+	-50, -40, -30, -30, -30, -30, -40, -50,
+}
+
+var bishopTable = [64]int{
+	-20, -10, -10, -10, -10, -10, -10, -20,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-10, 0, 5, 10, 10, 5, 0, -10,
+	-10, 5, 5, 10, 10, 5, 5, -10,
+	-10, 0, 10, 10, 10, 10, 0, -10,
+	-10, 10, 10, 10, 10, 10, 10, -10,
+	-10, 5, 0, 0, 0, 0, 5, -10,
+	-20, -10, -10, -10, -10, -10, -10, -20,
+}
+
+var rookTable = [64]int{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	5, 10, 10, 10, 10, 10, 10, 5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	0, 0, 0, 5, 5, 0, 0, 0,
+}
+
+var queenTable = [64]int{
+	-20, -10, -10, -5, -5, -10, -10, -20,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-10, 0, 5, 5, 5, 5, 0, -10,
+	-5, 0, 5, 5, 5, 5, 0, -5,
+	-5, 0, 5, 5, 5, 5, 0, -5,
+	-10, 0, 5, 5, 5, 5, 0, -10,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-20, -10, -10, -5, -5, -10, -10, -20,
+}
@@ -0,0 +1,114 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// Adjudication are the rules for ending engine games before the rules of
+// chess would. Scores are in centipawns. A rule is turned off by leaving its
+// number of moves, or its tablebase, unset.
+type Adjudication struct {
+	// A player loses once, for ResignMoves moves in a row, its own score
+	// has been below -ResignScore and its opponent's score above ResignScore.
+	ResignScore int
+	ResignMoves int
+	// The game is drawn once both players have scored within DrawScore of
+	// zero for DrawMoves moves in a row, but not before move DrawMoveNumber.
+	DrawScore      int
+	DrawMoveNumber int
+	DrawMoves      int
+	// Positions with at most TablebasePieces pieces, kings included, are
+	// decided by the tablebase.
+	Tablebase       Tablebase
+	TablebasePieces int
+	// The game is drawn after MaxPlies half moves.
+	MaxPlies int
+}
+
+// Tablebase knows the outcome of endgame positions.
+type Tablebase interface {
+	// Probe returns 1 if the player to move wins, 0 if the position is a
+	// draw and -1 if the player to move loses. The boolean is false if the
+	// position is not in the tablebase.
+	Probe(*position.Position) (int, bool)
+}
+
+// Adjudicator applies adjudication rules to a game using the scores the
+// players report.
+type Adjudicator struct {
+	Rules Adjudication
+	// losing, winning and drawish count how many moves in a row each
+	// player's score met the rule.
+	losing, winning, drawish [2]int
+}
+
+// NewAdjudicator returns an adjudicator for the rules.
+func NewAdjudicator(rules Adjudication) *Adjudicator {
+	return &Adjudicator{Rules: rules}
+}
+
+// Record tells the adjudicator the score the player reported for its move,
+// from its own point of view.
+func (a *Adjudicator) Record(player piece.Color, score int) {
+	count := func(n *int, ok bool) {
+		if ok {
+			*n++
+		} else {
+			*n = 0
+		}
+	}
+	count(&a.losing[player], score < -a.Rules.ResignScore)
+	count(&a.winning[player], score > a.Rules.ResignScore)
+	count(&a.drawish[player], score < a.Rules.DrawScore && score > -a.Rules.DrawScore)
+}
+
+// NoScore tells the adjudicator the player moved without reporting a score,
+// which breaks any run of moves the rules are counting.
+func (a *Adjudicator) NoScore(player piece.Color) {
+	a.losing[player], a.winning[player], a.drawish[player] = 0, 0, 0
+}
+
+// Adjudicate returns the status the game should end with, or InProgress if
+// none of the rules apply.
+func (a *Adjudicator) Adjudicate(g *Game) GameStatus {
+	r := a.Rules
+	if r.Tablebase != nil && pieceCount(g.Position()) <= r.TablebasePieces {
+		if wdl, ok := r.Tablebase.Probe(g.Position()); ok {
+			switch {
+			case wdl == 0:
+				return DrawByTablebase
+			case (wdl > 0) == (g.ActiveColor() == piece.White):
+				return BlackLostByTablebase
+			default:
+				return WhiteLostByTablebase
+			}
+		}
+	}
+	if r.ResignMoves > 0 {
+		for _, c := range piece.Colors {
+			if a.losing[c] >= r.ResignMoves && a.winning[1-c] >= r.ResignMoves {
+				return []GameStatus{WhiteLostByAdjudication, BlackLostByAdjudication}[c]
+			}
+		}
+	}
+	if r.DrawMoves > 0 && g.Position().MoveNumber >= r.DrawMoveNumber &&
+		a.drawish[piece.White] >= r.DrawMoves && a.drawish[piece.Black] >= r.DrawMoves {
+		return DrawByAdjudication
+	}
+	if r.MaxPlies > 0 && g.Ply() >= r.MaxPlies {
+		return MaxPliesReached
+	}
+	return InProgress
+}
+
+func pieceCount(p *position.Position) int {
+	n := 0
+	for s := square.Square(0); s <= square.LastSquare; s++ {
+		if p.OnSquare(s).Type != piece.None {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/square"
+	"testing"
+)
+
+func TestAdjudicateResign(t *testing.T) {
+	a := NewAdjudicator(Adjudication{ResignScore: 500, ResignMoves: 2})
+	g := New()
+	for i := 0; i < 2; i++ {
+		if status := a.Adjudicate(g); status != InProgress {
+			t.Fatal("adjudicated too early:", status)
+		}
+		a.Record(piece.White, 600)
+		a.Record(piece.Black, -700)
+	}
+	if status := a.Adjudicate(g); status != BlackLostByAdjudication {
+		t.Error("got", status, "wanted", BlackLostByAdjudication)
+	}
+	a.NoScore(piece.White)
+	if status := a.Adjudicate(g); status != InProgress {
+		t.Error("a missing score should reset the count, got", status)
+	}
+}
+
+func TestAdjudicateDraw(t *testing.T) {
+	a := NewAdjudicator(Adjudication{DrawScore: 10, DrawMoves: 1, DrawMoveNumber: 2})
+	g := New()
+	a.Record(piece.White, 5)
+	a.Record(piece.Black, -5)
+	if status := a.Adjudicate(g); status != InProgress {
+		t.Error("adjudicated before the draw move number:", status)
+	}
+	for _, san := range []string{"e4", "e5"} {
+		m, _ := g.Position().ParseMove(san)
+		g.MakeMove(m)
+	}
+	if status := a.Adjudicate(g); status != DrawByAdjudication {
+		t.Error("got", status, "wanted", DrawByAdjudication)
+	}
+}
+
+func TestAdjudicateMaxPlies(t *testing.T) {
+	a := NewAdjudicator(Adjudication{MaxPlies: 1})
+	g := New()
+	m, _ := g.Position().ParseMove("e4")
+	g.MakeMove(m)
+	if status := a.Adjudicate(g); status != MaxPliesReached {
+		t.Error("got", status, "wanted", MaxPliesReached)
+	}
+	g.GoTo(0)
+	if status := a.Adjudicate(g); status != InProgress {
+		t.Error("counted an undone ply, got", status)
+	}
+}
+
+// kqk is a tablebase that knows the side with the queen wins.
+type kqk struct{}
+
+func (kqk) Probe(p *position.Position) (int, bool) {
+	for c := range piece.Colors {
+		if len(p.Find(piece.New(piece.Color(c), piece.Queen))) > 0 {
+			if piece.Color(c) == p.ActiveColor {
+				return 1, true
+			}
+			return -1, true
+		}
+	}
+	return 0, false
+}
+
+func TestAdjudicateTablebase(t *testing.T) {
+	p := position.New()
+	p.Clear()
+	p.Put(piece.New(piece.White, piece.King), square.E1)
+	p.Put(piece.New(piece.Black, piece.Queen), square.D8)
+	p.Put(piece.New(piece.Black, piece.King), square.E8)
+	g := New()
+	g.Positions = []*position.Position{p}
+	if status := NewAdjudicator(Adjudication{Tablebase: kqk{}, TablebasePieces: 2}).Adjudicate(g); status != InProgress {
+		t.Error("probed a position with too many pieces:", status)
+	}
+	if status := NewAdjudicator(Adjudication{Tablebase: kqk{}, TablebasePieces: 3}).Adjudicate(g); status != WhiteLostByTablebase {
+		t.Error("got", status, "wanted", WhiteLostByTablebase)
+	}
+}
@@ -1,16 +1,27 @@
 package game
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrUnknownTimeControl is returned when parsing the "?" time control.
+var ErrUnknownTimeControl = errors.New("time control is unknown")
+
 // TimeControl represents the time contraints for a chess game.
-// TODO(andrewbackes): new TimeControl from parsed string. ex: 40/40
 type TimeControl struct {
 	Time      time.Duration `json:"time,omitempty"`
 	Increment time.Duration `json:"increment,omitempty"`
 	Moves     int           `json:"moves,omitempty"`
 	Repeating bool          `json:"repeating,omitempty"`
+	// Hourglass adds the time a player uses to the opponent's clock.
+	Hourglass bool `json:"hourglass,omitempty"`
+	// Then is the time control that follows once Moves were made. It takes
+	// precedence over Repeating.
+	Then *TimeControl `json:"then,omitempty"`
 }
 
 // NewTimeControl creates a time control where 'time' is the time per control,
@@ -26,16 +37,154 @@ func NewTimeControl(time time.Duration, moves int, inc time.Duration, repeating
 	}
 }
 
-/*
-// Reset adds the time control to the clock current clock value.
-func (t *TimeControl) Reset() {
-	t.clock += t.Time
-	t.movesLeft = t.Moves
+// next returns the time control that follows once Moves were made, or nil if
+// no more time is given.
+func (tc TimeControl) next() *TimeControl {
+	if tc.Moves <= 0 {
+		return nil
+	}
+	if tc.Then != nil {
+		return tc.Then
+	}
+	if tc.Repeating {
+		return &tc
+	}
+	return nil
+}
+
+// stageAfter returns the period being played once the given number of moves
+// were made.
+func (tc TimeControl) stageAfter(moves int) TimeControl {
+	for tc.Moves > 0 && moves >= tc.Moves {
+		next := tc.next()
+		if next == nil {
+			break
+		}
+		moves -= tc.Moves
+		tc = *next
+	}
+	return tc
+}
+
+// ParseTimeControl parses the value of a PGN TimeControl tag, like
+// "40/7200:3600", "300+3" or "*180". Times are in seconds. The last period
+// repeats when it is for a number of moves. The "-" of untimed games gives
+// the zero value, while "?" gives ErrUnknownTimeControl.
+func ParseTimeControl(s string) (TimeControl, error) {
+	return parseTimeControl(s, time.Second)
 }
 
-// Clear sets the clock to zero.
-func (t *TimeControl) Clear() {
-	t.clock = 0
-	t.movesLeft = 0
+// ParseShorthand parses the time controls of engine testing, where a number
+// of moves is played in minutes, like "40/2", and sudden death is given in
+// seconds, like "60+0.6".
+func ParseShorthand(s string) (TimeControl, error) {
+	return parseTimeControl(s, time.Minute)
+}
+
+func parseTimeControl(s string, perMoves time.Duration) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "?":
+		return TimeControl{}, ErrUnknownTimeControl
+	case "-":
+		return TimeControl{}, nil
+	}
+	fields := strings.Split(s, ":")
+	periods := make([]TimeControl, len(fields))
+	for i, field := range fields {
+		var err error
+		if periods[i], err = parsePeriod(field, perMoves); err != nil {
+			return TimeControl{}, errors.New("bad time control " + strconv.Quote(s) + ": " + err.Error())
+		}
+	}
+	for i := len(periods) - 2; i >= 0; i-- {
+		if periods[i].Moves == 0 {
+			return TimeControl{}, errors.New("bad time control " + strconv.Quote(s) + ": only the last period can be sudden death")
+		}
+		periods[i].Then = &periods[i+1]
+	}
+	last := &periods[len(periods)-1]
+	last.Repeating = last.Moves > 0
+	return periods[0], nil
+}
+
+// parsePeriod parses one period of a time control, like "40/7200", "300+3"
+// or "*180".
+func parsePeriod(s string, perMoves time.Duration) (TimeControl, error) {
+	var tc TimeControl
+	unit := time.Second
+	if strings.HasPrefix(s, "*") {
+		tc.Hourglass = true
+		s = s[1:]
+	} else if i := strings.Index(s, "/"); i >= 0 {
+		moves, err := strconv.Atoi(s[:i])
+		if err != nil || moves <= 0 {
+			return tc, errors.New("bad number of moves " + strconv.Quote(s[:i]))
+		}
+		tc.Moves, unit, s = moves, perMoves, s[i+1:]
+	}
+	if i := strings.Index(s, "+"); i >= 0 && !tc.Hourglass {
+		inc, err := parseSeconds(s[i+1:], time.Second)
+		if err != nil {
+			return tc, err
+		}
+		tc.Increment, s = inc, s[:i]
+	}
+	var err error
+	tc.Time, err = parseSeconds(s, unit)
+	return tc, err
+}
+
+func parseSeconds(s string, unit time.Duration) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, errors.New("bad time " + strconv.Quote(s))
+	}
+	return time.Duration(f * float64(unit)), nil
+}
+
+// String returns the time control as the value of a PGN TimeControl tag.
+func (tc TimeControl) String() string {
+	return tc.format(time.Second)
+}
+
+// Shorthand returns the time control the way ParseShorthand reads it.
+func (tc TimeControl) Shorthand() string {
+	return tc.format(time.Minute)
+}
+
+func (tc TimeControl) format(perMoves time.Duration) string {
+	if tc == (TimeControl{}) {
+		return "-"
+	}
+	var periods []string
+	for p := &tc; ; {
+		periods = append(periods, p.period(perMoves))
+		if p.Then == nil {
+			if p.Moves > 0 && !p.Repeating {
+				// No more time is given after the last period:
+				periods = append(periods, "0")
+			}
+			break
+		}
+		p = p.Then
+	}
+	return strings.Join(periods, ":")
+}
+
+func (tc TimeControl) period(perMoves time.Duration) string {
+	seconds := func(d, unit time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(unit), 'f', -1, 64)
+	}
+	if tc.Hourglass {
+		return "*" + seconds(tc.Time, time.Second)
+	}
+	s := seconds(tc.Time, time.Second)
+	if tc.Moves > 0 {
+		s = strconv.Itoa(tc.Moves) + "/" + seconds(tc.Time, perMoves)
+	}
+	if tc.Increment > 0 {
+		s += "+" + seconds(tc.Increment, time.Second)
+	}
+	return s
 }
-*/
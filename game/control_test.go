@@ -1,7 +1,10 @@
 package game
 
 import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
 	"testing"
+	"time"
 )
 
 func TestClearTC(t *testing.T) {
@@ -13,3 +16,85 @@ func TestClearTC(t *testing.T) {
 		}
 	*/
 }
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		tag string
+		tc  TimeControl
+	}{
+		{"-", TimeControl{}},
+		{"300", TimeControl{Time: 5 * time.Minute}},
+		{"300+3", TimeControl{Time: 5 * time.Minute, Increment: 3 * time.Second}},
+		{"60+0.6", TimeControl{Time: time.Minute, Increment: 600 * time.Millisecond}},
+		{"*180", TimeControl{Time: 3 * time.Minute, Hourglass: true}},
+		{"40/9000", TimeControl{Time: 150 * time.Minute, Moves: 40, Repeating: true}},
+		{"40/7200:3600", TimeControl{Time: 2 * time.Hour, Moves: 40, Then: &TimeControl{Time: time.Hour}}},
+		{"40/7200:20/3600:900+30", TimeControl{Time: 2 * time.Hour, Moves: 40, Then: &TimeControl{Time: time.Hour, Moves: 20,
+			Then: &TimeControl{Time: 15 * time.Minute, Increment: 30 * time.Second}}}},
+	}
+	for _, test := range tests {
+		tc, err := ParseTimeControl(test.tag)
+		if err != nil || tc.String() != test.tc.String() || tc.Time != test.tc.Time || tc.Moves != test.tc.Moves {
+			t.Error(test.tag, "got", tc, err)
+		}
+		if s := test.tc.String(); s != test.tag {
+			t.Error(test.tc, "formatted as", s, "wanted", test.tag)
+		}
+	}
+	if _, err := ParseTimeControl("?"); err != ErrUnknownTimeControl {
+		t.Error("got", err, "wanted", ErrUnknownTimeControl)
+	}
+	for _, bad := range []string{"", "abc", "40/", "/60", "300:40/60", "-5", "*60+1"} {
+		if _, err := ParseTimeControl(bad); err == nil {
+			t.Error("parsed", bad)
+		}
+	}
+	// A period of moves with nothing after is written as sudden death of zero:
+	if s := NewTimeControl(time.Minute, 40, 0, false).String(); s != "40/60:0" {
+		t.Error("got", s)
+	}
+}
+
+func TestParseShorthand(t *testing.T) {
+	tc, err := ParseShorthand("40/2")
+	if err != nil || tc.Moves != 40 || tc.Time != 2*time.Minute || !tc.Repeating {
+		t.Error("got", tc, err)
+	}
+	tc, err = ParseShorthand("60+0.6")
+	if err != nil || tc.Time != time.Minute || tc.Increment != 600*time.Millisecond {
+		t.Error("got", tc, err)
+	}
+	if s := tc.Shorthand(); s != "60+0.6" {
+		t.Error("got", s)
+	}
+	if s := NewTimeControl(15*time.Minute, 40, 0, true).Shorthand(); s != "40/15" {
+		t.Error("got", s)
+	}
+}
+
+func TestTimeControlPeriods(t *testing.T) {
+	tc, _ := ParseTimeControl("1/60:30+1")
+	g := NewTimedGame(map[piece.Color]TimeControl{piece.White: tc, piece.Black: tc})
+	for _, s := range []string{"e2e4", "e7e5", "d2d4"} {
+		m := move.Parse(s)
+		m.Duration = 10 * time.Second
+		g.MakeMove(m)
+	}
+	if c := g.Clock(piece.White); c != 50*time.Second+30*time.Second-10*time.Second+time.Second {
+		t.Error("white has", c)
+	}
+	if g.Stage(piece.White).Time != 30*time.Second || g.TimeControl(piece.White).Moves != 1 {
+		t.Error("got", g.Stage(piece.White), g.TimeControl(piece.White))
+	}
+}
+
+func TestHourglass(t *testing.T) {
+	tc, _ := ParseTimeControl("*60")
+	g := NewTimedGame(map[piece.Color]TimeControl{piece.White: tc, piece.Black: tc})
+	m := move.Parse("e2e4")
+	m.Duration = 10 * time.Second
+	g.MakeMove(m)
+	if g.Clock(piece.White) != 50*time.Second || g.Clock(piece.Black) != 70*time.Second {
+		t.Error("got", g.Clock(piece.White), g.Clock(piece.Black))
+	}
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"errors"
+	"github.com/andrewbackes/chess/piece"
+)
+
+var (
+	// ErrGameOver is returned when trying to play on after the game has ended.
+	ErrGameOver = errors.New("the game is over")
+	// ErrNoDrawOffer is returned when accepting or declining a draw that was
+	// not offered.
+	ErrNoDrawOffer = errors.New("no draw was offered")
+	// ErrNoDrawClaim is returned when claiming a draw the position does not
+	// allow.
+	ErrNoDrawClaim = errors.New("there is no draw to claim")
+)
+
+// Resign ends the game with the player losing.
+func (G *Game) Resign(player piece.Color) error {
+	return G.End([]GameStatus{WhiteResigned, BlackResigned}[player])
+}
+
+// OfferDraw makes a draw offer on behalf of the player. The offer stands
+// until the opponent accepts it, declines it or makes a move.
+func (G *Game) OfferDraw(player piece.Color) error {
+	if G.Status() != InProgress {
+		return ErrGameOver
+	}
+	G.drawOffer[player] = true
+	G.subscribers.publish(DrawOffered{Player: player})
+	return nil
+}
+
+// DrawOffered returns whether the player has a draw offer standing.
+func (G *Game) DrawOffered(player piece.Color) bool {
+	return G.drawOffer[player] && G.Status() == InProgress
+}
+
+// AcceptDraw ends the game in a draw if the player's opponent offered one.
+func (G *Game) AcceptDraw(player piece.Color) error {
+	if !G.DrawOffered(1 - player) {
+		if G.Status() != InProgress {
+			return ErrGameOver
+		}
+		return ErrNoDrawOffer
+	}
+	return G.End(DrawByAgreement)
+}
+
+// DeclineDraw withdraws the draw offer of the player's opponent.
+func (G *Game) DeclineDraw(player piece.Color) error {
+	if !G.DrawOffered(1 - player) {
+		return ErrNoDrawOffer
+	}
+	G.drawOffer[1-player] = false
+	return nil
+}
+
+// ClaimDraw ends the game with the draw returned by ClaimableDraw, if there
+// is one, and returns how it ended. Under rules that end the game with that
+// draw by themselves, the claim only makes the ending final. Claiming after
+// going back with GoTo replaces the moves that followed, like a move would.
+func (G *Game) ClaimDraw() (GameStatus, error) {
+	if G.over != 0 && G.undone == 0 {
+		return G.over, ErrGameOver
+	}
+	status := G.ClaimableDraw()
+	if current := G.Status(); current != InProgress && current != status {
+		return current, ErrGameOver
+	}
+	if status == InProgress {
+		return status, ErrNoDrawClaim
+	}
+	G.changeStatus(func() {
+		G.truncate(G.Ply())
+		G.over = status
+	})
+	return status, nil
+}
+
+// End ends a game that is still in progress with the status, which should be
+// one of the finished ones. It is meant for outcomes decided off the board,
+// like time forfeits, disconnects or adjudication.
+func (G *Game) End(status GameStatus) error {
+	if G.Status() != InProgress {
+		return ErrGameOver
+	}
+	G.changeStatus(func() { G.over = status })
+	return nil
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"testing"
+)
+
+func play(g *Game, moves ...string) error {
+	for _, san := range moves {
+		m, err := g.Position().ParseMove(san)
+		if err != nil {
+			return err
+		}
+		if _, err := g.MakeMove(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestResign(t *testing.T) {
+	g := New()
+	if err := g.Resign(piece.White); err != nil {
+		t.Fatal(err)
+	}
+	if g.Status() != WhiteResigned || g.Result() != "0-1" {
+		t.Error("got", g.Status(), g.Result())
+	}
+	if err := play(g, "e4"); err != ErrGameOver {
+		t.Error("moved after resigning:", err)
+	}
+	if err := g.Resign(piece.Black); err != ErrGameOver {
+		t.Error("resigned twice:", err)
+	}
+}
+
+func TestDrawOffer(t *testing.T) {
+	g := New()
+	if err := g.AcceptDraw(piece.Black); err != ErrNoDrawOffer {
+		t.Error("accepted a draw that was not offered:", err)
+	}
+	play(g, "e4")
+	g.OfferDraw(piece.White)
+	play(g, "e5")
+	if g.DrawOffered(piece.White) {
+		t.Error("moving should decline the offer")
+	}
+	g.OfferDraw(piece.White)
+	if err := g.AcceptDraw(piece.White); err != ErrNoDrawOffer {
+		t.Error("accepted our own offer:", err)
+	}
+	if err := g.AcceptDraw(piece.Black); err != nil {
+		t.Fatal(err)
+	}
+	if g.Status() != DrawByAgreement || g.Result() != "1/2-1/2" {
+		t.Error("got", g.Status(), g.Result())
+	}
+}
+
+func TestClaimDraw(t *testing.T) {
+	g := New()
+	if _, err := g.ClaimDraw(); err != ErrNoDrawClaim {
+		t.Error("claimed a draw from the start:", err)
+	}
+	play(g, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8", "Nf3")
+	if status, err := g.ClaimDraw(); status != Threefold || err != nil {
+		t.Error("got", status, err)
+	}
+	if err := play(g, "e5"); err != ErrGameOver {
+		t.Error("moved after the draw:", err)
+	}
+}
+
+func TestClaimDrawAfterUndo(t *testing.T) {
+	g := New()
+	play(g, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "e5")
+	g.Resign(piece.White)
+	if err := g.GoTo(9); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := g.ClaimDraw(); status != Threefold || err != nil {
+		t.Error("got", status, err)
+	}
+	if g.Plies() != 9 || g.Status() != Threefold {
+		t.Error("got", g.Plies(), g.Status())
+	}
+}
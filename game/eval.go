@@ -0,0 +1,39 @@
+package game
+
+import "strconv"
+
+// Eval is an engine evaluation from White's point of view.
+type Eval struct {
+	Centipawns int
+	// Mate is the number of moves to mate, negative when Black mates. It is
+	// zero when there is no mate.
+	Mate int
+}
+
+// String returns the evaluation in pawns, like 0.35, or as the moves to mate,
+// like #-3.
+func (e Eval) String() string {
+	if e.Mate != 0 {
+		return "#" + strconv.Itoa(e.Mate)
+	}
+	return strconv.FormatFloat(float64(e.Centipawns)/100, 'f', 2, 64)
+}
+
+// SetEval records the evaluation of the position at the ply, usually the one
+// the engine that made the move reported for it.
+func (G *Game) SetEval(ply int, e Eval) error {
+	if ply < 0 || ply >= len(G.Positions) {
+		return ErrPlyOutOfRange
+	}
+	if G.evals == nil {
+		G.evals = make(map[int]Eval)
+	}
+	G.evals[ply] = e
+	return nil
+}
+
+// Eval returns the evaluation recorded for the position at the ply.
+func (G *Game) Eval(ply int) (Eval, bool) {
+	e, ok := G.evals[ply]
+	return e, ok
+}
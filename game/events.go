@@ -0,0 +1,199 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"sync"
+	"time"
+)
+
+// Event is something that happened in a game. It is one of MoveMade,
+// ClockUpdated, DrawOffered, StatusChanged and GameOver.
+type Event interface {
+	event()
+}
+
+// MoveMade is published after a move is made.
+type MoveMade struct {
+	Ply    int
+	Player piece.Color
+	Move   move.Move
+	SAN    string
+	// Captured is the piece the move took, if any.
+	Captured piece.Piece
+	// Check is whether the move gives check.
+	Check bool
+	// Position is the position after the move.
+	Position *position.Position
+}
+
+// ClockUpdated is published after a move in a timed game with the time
+// left for both players.
+type ClockUpdated struct {
+	Clocks map[piece.Color]time.Duration
+}
+
+// DrawOffered is published when a player offers a draw.
+type DrawOffered struct {
+	Player piece.Color
+}
+
+// StatusChanged is published when the status of the game changes, be it by
+// a move, an ending off the board or moves being taken back.
+type StatusChanged struct {
+	From, To GameStatus
+}
+
+// GameOver is published when the game ends.
+type GameOver struct {
+	Status GameStatus
+}
+
+func (MoveMade) event()      {}
+func (ClockUpdated) event()  {}
+func (DrawOffered) event()   {}
+func (StatusChanged) event() {}
+func (GameOver) event()      {}
+
+// subscribers holds the functions events are published to.
+type subscribers struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]func(Event)
+}
+
+// Subscribe calls the function with every event of the game until the
+// returned cancel function is called. The function is called on the
+// goroutine that changed the game, after the change, and may be called
+// while other subscribers are. It is safe to subscribe and cancel from any
+// goroutine.
+func (G *Game) Subscribe(f func(Event)) (cancel func()) {
+	s := G.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[int]func(Event))
+	}
+	id := s.next
+	s.next++
+	s.subs[id] = f
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, id)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Events returns a channel that receives the events of the game until the
+// returned cancel function is called, which closes it. The game waits for
+// the channel to have room for each event, so it should be read promptly or
+// given a buffer.
+func (G *Game) Events(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	done := make(chan struct{})
+	var mu sync.RWMutex
+	closed := false
+	unsubscribe := G.Subscribe(func(e Event) {
+		mu.RLock()
+		defer mu.RUnlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		case <-done:
+		}
+	})
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			unsubscribe()
+			// Let events being sent give up before closing the channel:
+			close(done)
+			mu.Lock()
+			closed = true
+			close(ch)
+			mu.Unlock()
+		})
+	}
+}
+
+// watched returns whether anyone subscribed to the events.
+func (s *subscribers) watched() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subs) > 0
+}
+
+// publish calls the subscribers with the events, in order.
+func (s *subscribers) publish(events ...Event) {
+	s.mu.RLock()
+	subs := make([]func(Event), 0, len(s.subs))
+	for _, f := range s.subs {
+		subs = append(subs, f)
+	}
+	s.mu.RUnlock()
+	for _, e := range events {
+		for _, f := range subs {
+			f(e)
+		}
+	}
+}
+
+// changeStatus makes the change to the game and publishes the change of
+// status it leads to, if any.
+func (G *Game) changeStatus(change func()) {
+	if !G.subscribers.watched() {
+		change()
+		return
+	}
+	before := G.Status()
+	change()
+	G.subscribers.publish(G.statusEvents(before)...)
+}
+
+// statusEvents returns the events for the status changing from before to the
+// current one.
+func (G *Game) statusEvents(before GameStatus) []Event {
+	after := G.Status()
+	if after == before {
+		return nil
+	}
+	events := []Event{StatusChanged{From: before, To: after}}
+	if after != InProgress {
+		events = append(events, GameOver{Status: after})
+	}
+	return events
+}
+
+// moveEvents returns the events for the move that led to the current
+// position.
+func (G *Game) moveEvents(before GameStatus) []Event {
+	prev, cur := G.Positions[G.Ply()-1], G.Position()
+	m := cur.LastMove
+	captured := prev.OnSquare(m.To())
+	if mover := prev.OnSquare(m.From()); mover.Type == piece.Pawn && m.To() == prev.EnPassant {
+		captured = piece.New(1-mover.Color, piece.Pawn)
+	}
+	events := []Event{MoveMade{
+		Ply:      G.Ply(),
+		Player:   prev.ActiveColor,
+		Move:     m,
+		SAN:      prev.SAN(m),
+		Captured: captured,
+		Check:    cur.Check(cur.ActiveColor),
+		Position: cur,
+	}}
+	if G.control != nil {
+		clocks := make(map[piece.Color]time.Duration)
+		for c, d := range cur.Clocks {
+			clocks[c] = d
+		}
+		events = append(events, ClockUpdated{Clocks: clocks})
+	}
+	return append(events, G.statusEvents(before)...)
+}
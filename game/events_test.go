@@ -0,0 +1,83 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMoveEvents(t *testing.T) {
+	g := New()
+	var events []Event
+	cancel := g.Subscribe(func(e Event) { events = append(events, e) })
+	play(g, "e4", "d5", "exd5")
+	if len(events) != 3 {
+		t.Fatal("got", events)
+	}
+	m := events[2].(MoveMade)
+	if m.SAN != "exd5" || m.Ply != 3 || m.Player != piece.White || m.Captured != piece.New(piece.Black, piece.Pawn) || m.Check {
+		t.Error("got", m)
+	}
+	cancel()
+	play(g, "Qxd5")
+	if len(events) != 3 {
+		t.Error("got events after canceling")
+	}
+}
+
+func TestGameOverEvents(t *testing.T) {
+	g := New()
+	var events []Event
+	g.Subscribe(func(e Event) { events = append(events, e) })
+	play(g, "f3", "e5", "g4", "Qh4")
+	want := []Event{StatusChanged{From: InProgress, To: WhiteCheckmated}, GameOver{Status: WhiteCheckmated}}
+	if len(events) != 6 || !events[3].(MoveMade).Check || events[4] != want[0] || events[5] != want[1] {
+		t.Fatal("got", events)
+	}
+	g.Undo()
+	if events[6] != (StatusChanged{From: WhiteCheckmated, To: InProgress}) {
+		t.Error("got", events[6])
+	}
+	g.OfferDraw(piece.Black)
+	g.AcceptDraw(piece.White)
+	if events[7] != (DrawOffered{Player: piece.Black}) || events[9] != (GameOver{Status: DrawByAgreement}) {
+		t.Error("got", events[7:])
+	}
+}
+
+func TestClockEvents(t *testing.T) {
+	tc := TimeControl{Time: time.Minute, Increment: time.Second}
+	g := NewTimedGame(map[piece.Color]TimeControl{piece.White: tc, piece.Black: tc})
+	ch, cancel := g.Events(4)
+	m, _ := g.Position().ParseMove("e4")
+	m.Duration = 10 * time.Second
+	g.MakeMove(m)
+	cancel()
+	<-ch
+	if c := (<-ch).(ClockUpdated); c.Clocks[piece.White] != 51*time.Second || c.Clocks[piece.Black] != time.Minute {
+		t.Error("got", c)
+	}
+	if _, open := <-ch; open {
+		t.Error("the channel was not closed")
+	}
+}
+
+func TestConcurrentSubscribers(t *testing.T) {
+	g := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch, cancel := g.Events(0)
+			go func() {
+				for range ch {
+				}
+			}()
+			cancel()
+		}()
+	}
+	play(g, "e4", "e5", "Nf3", "Nc6")
+	wg.Wait()
+}
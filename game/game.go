@@ -1,4 +1,9 @@
 // Package game handles chess games. You can create timed and untimed games.
+//
+// A Game is not safe for concurrent use, but the positions it holds are never
+// changed once a later move was made from them, and Copy and Fork give deep
+// copies that share nothing with the original. Wrap a game in a Session to
+// play moves on one goroutine while others read it.
 package game
 
 import (
@@ -16,15 +21,30 @@ type Game struct {
 	Tags      map[string]string
 	control   map[piece.Color]TimeControl
 	Positions []*position.Position
+	// Rules decides which draws end the game by themselves.
+	Rules RuleSet
+	// over is the status of a game that ended off the board, like by
+	// resignation or agreement. It is zero while the board decides.
+	over GameStatus
+	// evals are the evaluations of the positions, keyed on their ply.
+	evals map[int]Eval
+	// drawOffer is which players have a draw offer standing.
+	drawOffer [2]bool
+	// undone is how many of the last positions are ahead of the current one
+	// after going back with GoTo.
+	undone int
+	// subscribers are called with the events of the game.
+	subscribers *subscribers
 }
 
 // New returns a fresh game with all of the pieces in the
 // opening position.
 func New() *Game {
 	return &Game{
-		control:   nil,
-		Tags:      make(map[string]string),
-		Positions: []*position.Position{position.New()},
+		control:     nil,
+		Tags:        make(map[string]string),
+		Positions:   []*position.Position{position.New()},
+		subscribers: &subscribers{},
 	}
 }
 
@@ -32,7 +52,10 @@ func New() *Game {
 // time control to what is specified.
 func NewTimedGame(control map[piece.Color]TimeControl) *Game {
 	g := New()
-	g.control = control
+	g.control = make(map[piece.Color]TimeControl)
+	for c, tc := range control {
+		g.control[c] = tc
+	}
 	g.Position().Clocks[piece.White] = control[piece.White].Time
 	g.Position().MovesLeft[piece.White] = control[piece.White].Moves
 	g.Position().Clocks[piece.Black] = control[piece.Black].Time
@@ -42,7 +65,7 @@ func NewTimedGame(control map[piece.Color]TimeControl) *Game {
 
 // Position returns the current position of the game.
 func (G *Game) Position() *position.Position {
-	return G.Positions[len(G.Positions)-1]
+	return G.Positions[G.Ply()]
 }
 
 // ActiveColor returns the color of the player whos turn it is.
@@ -59,23 +82,68 @@ func (G *Game) QuickMove(m move.Move) {
 
 // MakeMove makes the specified move on the game position. Game state information
 // such as the en passant square, castling rights, 50 move rule count are also adjusted.
-// The game status after the given move is made is returned.
+// The game status after the given move is made is returned. Moves are rejected
+// with ErrGameOver once the game has ended. Making a move after going back
+// with GoTo replaces the moves that followed. Subscribers are told about the
+// move once it is made.
 func (G *Game) MakeMove(m move.Move) (GameStatus, error) {
+	if G.over != 0 && G.undone == 0 {
+		return G.over, ErrGameOver
+	}
 	from, to, movingPiece, capturedPiece := G.decompose(m)
 	if G.illegalMove(movingPiece, m) {
 		return G.illegalMoveStatus(), fmt.Errorf("%s illegal move %s", G.Position().ActiveColor, m)
 	}
+	watched := G.subscribers.watched()
+	var before GameStatus
+	if watched {
+		before = G.Status()
+	}
 	G.makeMove(m, from, to, movingPiece, capturedPiece)
+	// Moving instead of accepting declines the opponent's draw offer:
+	G.drawOffer[1-movingPiece.Color] = false
 	if G.Position().Clocks[movingPiece.Color] < 0 {
-		return map[piece.Color]GameStatus{piece.White: WhiteTimedOut, piece.Black: BlackTimedOut}[movingPiece.Color], nil
+		G.over = map[piece.Color]GameStatus{piece.White: WhiteTimedOut, piece.Black: BlackTimedOut}[movingPiece.Color]
+	} else {
+		G.adjustClocks(movingPiece.Color, m.Duration)
 	}
-	G.Position().Clocks[movingPiece.Color] += G.control[movingPiece.Color].Increment
-	if G.Position().MovesLeft[movingPiece.Color] <= 0 && G.control[movingPiece.Color].Repeating {
-		G.Position().MovesLeft[movingPiece.Color] = G.control[movingPiece.Color].Moves
+	if watched {
+		G.subscribers.publish(G.moveEvents(before)...)
 	}
 	return G.Status(), nil
 }
 
+// adjustClocks gives the player the time the time control adds after a move.
+func (G *Game) adjustClocks(player piece.Color, used time.Duration) {
+	tc := G.control[player].stageAfter(G.movesMade(player) - 1)
+	G.Position().Clocks[player] += tc.Increment
+	if tc.Hourglass {
+		G.Position().Clocks[1-player] += used
+	}
+	if G.Position().MovesLeft[player] <= 0 {
+		if next := tc.next(); next != nil {
+			// Only the periods that follow with Then bring time of their own.
+			// A Repeating control starts counting its moves over, as it always
+			// has.
+			if tc.Then != nil {
+				G.Position().Clocks[player] += next.Time
+			}
+			G.Position().MovesLeft[player] = next.Moves
+		}
+	}
+}
+
+// movesMade returns how many moves the player made up to the current position.
+func (G *Game) movesMade(player piece.Color) int {
+	n := 0
+	for i := 1; i <= G.Ply(); i++ {
+		if G.Positions[i].LastMove != move.Null && G.Positions[i-1].ActiveColor == player {
+			n++
+		}
+	}
+	return n
+}
+
 func (G *Game) decompose(m move.Move) (from, to square.Square, movingPiece, capturedPiece piece.Piece) {
 	from, to = m.From(), m.To()
 	movingPiece = G.Position().OnSquare(from)
@@ -84,12 +152,16 @@ func (G *Game) decompose(m move.Move) (from, to square.Square, movingPiece, capt
 }
 
 func (G *Game) makeMove(m move.Move, from, to square.Square, movingPiece, capturedPiece piece.Piece) {
+	G.truncate(G.Ply())
 	newPos := G.Position().MakeMove(m)
 	G.Positions = append(G.Positions, newPos)
 }
 
 // Status returns the game's status.
 func (G *Game) Status() GameStatus {
+	if G.over != 0 && G.undone == 0 {
+		return G.over
+	}
 	activeColor := G.ActiveColor()
 	check, stale := G.Check(activeColor), len(G.LegalMoves()) == 0
 	if stale && check {
@@ -98,16 +170,7 @@ func (G *Game) Status() GameStatus {
 	if stale {
 		return Stalemate
 	}
-	if G.threeFold() {
-		return Threefold
-	}
-	if G.Position().FiftyMoveCount >= 100 { // we keep track of it in half moves, start at 0
-		return FiftyMoveRule
-	}
-	if G.Position().InsufficientMaterial() {
-		return InsufficientMaterial
-	}
-	return InProgress
+	return G.automaticDraw()
 }
 
 func (G *Game) illegalMove(p piece.Piece, m move.Move) bool {
@@ -130,12 +193,8 @@ func (G *Game) illegalMoveStatus() GameStatus {
 
 // TODO(andrewbackes): threeFold detection should not have to go through all of the move history.
 // BUG(andrewbackes): starting FEN is not considered when calculating threefold.
-func (G *Game) threeFold() bool {
-	hash := G.Position().Polyglot()
-	if G.Position().ThreeFoldCount[hash] >= 3 {
-		return true
-	}
-	return false
+func (G *Game) repetitions() int {
+	return G.Position().ThreeFoldCount[G.Position().Polyglot()]
 }
 
 // Check returns whether or not the specified color is in check.
@@ -206,6 +265,19 @@ func (G *Game) Clock(player piece.Color) time.Duration {
 	return G.Position().Clocks[player]
 }
 
+// TimeControl returns the time control of the player. It is the zero value
+// for untimed games.
+func (G *Game) TimeControl(player piece.Color) TimeControl {
+	return G.control[player]
+}
+
+// Stage returns the period of the player's time control being played, which
+// differs from TimeControl once the player reached a control with more
+// periods to follow.
+func (G *Game) Stage(player piece.Color) TimeControl {
+	return G.control[player].stageAfter(G.movesMade(player))
+}
+
 // MovesLeft returns the number of moves left until time control.
 func (G *Game) MovesLeft(player piece.Color) int {
 	return G.Position().MovesLeft[player]
@@ -213,14 +285,5 @@ func (G *Game) MovesLeft(player piece.Color) int {
 
 // Result returns a human readable
 func (G *Game) Result() string {
-	if WhiteWon&G.Status() != 0 {
-		return "1-0"
-	}
-	if BlackWon&G.Status() != 0 {
-		return "0-1"
-	}
-	if Draw&G.Status() != 0 {
-		return "1/2-1/2"
-	}
-	return "*"
+	return G.Status().Result()
 }
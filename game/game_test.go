@@ -34,7 +34,7 @@ func ExampleGame() {
 	// Output: true
 }
 
-func ExampleLegalMoves() {
+func ExampleGame_LegalMoves() {
 	game, _ := gameFromFEN("8/8/1KP5/3r4/8/8/8/k7 w - - 0 1")
 	moves := game.LegalMoves()
 	fmt.Println(moves)
@@ -178,6 +178,19 @@ func TestTimeReset(t *testing.T) {
 	}
 }
 
+func TestTimeResetKeepsClock(t *testing.T) {
+	g := timedTestGame()
+	for _, s := range []string{"e2e4", "e7e5", "d2d4", "d7d5"} {
+		m := move.Parse(s)
+		m.Duration = 5 * time.Minute
+		g.MakeMove(m)
+	}
+	// A Repeating control only gives the increment, not its time again:
+	if c := g.Clock(piece.White); c != 40*time.Minute {
+		t.Error("should have 40 min on clock but have", c)
+	}
+}
+
 func TestFiftyMoveRule(t *testing.T) {
 	fen := "8/8/2B2k2/8/3r1NKp/3N4/8/8 b - - 0 62"
 	g, _ := gameFromFEN(fen)
@@ -213,6 +226,60 @@ func TestThreeFold(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	// Under FIDE rules the repetition has to be claimed:
+	g = New()
+	g.Rules = FIDE
+	if err := playTestGame(t, g, moves, InProgress); err != nil {
+		t.Error(err)
+	}
+	if g.ClaimableDraw() != Threefold {
+		t.Error("threefold repetition should be claimable, got", g.ClaimableDraw())
+	}
+}
+
+func TestFivefold(t *testing.T) {
+	g := New()
+	g.Rules = FIDE
+	var moves []string
+	for i := 0; i < 4; i++ {
+		moves = append(moves, "Nf3", "Nf6", "Ng1", "Ng8")
+	}
+	moves = append(moves, "Nf3")
+	if err := playTestGame(t, g, moves, FivefoldRepetition); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSeventyFiveMoveRule(t *testing.T) {
+	g, _ := gameFromFEN("8/8/8/8/8/8/R7/k1K5 w - - 0 1")
+	g.Rules = FIDE
+	g.Position().FiftyMoveCount = 149
+	if err := playTestGame(t, g, []string{"Kd2"}, SeventyFiveMoveRule); err != nil {
+		t.Error(err)
+	}
+	g, _ = gameFromFEN("8/8/8/8/8/8/R7/k1K5 w - - 0 1")
+	g.Rules = FIDE
+	g.Position().FiftyMoveCount = 149
+	if err := playTestGame(t, g, []string{"Ra8"}, BlackCheckmated); err != nil {
+		t.Error("checkmate should take precedence:", err)
+	}
+}
+
+func TestInsufficientMaterialRules(t *testing.T) {
+	for rules, claimable := range map[RuleSet]GameStatus{FIDE: InProgress, USCF: InsufficientMaterial, EngineTesting: InProgress} {
+		g, _ := gameFromFEN("8/8/8/4k3/8/8/8/1NNK4 w - - 0 1")
+		g.Rules = rules
+		if g.Status() != InProgress || g.ClaimableDraw() != claimable {
+			t.Error(rules, "got", g.Status(), g.ClaimableDraw(), "wanted", InProgress, claimable)
+		}
+	}
+	for rules, expected := range map[RuleSet]GameStatus{FIDE: DeadPosition, USCF: DeadPosition, EngineTesting: InsufficientMaterial} {
+		g, _ := gameFromFEN("8/8/8/4k3/8/8/8/1N1K4 w - - 0 1")
+		g.Rules = rules
+		if g.Status() != expected {
+			t.Error(rules, "got", g.Status(), "wanted", expected)
+		}
+	}
 }
 
 func TestStalemate(t *testing.T) {
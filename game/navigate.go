@@ -0,0 +1,109 @@
+package game
+
+import (
+	"errors"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+)
+
+var (
+	// ErrNoMove is returned when undoing a move before the first one.
+	ErrNoMove = errors.New("there is no move to undo")
+	// ErrPlyOutOfRange is returned for plies the game does not have.
+	ErrPlyOutOfRange = errors.New("ply is out of range")
+)
+
+// Ply returns the number of the current position, counted in half moves
+// from the first position of the game.
+func (G *Game) Ply() int {
+	return len(G.Positions) - 1 - G.undone
+}
+
+// Plies returns the number of the last position of the game, which differs
+// from Ply after going back with GoTo.
+func (G *Game) Plies() int {
+	return len(G.Positions) - 1
+}
+
+// GoTo makes the position at the ply the current one. The moves that follow
+// are kept, so GoTo can return to them, until a different move is made.
+func (G *Game) GoTo(ply int) error {
+	if ply < 0 || ply >= len(G.Positions) {
+		return ErrPlyOutOfRange
+	}
+	G.changeStatus(func() { G.undone = len(G.Positions) - 1 - ply })
+	return nil
+}
+
+// Undo takes back the last move of the game. Any ending off the board, like
+// a resignation, and any draw offer are taken back with it.
+func (G *Game) Undo() error {
+	if len(G.Positions) < 2 {
+		return ErrNoMove
+	}
+	return G.Truncate(len(G.Positions) - 2)
+}
+
+// Truncate removes the moves after the ply, making it the last and current
+// position. Removing moves also removes any ending off the board and any
+// draw offer.
+func (G *Game) Truncate(ply int) error {
+	if ply < 0 || ply >= len(G.Positions) {
+		return ErrPlyOutOfRange
+	}
+	G.changeStatus(func() { G.truncate(ply) })
+	return nil
+}
+
+// truncate is Truncate without the events, for a ply known to be in range.
+func (G *Game) truncate(ply int) {
+	if ply < len(G.Positions)-1 {
+		G.Positions = G.Positions[:ply+1]
+		G.over, G.drawOffer = 0, [2]bool{}
+		for i := range G.evals {
+			if i > ply {
+				delete(G.evals, i)
+			}
+		}
+	}
+	G.undone = 0
+}
+
+// Fork returns a new game that has the moves of this one up to the ply.
+// The two games can be played on independently, and the fork starts without
+// subscribers.
+func (G *Game) Fork(ply int) (*Game, error) {
+	if ply < 0 || ply >= len(G.Positions) {
+		return nil, ErrPlyOutOfRange
+	}
+	f := &Game{
+		Tags:        make(map[string]string),
+		Positions:   make([]*position.Position, ply+1),
+		Rules:       G.Rules,
+		subscribers: &subscribers{},
+	}
+	for k, v := range G.Tags {
+		f.Tags[k] = v
+	}
+	if G.control != nil {
+		f.control = make(map[piece.Color]TimeControl)
+		for c, tc := range G.control {
+			f.control[c] = tc
+		}
+	}
+	for i := range f.Positions {
+		f.Positions[i] = position.Copy(G.Positions[i])
+		if e, ok := G.evals[i]; ok {
+			f.SetEval(i, e)
+		}
+	}
+	return f, nil
+}
+
+// Copy returns a deep copy of the game, at the same ply and with the same
+// status, that shares nothing with it. The copy starts without subscribers.
+func (G *Game) Copy() *Game {
+	c, _ := G.Fork(G.Plies())
+	c.over, c.drawOffer, c.undone = G.over, G.drawOffer, G.undone
+	return c
+}
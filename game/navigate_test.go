@@ -0,0 +1,134 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"testing"
+	"time"
+)
+
+func TestUndo(t *testing.T) {
+	g := New()
+	if err := g.Undo(); err != ErrNoMove {
+		t.Error("undid a move before the first:", err)
+	}
+	play(g, "e4", "e5")
+	g.Resign(piece.White)
+	if err := g.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Status() != InProgress || g.Ply() != 1 || g.ActiveColor() != piece.Black {
+		t.Error("got", g.Status(), g.Ply(), g.ActiveColor())
+	}
+	if err := play(g, "c5"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUndoRestoresRepetitions(t *testing.T) {
+	g := New()
+	play(g, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8", "Nf3")
+	if g.ClaimableDraw() != Threefold {
+		t.Fatal("got", g.ClaimableDraw())
+	}
+	g.Undo()
+	if g.ClaimableDraw() != InProgress {
+		t.Error("the repetition should be undone, got", g.ClaimableDraw())
+	}
+}
+
+func TestUndoRestoresClocks(t *testing.T) {
+	tc, _ := ParseTimeControl("1/60:30+1")
+	g := NewTimedGame(map[piece.Color]TimeControl{piece.White: tc, piece.Black: tc})
+	for _, s := range []string{"e2e4", "e7e5", "d2d4"} {
+		m := move.Parse(s)
+		m.Duration = 10 * time.Second
+		g.MakeMove(m)
+	}
+	g.Undo()
+	g.Undo()
+	if g.Clock(piece.White) != 80*time.Second || g.Stage(piece.White).Time != 30*time.Second {
+		t.Error("got", g.Clock(piece.White), g.Stage(piece.White))
+	}
+	g.Undo()
+	if g.Clock(piece.White) != time.Minute || g.Stage(piece.White).Moves != 1 {
+		t.Error("got", g.Clock(piece.White), g.Stage(piece.White))
+	}
+}
+
+func TestGoTo(t *testing.T) {
+	g := New()
+	play(g, "e4", "e5", "Nf3")
+	if err := g.GoTo(4); err != ErrPlyOutOfRange {
+		t.Error("went past the last ply:", err)
+	}
+	g.GoTo(1)
+	if g.Ply() != 1 || g.Plies() != 3 || g.ActiveColor() != piece.Black {
+		t.Error("got", g.Ply(), g.Plies(), g.ActiveColor())
+	}
+	g.GoTo(3)
+	if g.Position() != g.Positions[3] {
+		t.Error("should be back at the last position")
+	}
+	g.GoTo(1)
+	play(g, "c5")
+	if g.Plies() != 2 || g.Ply() != 2 {
+		t.Error("a new move should replace the ones that followed, got", g.Plies(), g.Ply())
+	}
+}
+
+func TestGoToAfterGameOver(t *testing.T) {
+	g := New()
+	play(g, "f3", "e5", "g4", "Qh4#")
+	g.GoTo(2)
+	if g.Status() != InProgress {
+		t.Error("got", g.Status())
+	}
+	if err := play(g, "d4"); err != nil {
+		t.Error("should be able to branch from an earlier position:", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	g := New()
+	play(g, "e4", "e5", "Nf3")
+	g.SetEval(1, Eval{Centipawns: 30})
+	g.SetEval(3, Eval{Centipawns: 40})
+	g.OfferDraw(piece.White)
+	if err := g.Truncate(1); err != nil {
+		t.Fatal(err)
+	}
+	if g.Plies() != 1 || g.DrawOffered(piece.White) {
+		t.Error("got", g.Plies(), g.DrawOffered(piece.White))
+	}
+	if _, ok := g.Eval(3); ok {
+		t.Error("kept the evaluation of a removed position")
+	}
+	if e, ok := g.Copy().Eval(1); !ok || e.Centipawns != 30 {
+		t.Error("got", e, ok)
+	}
+	if err := g.Truncate(2); err != ErrPlyOutOfRange {
+		t.Error("got", err)
+	}
+}
+
+func TestFork(t *testing.T) {
+	g := New()
+	g.Tags["Event"] = "fork"
+	play(g, "e4", "e5", "Nf3")
+	f, err := g.Fork(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	play(f, "Nc3")
+	f.Tags["Event"] = "forked"
+	if g.Plies() != 3 || g.Tags["Event"] != "fork" || g.Position().LastMove != move.Parse("g1f3") {
+		t.Error("forking changed the game")
+	}
+	if f.Plies() != 3 || f.Position().LastMove != move.Parse("b1c3") {
+		t.Error("got", f.Plies(), f.Position().LastMove)
+	}
+	if _, err := g.Fork(-1); err != ErrPlyOutOfRange {
+		t.Error("got", err)
+	}
+}
@@ -0,0 +1,65 @@
+package game
+
+// RuleSet decides which draws end a game by themselves and which a player
+// has to claim.
+type RuleSet int
+
+const (
+	// EngineTesting ends the game as soon as a draw could be claimed, which
+	// keeps engines from playing on in drawn positions. Threefold repetition,
+	// the fifty move rule and insufficient material end the game at once.
+	// It is the zero value, and how games have always been scored.
+	EngineTesting RuleSet = iota
+	// FIDE ends the game at fivefold repetition, after 75 moves without a
+	// capture or pawn move and in dead positions. Threefold repetition and
+	// the fifty move rule have to be claimed.
+	FIDE
+	// USCF only ends dead positions by itself. Threefold repetition, the
+	// fifty move rule and insufficient material to force mate have to be
+	// claimed.
+	USCF
+)
+
+func (r RuleSet) String() string {
+	return []string{"Engine testing", "FIDE", "USCF"}[r]
+}
+
+// ClaimableDraw returns the draw a player could claim in the current
+// position, or InProgress if there is none.
+func (G *Game) ClaimableDraw() GameStatus {
+	switch {
+	case G.repetitions() >= 3:
+		return Threefold
+	case G.Position().FiftyMoveCount >= 100: // we keep track of it in half moves, start at 0
+		return FiftyMoveRule
+	case G.Rules == USCF && G.Position().CannotForceMate():
+		return InsufficientMaterial
+	}
+	return InProgress
+}
+
+// automaticDraw returns the draw the rule set ends the game with, or
+// InProgress if there is none.
+func (G *Game) automaticDraw() GameStatus {
+	if G.Rules == EngineTesting {
+		if status := G.ClaimableDraw(); status != InProgress {
+			return status
+		}
+		if G.Position().InsufficientMaterial() {
+			return InsufficientMaterial
+		}
+		return InProgress
+	}
+	if G.Position().DeadPosition() {
+		return DeadPosition
+	}
+	if G.Rules == FIDE {
+		if G.repetitions() >= 5 {
+			return FivefoldRepetition
+		}
+		if G.Position().FiftyMoveCount >= 150 {
+			return SeventyFiveMoveRule
+		}
+	}
+	return InProgress
+}
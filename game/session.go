@@ -0,0 +1,131 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"sync"
+)
+
+// Session guards a game so that any number of goroutines can use it at once,
+// typically one playing the moves while others read the game. The game must
+// not be used directly once it is in a session.
+//
+// Subscribers of the game are called while the session is locked, so they
+// must not call the session's methods. The events they get are safe to keep.
+type Session struct {
+	mu sync.RWMutex
+	g  *Game
+}
+
+// NewSession returns a session for the game.
+func NewSession(g *Game) *Session {
+	return &Session{g: g}
+}
+
+// Snapshot returns a deep copy of the game as it is now.
+func (s *Session) Snapshot() *Game {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Copy()
+}
+
+// Read calls the function with the game while no changes can be made to it.
+// The function must not change the game or keep it after returning.
+func (s *Session) Read(f func(*Game)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(s.g)
+}
+
+// Do calls the function with the game while nothing else can use it.
+func (s *Session) Do(f func(*Game)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.g)
+}
+
+// Position returns a copy of the current position.
+func (s *Session) Position() *position.Position {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return position.Copy(s.g.Position())
+}
+
+// Status returns the game's status.
+func (s *Session) Status() GameStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Status()
+}
+
+// Tag returns the value of the game's tag.
+func (s *Session) Tag(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.g.Tags[name]
+	return v, ok
+}
+
+// SetTag sets the game's tag.
+func (s *Session) SetTag(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.g.Tags[name] = value
+}
+
+// MakeMove makes the move on the game, see Game.MakeMove.
+func (s *Session) MakeMove(m move.Move) (GameStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.MakeMove(m)
+}
+
+// Undo takes back the last move, see Game.Undo.
+func (s *Session) Undo() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.Undo()
+}
+
+// Resign ends the game with the player losing.
+func (s *Session) Resign(player piece.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.Resign(player)
+}
+
+// OfferDraw makes a draw offer on behalf of the player.
+func (s *Session) OfferDraw(player piece.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.OfferDraw(player)
+}
+
+// AcceptDraw ends the game in a draw if the player's opponent offered one.
+func (s *Session) AcceptDraw(player piece.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.AcceptDraw(player)
+}
+
+// DeclineDraw withdraws the draw offer of the player's opponent.
+func (s *Session) DeclineDraw(player piece.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.DeclineDraw(player)
+}
+
+// ClaimDraw ends the game with a claimable draw, see Game.ClaimDraw.
+func (s *Session) ClaimDraw() (GameStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.ClaimDraw()
+}
+
+// End ends the game with the status, see Game.End.
+func (s *Session) End(status GameStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.End(status)
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"sync"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	g := New()
+	g.Tags["Event"] = "original"
+	play(g, "e4", "e5")
+	g.Resign(piece.White)
+	c := g.Copy()
+	if c.Status() != WhiteResigned || c.Ply() != 2 {
+		t.Error("got", c.Status(), c.Ply())
+	}
+	c.Undo()
+	c.Tags["Event"] = "copy"
+	c.Position().Clocks[piece.White] = 1
+	play(c, "Nf6")
+	if g.Status() != WhiteResigned || g.Plies() != 2 || g.Tags["Event"] != "original" || g.Position().Clocks[piece.White] != 0 {
+		t.Error("the copy changed the original")
+	}
+}
+
+func TestSessionConcurrentReads(t *testing.T) {
+	s := NewSession(New())
+	moves := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7"}
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				s.Position().LegalMoves()
+				s.Status()
+				s.Tag("Event")
+				g := s.Snapshot()
+				g.Tags["Event"] = "snapshot"
+				s.Read(func(g *Game) {
+					for _, p := range g.Positions {
+						p.Polyglot()
+					}
+				})
+			}
+		}()
+	}
+	for _, san := range moves {
+		m, err := s.Position().ParseMove(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.MakeMove(m); err != nil {
+			t.Fatal(err)
+		}
+		s.SetTag("Round", san)
+	}
+	s.OfferDraw(piece.White)
+	s.AcceptDraw(piece.Black)
+	close(done)
+	wg.Wait()
+	if s.Status() != DrawByAgreement || s.Snapshot().Plies() != len(moves) {
+		t.Error("got", s.Status())
+	}
+}
@@ -1,46 +1,113 @@
 package game
 
-type GameStatus uint16
+type GameStatus uint32
 
 const (
-	InProgress           GameStatus = 1 << iota
-	BlackCheckmated                 //2
-	WhiteCheckmated                 //4
-	BlackTimedOut                   //8
-	WhiteTimedOut                   //16
-	BlackResigned                   //32
-	WhiteResigned                   //64
-	BlackIllegalMove                //128
-	WhiteIllegalMove                //256
-	Threefold                       //512
-	FiftyMoveRule                   //1024
-	Stalemate                       //2048
-	InsufficientMaterial            //4096
+	InProgress              GameStatus = 1 << iota
+	BlackCheckmated                    //2
+	WhiteCheckmated                    //4
+	BlackTimedOut                      //8
+	WhiteTimedOut                      //16
+	BlackResigned                      //32
+	WhiteResigned                      //64
+	BlackIllegalMove                   //128
+	WhiteIllegalMove                   //256
+	Threefold                          //512
+	FiftyMoveRule                      //1024
+	Stalemate                          //2048
+	InsufficientMaterial               //4096
+	BlackDisconnected                  //8192
+	WhiteDisconnected                  //16384
+	BlackLostByAdjudication            //32768
+	WhiteLostByAdjudication            //65536
+	DrawByAdjudication                 //131072
+	BlackLostByTablebase               //262144
+	WhiteLostByTablebase               //524288
+	DrawByTablebase                    //1048576
+	MaxPliesReached                    //2097152
+	DrawByAgreement                    //4194304
+	FivefoldRepetition                 //8388608
+	SeventyFiveMoveRule                //16777216
+	DeadPosition                       //33554432
 )
 
 func (g GameStatus) String() string {
 	return map[GameStatus]string{
-		InProgress:           "In progress",
-		BlackCheckmated:      "White checkmated Black",
-		WhiteCheckmated:      "Black checkmated White",
-		BlackTimedOut:        "Black ran out of time",
-		WhiteTimedOut:        "White ran out of time",
-		BlackResigned:        "Black resigned",
-		WhiteResigned:        "White resigned",
-		BlackIllegalMove:     "Black made an illegal move",
-		WhiteIllegalMove:     "White made an illegal move",
-		Threefold:            "Draw by threefold repetition",
-		FiftyMoveRule:        "Draw by Fifty move rule",
-		Stalemate:            "Draw by stalemate",
-		InsufficientMaterial: "Draw by insufficient material",
-		WhiteWon:             "White won",
-		BlackWon:             "Black won",
-		Draw:                 "Draw",
+		InProgress:              "In progress",
+		BlackCheckmated:         "White checkmated Black",
+		WhiteCheckmated:         "Black checkmated White",
+		BlackTimedOut:           "Black ran out of time",
+		WhiteTimedOut:           "White ran out of time",
+		BlackResigned:           "Black resigned",
+		WhiteResigned:           "White resigned",
+		BlackIllegalMove:        "Black made an illegal move",
+		WhiteIllegalMove:        "White made an illegal move",
+		Threefold:               "Draw by threefold repetition",
+		FiftyMoveRule:           "Draw by Fifty move rule",
+		Stalemate:               "Draw by stalemate",
+		InsufficientMaterial:    "Draw by insufficient material",
+		BlackDisconnected:       "Black disconnected",
+		WhiteDisconnected:       "White disconnected",
+		BlackLostByAdjudication: "Black lost by adjudication",
+		WhiteLostByAdjudication: "White lost by adjudication",
+		DrawByAdjudication:      "Draw by adjudication",
+		BlackLostByTablebase:    "Black lost by tablebase adjudication",
+		WhiteLostByTablebase:    "White lost by tablebase adjudication",
+		DrawByTablebase:         "Draw by tablebase adjudication",
+		MaxPliesReached:         "Draw by reaching the maximum number of plies",
+		DrawByAgreement:         "Draw by agreement",
+		FivefoldRepetition:      "Draw by fivefold repetition",
+		SeventyFiveMoveRule:     "Draw by seventy-five move rule",
+		DeadPosition:            "Draw by dead position",
+		WhiteWon:                "White won",
+		BlackWon:                "Black won",
+		Draw:                    "Draw",
 	}[g]
 }
 
 const (
-	WhiteWon GameStatus = (BlackCheckmated | BlackTimedOut | BlackResigned | BlackIllegalMove)
-	BlackWon GameStatus = (WhiteCheckmated | WhiteTimedOut | WhiteResigned | WhiteIllegalMove)
-	Draw     GameStatus = (Threefold | FiftyMoveRule | Stalemate | InsufficientMaterial)
+	WhiteWon GameStatus = (BlackCheckmated | BlackTimedOut | BlackResigned | BlackIllegalMove | BlackDisconnected |
+		BlackLostByAdjudication | BlackLostByTablebase)
+	BlackWon GameStatus = (WhiteCheckmated | WhiteTimedOut | WhiteResigned | WhiteIllegalMove | WhiteDisconnected |
+		WhiteLostByAdjudication | WhiteLostByTablebase)
+	Draw GameStatus = (Threefold | FiftyMoveRule | Stalemate | InsufficientMaterial |
+		DrawByAdjudication | DrawByTablebase | MaxPliesReached | DrawByAgreement |
+		FivefoldRepetition | SeventyFiveMoveRule | DeadPosition)
+	// Adjudicated are the statuses of games that were decided by an Adjudicator
+	// rather than by the rules of chess.
+	Adjudicated GameStatus = (BlackLostByAdjudication | WhiteLostByAdjudication | DrawByAdjudication |
+		BlackLostByTablebase | WhiteLostByTablebase | DrawByTablebase | MaxPliesReached)
 )
+
+// Result returns the status as a PGN result: "1-0", "0-1", "1/2-1/2" or "*".
+func (g GameStatus) Result() string {
+	if WhiteWon&g != 0 {
+		return "1-0"
+	}
+	if BlackWon&g != 0 {
+		return "0-1"
+	}
+	if Draw&g != 0 {
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// Termination returns the value of the PGN Termination tag that describes
+// how a game with this status ended.
+func (g GameStatus) Termination() string {
+	switch g {
+	case InProgress:
+		return "unterminated"
+	case BlackTimedOut, WhiteTimedOut:
+		return "time forfeit"
+	case BlackIllegalMove, WhiteIllegalMove:
+		return "rules infraction"
+	case BlackDisconnected, WhiteDisconnected:
+		return "abandoned"
+	}
+	if g&Adjudicated != 0 {
+		return "adjudication"
+	}
+	return "normal"
+}
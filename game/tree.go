@@ -0,0 +1,187 @@
+package game
+
+import (
+	"errors"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// ErrIllegalMove is returned when adding an illegal move to a tree.
+var ErrIllegalMove = errors.New("illegal move")
+
+// Tree is a game with its variations. Every node is a position, and its
+// first child continues the main line while the others are alternatives.
+type Tree struct {
+	Tags map[string]string
+	Root *Node
+}
+
+// Node is a position in a tree along with the move that led to it.
+type Node struct {
+	Position *position.Position
+	// Move is move.Null for the root.
+	Move     move.Move
+	Comments []string
+	// NAGs are the Numeric Annotation Glyphs of the move, like 1 for "!".
+	NAGs     []int
+	Parent   *Node
+	Children []*Node
+}
+
+// NewTree returns a tree that starts at the position.
+func NewTree(start *position.Position) *Tree {
+	return &Tree{
+		Tags: make(map[string]string),
+		Root: &Node{Position: start, Move: move.Null},
+	}
+}
+
+// TreeFromGame returns a tree whose main line is the game.
+func TreeFromGame(g *Game) *Tree {
+	t := NewTree(g.Positions[0])
+	for k, v := range g.Tags {
+		t.Tags[k] = v
+	}
+	n := t.Root
+	for _, p := range g.Positions[1:] {
+		if p.LastMove == move.Null {
+			// A position set up without a move starts the game over:
+			n.Position = p
+			continue
+		}
+		child := &Node{Position: p, Move: p.LastMove, Parent: n}
+		n.Children = append(n.Children, child)
+		n = child
+	}
+	return t
+}
+
+// Game returns the main line of the tree as a game.
+func (t *Tree) Game() *Game {
+	g := New()
+	for k, v := range t.Tags {
+		g.Tags[k] = v
+	}
+	g.Positions = nil
+	for _, n := range t.Mainline() {
+		g.Positions = append(g.Positions, n.Position)
+	}
+	return g
+}
+
+// Mainline returns the nodes of the main line, starting with the root.
+func (t *Tree) Mainline() []*Node {
+	var line []*Node
+	for n := t.Root; n != nil; n = n.Next() {
+		line = append(line, n)
+	}
+	return line
+}
+
+// Walk visits the nodes of the tree depth first, main lines before
+// variations.
+func (t *Tree) Walk(visit func(*Node) bool) {
+	t.Root.Walk(visit)
+}
+
+// Next returns the node that continues the main line from this one, or nil
+// at the end of the line.
+func (n *Node) Next() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// AddMove adds the move as a child of the node and returns the new node.
+// The first move added continues the main line and later ones are
+// variations. If the move was added before, its node is returned.
+func (n *Node) AddMove(m move.Move) (*Node, error) {
+	m.Duration = 0
+	if _, ok := n.Position.LegalMoves()[m]; !ok {
+		return nil, ErrIllegalMove
+	}
+	for _, child := range n.Children {
+		if child.Move == m {
+			return child, nil
+		}
+	}
+	child := &Node{Position: n.Position.MakeMove(m), Move: m, Parent: n}
+	n.Children = append(n.Children, child)
+	return child, nil
+}
+
+// Ply returns how many moves lead from the root to the node.
+func (n *Node) Ply() int {
+	ply := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		ply++
+	}
+	return ply
+}
+
+// IsMainline returns whether the node is on the main line of the tree.
+func (n *Node) IsMainline() bool {
+	for c := n; c.Parent != nil; c = c.Parent {
+		if c.Parent.Children[0] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// index returns where the node is among its siblings.
+func (n *Node) index() int {
+	for i, sibling := range n.Parent.Children {
+		if sibling == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Promote moves the node's variation one place up among its siblings. The
+// first variation becomes the main line.
+func (n *Node) Promote() {
+	if n.Parent == nil {
+		return
+	}
+	if i := n.index(); i > 0 {
+		siblings := n.Parent.Children
+		siblings[i-1], siblings[i] = siblings[i], siblings[i-1]
+	}
+}
+
+// PromoteToMainline makes the line leading to the node the main line of
+// the tree, keeping the lines it replaces as variations.
+func (n *Node) PromoteToMainline() {
+	for c := n; c.Parent != nil; c = c.Parent {
+		if i := c.index(); i > 0 {
+			siblings := c.Parent.Children
+			copy(siblings[1:i+1], siblings[:i])
+			siblings[0] = c
+		}
+	}
+}
+
+// Delete removes the node and everything that follows it from the tree.
+func (n *Node) Delete() {
+	if n.Parent == nil {
+		return
+	}
+	i := n.index()
+	n.Parent.Children = append(n.Parent.Children[:i], n.Parent.Children[i+1:]...)
+	n.Parent = nil
+}
+
+// Walk visits the node and the nodes that follow it depth first, main lines
+// before variations. The nodes following a node are skipped when visit
+// returns false for it.
+func (n *Node) Walk(visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.Walk(visit)
+	}
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"testing"
+)
+
+// line adds the moves one after the other from the node.
+func line(n *Node, moves ...string) (*Node, error) {
+	for _, san := range moves {
+		m, err := n.Position.ParseMove(san)
+		if err != nil {
+			return nil, err
+		}
+		if n, err = n.AddMove(m); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func TestTreeVariations(t *testing.T) {
+	tr := NewTree(position.New())
+	e5, err := line(tr.Root, "e4", "e5", "Nf3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c5, err := line(tr.Root.Next(), "c5", "Nf3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := line(tr.Root, "e4"); same != tr.Root.Next() {
+		t.Error("adding a move twice made a new node")
+	}
+	if _, err := tr.Root.AddMove(move.Parse("e2e5")); err != ErrIllegalMove {
+		t.Error("added an illegal move:", err)
+	}
+	if len(tr.Mainline()) != 4 || !e5.IsMainline() || c5.IsMainline() || c5.Ply() != 3 {
+		t.Error("wrong main line", len(tr.Mainline()), e5.IsMainline(), c5.IsMainline(), c5.Ply())
+	}
+	c5.Parent.Promote()
+	if !c5.IsMainline() || e5.IsMainline() {
+		t.Error("promote did not swap the lines")
+	}
+	c5.Parent.Delete()
+	if !e5.IsMainline() || len(tr.Root.Next().Children) != 1 {
+		t.Error("delete did not remove the variation")
+	}
+}
+
+func TestPromoteToMainline(t *testing.T) {
+	tr := NewTree(position.New())
+	line(tr.Root, "e4", "e5")
+	line(tr.Root, "d4", "d5")
+	c4, _ := line(tr.Root, "c4")
+	deep, _ := line(tr.Root.Children[1], "Nf6", "c4")
+	deep.PromoteToMainline()
+	if !deep.IsMainline() || len(tr.Mainline()) != 4 {
+		t.Error("the line was not promoted")
+	}
+	if tr.Root.Children[1].Move != move.Parse("e2e4") || tr.Root.Children[2] != c4 {
+		t.Error("the other lines lost their order")
+	}
+}
+
+func TestTreeWalk(t *testing.T) {
+	tr := NewTree(position.New())
+	line(tr.Root, "e4", "e5")
+	line(tr.Root, "d4", "d5")
+	line(tr.Root.Next(), "c5")
+	var got []string
+	tr.Walk(func(n *Node) bool {
+		if n.Parent != nil {
+			got = append(got, n.Parent.Position.SAN(n.Move))
+		}
+		return n.Move != move.Parse("d2d4")
+	})
+	want := []string{"e4", "e5", "c5", "d4"}
+	if len(got) != len(want) {
+		t.Fatal("got", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatal("got", got)
+		}
+	}
+}
+
+func TestTreeFromGame(t *testing.T) {
+	g := New()
+	g.Tags["Event"] = "test"
+	play(g, "e4", "e5", "Nf3")
+	tr := TreeFromGame(g)
+	if len(tr.Mainline()) != 4 || tr.Tags["Event"] != "test" {
+		t.Error("wrong tree", len(tr.Mainline()))
+	}
+	line(tr.Root, "d4")
+	back := tr.Game()
+	if back.Ply() != 3 || back.Position().Polyglot() != g.Position().Polyglot() || back.Tags["Event"] != "test" {
+		t.Error("wrong game", back.Ply())
+	}
+}
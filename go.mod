@@ -6,3 +6,5 @@ require (
 	github.com/andrewbackes/chess v0.0.0-20171122002438-368c396b5300
 	github.com/stretchr/testify v1.7.0
 )
+
+replace github.com/andrewbackes/chess => ./
@@ -0,0 +1,344 @@
+// Package match plays engine versus engine games. It can run gauntlets,
+// round robins and Swiss tournaments, playing several games at once and
+// writing each game as PGN as soon as it is over.
+package match
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/pgn"
+	"github.com/andrewbackes/chess/piece"
+)
+
+// Player is an engine taking part in an event.
+type Player struct {
+	Name string
+	// Start starts a new instance of the engine. Games that are played at
+	// the same time each get their own instance, and instances are reused
+	// for later games unless they misbehaved.
+	Start func() (engines.Engine, error)
+}
+
+// Format is how the players of an event are paired.
+type Format int
+
+// Possible formats.
+const (
+	// Gauntlet pairs the first player against each of the others.
+	Gauntlet Format = iota
+	// RoundRobin pairs every player against every other player.
+	RoundRobin
+	// Swiss pairs players with similar scores each round, avoiding
+	// rematches where possible.
+	Swiss
+)
+
+// Event is a match or tournament between engines.
+type Event struct {
+	Name    string
+	Site    string
+	Format  Format
+	Players []Player
+	// Rounds is how many times every pairing is played in gauntlets and
+	// round robins, and the number of rounds of a Swiss tournament. Zero
+	// is treated as one.
+	Rounds int
+	// Openings are used in turn for every pairing. Each opening is played
+	// twice with colors swapped. When empty, games start from the initial
+	// position.
+	Openings []Opening
+	// TimeControl is used for both players of every game.
+	TimeControl game.TimeControl
+	// Adjudication ends games early using the scores the engines report.
+	Adjudication game.Adjudication
+	// Concurrency is how many games are played at the same time. Zero is
+	// treated as one.
+	Concurrency int
+	// PGN, when set, receives every game as soon as it is over.
+	PGN io.Writer
+}
+
+// Result is the outcome of a game.
+type Result struct {
+	Round        int
+	White, Black int // indexes into the event's players
+	Status       game.GameStatus
+	Game         *game.Game // with the evaluations the engines reported
+}
+
+// Standing is how a player is doing in an event.
+type Standing struct {
+	Player              int // index into the event's players
+	Points              float64
+	Wins, Draws, Losses int
+	Byes                int
+}
+
+// Report is the outcome of an event.
+type Report struct {
+	// Results of every game in the order they were finished.
+	Results []Result
+	// Standings sorted from first place to last.
+	Standings []Standing
+}
+
+// pairing is two players meeting over an opening, once with each color.
+type pairing struct {
+	round   int
+	a, b    int
+	opening Opening
+}
+
+// Run plays the event. Canceling the context stops it after the moves that
+// are being thought about; games that were cut short are not reported.
+func (e *Event) Run(ctx context.Context) (*Report, error) {
+	if len(e.Players) < 2 {
+		return nil, errors.New("match: at least two players are needed")
+	}
+	if e.TimeControl.Time <= 0 {
+		return nil, errors.New("match: a time control is needed")
+	}
+	r := &runner{event: e, pool: newPool(e.Players), byes: make([]int, len(e.Players))}
+	defer r.pool.close()
+	rounds := e.Rounds
+	if rounds <= 0 {
+		rounds = 1
+	}
+	if e.Format == Swiss {
+		played := make(map[[2]int]bool)
+		for round := 1; round <= rounds; round++ {
+			pairs, bye := swissPairings(r.scores(), played, r.byes)
+			if bye >= 0 {
+				r.byes[bye]++
+			}
+			var pairings []pairing
+			for _, p := range pairs {
+				played[[2]int{p[0], p[1]}], played[[2]int{p[1], p[0]}] = true, true
+				pairings = append(pairings, r.pairing(round, p[0], p[1]))
+			}
+			if err := r.play(ctx, pairings); err != nil {
+				return r.report(), err
+			}
+		}
+		return r.report(), nil
+	}
+	var pairings []pairing
+	for round := 1; round <= rounds; round++ {
+		for a := range e.Players {
+			for b := a + 1; b < len(e.Players); b++ {
+				if e.Format == Gauntlet && a != 0 {
+					break
+				}
+				pairings = append(pairings, r.pairing(round, a, b))
+			}
+		}
+	}
+	err := r.play(ctx, pairings)
+	return r.report(), err
+}
+
+// runner holds the state of an event while it is being played.
+type runner struct {
+	event    *Event
+	pool     *pool
+	openings int
+	mu       sync.Mutex
+	results  []Result
+	byes     []int
+	pgnErr   error
+}
+
+// pairing pairs the players over the next opening.
+func (r *runner) pairing(round, a, b int) pairing {
+	p := pairing{round: round, a: a, b: b}
+	if len(r.event.Openings) > 0 {
+		p.opening = r.event.Openings[r.openings%len(r.event.Openings)]
+		r.openings++
+	}
+	return p
+}
+
+// play plays both games of every pairing and returns once they are all over.
+func (r *runner) play(ctx context.Context, pairings []pairing) error {
+	type job struct {
+		round        int
+		white, black int
+		opening      Opening
+	}
+	jobs := make(chan job)
+	concurrency := r.event.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := r.playGame(ctx, j.round, j.white, j.black, j.opening); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+feed:
+	for _, p := range pairings {
+		for _, j := range []job{{p.round, p.a, p.b, p.opening}, {p.round, p.b, p.a, p.opening}} {
+			select {
+			case jobs <- j:
+			case err = <-errs:
+				break feed
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		r.mu.Lock()
+		err = r.pgnErr
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// playGame plays and records a single game.
+func (r *runner) playGame(ctx context.Context, round, white, black int, opening Opening) error {
+	g, err := opening.newGame(r.event.TimeControl)
+	if err != nil {
+		return err
+	}
+	var players [2]engines.Engine
+	var status game.GameStatus
+	for c, player := range []int{white, black} {
+		players[c], err = r.pool.get(player)
+		if err == nil {
+			if err = players[c].NewGame(); err != nil {
+				r.pool.discard(players[c])
+			}
+		}
+		if err != nil {
+			status = []game.GameStatus{game.WhiteDisconnected, game.BlackDisconnected}[c]
+			players[c] = nil
+			break
+		}
+	}
+	if status == 0 {
+		status, err = play(ctx, g, players, r.event.Adjudication)
+	}
+	if status != game.InProgress {
+		// Endings the board does not know about, like time forfeits:
+		g.End(status)
+	}
+	for c, player := range []int{white, black} {
+		if players[c] == nil {
+			continue
+		}
+		if misbehaved(status, piece.Color(c)) {
+			r.pool.discard(players[c])
+		} else {
+			r.pool.put(player, players[c])
+		}
+	}
+	if err != nil {
+		return err
+	}
+	r.record(Result{Round: round, White: white, Black: black, Status: status, Game: g})
+	return nil
+}
+
+// record adds the result and writes the game out.
+func (r *runner) record(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+	if r.event.PGN == nil || r.pgnErr != nil {
+		return
+	}
+	_, r.pgnErr = io.WriteString(r.event.PGN, r.encode(result).String())
+}
+
+// encode turns the game into a PGN with the clocks and evaluations.
+func (r *runner) encode(result Result) *pgn.PGN {
+	g := result.Game
+	p := pgn.EncodeSAN(g)
+	p.Tags["Event"] = r.event.Name
+	p.Tags["Site"] = r.event.Site
+	p.Tags["Date"] = time.Now().Format("2006.01.02")
+	p.Tags["Round"] = strconv.Itoa(result.Round)
+	p.Tags["White"] = r.event.Players[result.White].Name
+	p.Tags["Black"] = r.event.Players[result.Black].Name
+	p.Tags["PlyCount"] = strconv.Itoa(len(g.Positions) - 1)
+	return p
+}
+
+// scores returns the points of every player so far.
+func (r *runner) scores() []float64 {
+	scores := make([]float64, len(r.event.Players))
+	for _, s := range r.standings() {
+		scores[s.Player] = s.Points
+	}
+	return scores
+}
+
+func (r *runner) standings() []Standing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	standings := make([]Standing, len(r.event.Players))
+	for i := range standings {
+		standings[i].Player = i
+		standings[i].Byes = r.byes[i]
+		standings[i].Points = float64(r.byes[i])
+	}
+	for _, res := range r.results {
+		w, b := &standings[res.White], &standings[res.Black]
+		switch {
+		case res.Status&game.WhiteWon != 0:
+			w.Wins++
+			b.Losses++
+			w.Points++
+		case res.Status&game.BlackWon != 0:
+			b.Wins++
+			w.Losses++
+			b.Points++
+		default:
+			w.Draws++
+			b.Draws++
+			w.Points += 0.5
+			b.Points += 0.5
+		}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Points > standings[j].Points
+	})
+	return standings
+}
+
+func (r *runner) report() *Report {
+	r.mu.Lock()
+	results := append([]Result(nil), r.results...)
+	r.mu.Unlock()
+	return &Report{Results: results, Standings: r.standings()}
+}
@@ -0,0 +1,230 @@
+package match
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/pgn"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// fakeEngine is an in-process UCI engine. It answers "go" with whatever
+// choose returns for the current position, or hangs up when it returns "".
+// Anything after the move is reported as the score, like "e2e4 cp 30".
+func fakeEngine(conn net.Conn, choose func(*position.Position) string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	p := position.New()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "uci":
+			conn.Write([]byte("uciok\n"))
+		case "isready":
+			conn.Write([]byte("readyok\n"))
+		case "position":
+			p = position.New()
+			i := 2
+			if words[1] == "fen" {
+				p, _ = fen.Decode(strings.Join(words[2:8], " "))
+				i = 8
+			}
+			for _, m := range words[i:] {
+				if m != "moves" {
+					p = p.MakeMove(move.Parse(m))
+				}
+			}
+		case "go":
+			m := choose(p)
+			if m == "" {
+				return
+			}
+			if words := strings.SplitN(m, " ", 2); len(words) == 2 {
+				conn.Write([]byte("info depth 1 score " + words[1] + "\n"))
+				m = words[0]
+			}
+			conn.Write([]byte("bestmove " + m + "\n"))
+		case "quit":
+			return
+		}
+	}
+}
+
+// firstMove plays the legal move that sorts first.
+func firstMove(p *position.Position) string {
+	var moves []string
+	for m := range p.LegalMoves() {
+		moves = append(moves, m.String())
+	}
+	sort.Strings(moves)
+	return moves[0]
+}
+
+func fakePlayer(name string, choose func(*position.Position) string) Player {
+	return Player{
+		Name: name,
+		Start: func() (engines.Engine, error) {
+			client, server := net.Pipe()
+			go fakeEngine(server, choose)
+			return engines.AttachUCIEngine(name, client, engines.Options{})
+		},
+	}
+}
+
+const twoKnights = "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+
+func TestRoundRobin(t *testing.T) {
+	p, _ := fen.Decode(twoKnights)
+	var out bytes.Buffer
+	event := Event{
+		Name:   "test",
+		Format: RoundRobin,
+		Players: []Player{
+			fakePlayer("first", firstMove),
+			fakePlayer("crasher", func(*position.Position) string { return "" }),
+			fakePlayer("illegal", func(*position.Position) string { return "a1a8" }),
+		},
+		Openings:    []Opening{{Position: p}},
+		TimeControl: game.NewTimeControl(time.Minute, 0, 0, false),
+		Concurrency: 3,
+		PGN:         &out,
+	}
+	report, err := event.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 6 {
+		t.Fatal("got", len(report.Results), "games but wanted 6")
+	}
+	for _, res := range report.Results {
+		if res.White == 1 && res.Status != game.WhiteDisconnected {
+			t.Error("crasher as white should disconnect, got", res.Status)
+		}
+		if res.White == 2 && res.Status != game.WhiteIllegalMove {
+			t.Error("illegal as white should make an illegal move, got", res.Status)
+		}
+	}
+	if top := report.Standings[0]; top.Player != 0 || top.Wins != 4 {
+		t.Error("first should win every game, got", report.Standings)
+	}
+	games, err := pgn.Read(&out)
+	if err != nil || len(games) != 6 {
+		t.Fatal(len(games), err)
+	}
+	for _, g := range games {
+		if g.Tags["FEN"] != twoKnights || g.Tags["Termination"] == "" || g.Tags["Result"] == "*" {
+			t.Error(g.Tags)
+		}
+	}
+}
+
+func TestTimeForfeit(t *testing.T) {
+	slow := func(p *position.Position) string {
+		time.Sleep(150 * time.Millisecond)
+		return firstMove(p)
+	}
+	event := Event{
+		Format:      Gauntlet,
+		Players:     []Player{fakePlayer("slow", slow), fakePlayer("fast", firstMove)},
+		TimeControl: game.NewTimeControl(100*time.Millisecond, 0, 0, false),
+	}
+	report, err := event.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 ||
+		report.Results[0].Status != game.WhiteTimedOut ||
+		report.Results[1].Status != game.BlackTimedOut {
+		t.Error(report.Results)
+	}
+}
+
+func TestAdjudication(t *testing.T) {
+	var out bytes.Buffer
+	scoring := func(score string) func(*position.Position) string {
+		return func(p *position.Position) string { return firstMove(p) + " " + score }
+	}
+	event := Event{
+		Format:      Gauntlet,
+		Players:     []Player{fakePlayer("pessimist", scoring("cp -500")), fakePlayer("optimist", scoring("cp 500"))},
+		TimeControl: game.NewTimeControl(time.Minute, 0, 0, false),
+		Adjudication: game.Adjudication{
+			ResignScore: 400,
+			ResignMoves: 3,
+		},
+		PGN: &out,
+	}
+	report, err := event.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 ||
+		report.Results[0].Status != game.WhiteLostByAdjudication ||
+		report.Results[1].Status != game.BlackLostByAdjudication {
+		t.Fatal(report.Results)
+	}
+	if plies := len(report.Results[0].Game.Positions) - 1; plies != 6 {
+		t.Error("adjudicated after", plies, "plies, wanted 6")
+	}
+	if !strings.Contains(out.String(), "1. a3 {") || !strings.Contains(out.String(), "[%eval -5.00]") || !strings.Contains(out.String(), "[%clk ") {
+		t.Error("the PGN should have clocks and evaluations:\n", out.String())
+	}
+}
+
+func TestSwissPairings(t *testing.T) {
+	scores := []float64{1, 2, 0, 1, 2}
+	played := map[[2]int]bool{{1, 4}: true, {4, 1}: true}
+	pairs, bye := swissPairings(scores, played, []int{0, 0, 0, 0, 0})
+	if bye != 2 {
+		t.Error("lowest scored player should get the bye, got", bye)
+	}
+	expected := [][2]int{{1, 0}, {4, 3}}
+	if len(pairs) != 2 || pairs[0] != expected[0] || pairs[1] != expected[1] {
+		t.Error("got", pairs, "wanted", expected)
+	}
+}
+
+func TestSwiss(t *testing.T) {
+	event := Event{
+		Format: Swiss,
+		Rounds: 2,
+		Players: []Player{
+			fakePlayer("a", firstMove),
+			fakePlayer("b", firstMove),
+			fakePlayer("c", firstMove),
+		},
+		TimeControl: game.NewTimeControl(time.Minute, 0, 0, false),
+		Concurrency: 2,
+	}
+	report, err := event.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 4 {
+		t.Fatal("got", len(report.Results), "games but wanted 4")
+	}
+	byes := 0
+	for _, s := range report.Standings {
+		byes += s.Byes
+	}
+	if byes != 2 {
+		t.Error("got", byes, "byes but wanted 2")
+	}
+}
@@ -0,0 +1,81 @@
+package match
+
+import (
+	"errors"
+
+	"github.com/andrewbackes/chess/book"
+	"github.com/andrewbackes/chess/epd"
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Opening is where the engines take over a game. Each opening is played
+// twice, once with each engine as white.
+type Opening struct {
+	// Position is the starting position. When nil the standard initial
+	// position is used.
+	Position *position.Position
+	// Moves are made from the starting position before the engines are
+	// asked to move.
+	Moves []move.Move
+}
+
+// BookOpenings picks n openings at random from the book. Each one follows
+// the book for up to 'plies' half moves.
+func BookOpenings(b *book.Book, n, plies int) ([]Opening, error) {
+	var openings []Opening
+	for i := 0; i < n; i++ {
+		entries, err := b.RandomOpening(plies)
+		if err != nil {
+			return nil, err
+		}
+		o := Opening{}
+		for _, e := range entries {
+			o.Moves = append(o.Moves, e.Move)
+		}
+		openings = append(openings, o)
+	}
+	return openings, nil
+}
+
+// EPDOpenings uses the positions of the EPDs as starting positions.
+func EPDOpenings(epds []*epd.EPD) []Opening {
+	var openings []Opening
+	for _, e := range epds {
+		openings = append(openings, Opening{Position: e.Position})
+	}
+	return openings
+}
+
+// newGame sets up a timed game with the opening already played.
+func (o Opening) newGame(tc game.TimeControl) (*game.Game, error) {
+	g := game.NewTimedGame(map[piece.Color]game.TimeControl{piece.White: tc, piece.Black: tc})
+	g.Rules = game.EngineTesting
+	if o.Position != nil {
+		p := position.Copy(o.Position)
+		for _, c := range piece.Colors {
+			p.Clocks[c] = g.Position().Clocks[c]
+			p.MovesLeft[c] = g.Position().MovesLeft[c]
+		}
+		g.Positions[0] = p
+		f, err := fen.Encode(p)
+		if err != nil {
+			return nil, err
+		}
+		g.Tags["FEN"] = f
+		g.Tags["SetUp"] = "1"
+	}
+	for _, m := range o.Moves {
+		status, err := g.MakeMove(m)
+		if err != nil {
+			return nil, err
+		}
+		if status != game.InProgress {
+			return nil, errors.New("opening ends the game")
+		}
+	}
+	return g, nil
+}
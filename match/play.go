@@ -0,0 +1,88 @@
+package match
+
+import (
+	"context"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+)
+
+// play lets the engines play the game until it is over. Rules-based endings
+// are detected by the game itself, the rest is adjudicated here: an engine
+// that runs out of time, makes an illegal move or crashes loses, and the
+// adjudication rules are applied after every move. The evaluation the engines
+// report for their moves is recorded on the game.
+func play(ctx context.Context, g *game.Game, players [2]engines.Engine, rules game.Adjudication) (game.GameStatus, error) {
+	adjudicator := game.NewAdjudicator(rules)
+	for {
+		if status := g.Status(); status != game.InProgress {
+			return status, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return game.InProgress, err
+		}
+		color := g.ActiveColor()
+		start := time.Now()
+		info, err := players[color].BestMove(g, nil)
+		elapsed := time.Since(start)
+		switch {
+		case err == engines.ErrTimedOut || (err == nil && elapsed > g.Clock(color)):
+			return lost(color, game.WhiteTimedOut, game.BlackTimedOut), nil
+		case err != nil:
+			return lost(color, game.WhiteDisconnected, game.BlackDisconnected), nil
+		}
+		m, err := g.Position().ParseMove(info.BestMove)
+		if err != nil {
+			return lost(color, game.WhiteIllegalMove, game.BlackIllegalMove), nil
+		}
+		m.Duration = elapsed
+		if status, err := g.MakeMove(m); err != nil {
+			return status, nil
+		}
+		if score, ok := info.Score(); ok {
+			adjudicator.Record(color, score)
+			g.SetEval(g.Ply(), evalOf(score, color))
+		} else {
+			adjudicator.NoScore(color)
+		}
+		if g.Status() == game.InProgress {
+			if status := adjudicator.Adjudicate(g); status != game.InProgress {
+				return status, nil
+			}
+		}
+	}
+}
+
+func lost(c piece.Color, white, black game.GameStatus) game.GameStatus {
+	if c == piece.White {
+		return white
+	}
+	return black
+}
+
+// misbehaved returns whether the player of the color lost in a way that might
+// leave its engine unusable, by running out of time or crashing.
+func misbehaved(status game.GameStatus, c piece.Color) bool {
+	return status == lost(c, game.WhiteTimedOut, game.BlackTimedOut) ||
+		status == lost(c, game.WhiteDisconnected, game.BlackDisconnected)
+}
+
+// evalOf turns the score of the player who moved into an evaluation from
+// White's point of view.
+func evalOf(score int, mover piece.Color) game.Eval {
+	if mover == piece.Black {
+		score = -score
+	}
+	var e game.Eval
+	switch {
+	case score > engines.MateScore/2:
+		e.Mate = engines.MateScore - score
+	case score < -engines.MateScore/2:
+		e.Mate = -(engines.MateScore + score)
+	default:
+		e.Centipawns = score
+	}
+	return e
+}
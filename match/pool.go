@@ -0,0 +1,55 @@
+package match
+
+import (
+	"sync"
+
+	"github.com/andrewbackes/chess/engines"
+)
+
+// pool keeps idle engine instances of every player so they can be reused.
+type pool struct {
+	players []Player
+	mu      sync.Mutex
+	idle    [][]engines.Engine
+}
+
+func newPool(players []Player) *pool {
+	return &pool{players: players, idle: make([][]engines.Engine, len(players))}
+}
+
+// get returns an idle instance of the player's engine or starts a new one.
+func (p *pool) get(player int) (engines.Engine, error) {
+	p.mu.Lock()
+	if n := len(p.idle[player]); n > 0 {
+		e := p.idle[player][n-1]
+		p.idle[player] = p.idle[player][:n-1]
+		p.mu.Unlock()
+		return e, nil
+	}
+	p.mu.Unlock()
+	return p.players[player].Start()
+}
+
+// put returns an instance to the pool.
+func (p *pool) put(player int, e engines.Engine) {
+	p.mu.Lock()
+	p.idle[player] = append(p.idle[player], e)
+	p.mu.Unlock()
+}
+
+// discard shuts down an instance that should not be used again.
+func (p *pool) discard(e engines.Engine) {
+	e.Close()
+}
+
+// close shuts down all idle instances.
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, idle := range p.idle {
+		for _, e := range idle {
+			e.Close()
+		}
+		p.idle[i] = nil
+	}
+}
@@ -0,0 +1,61 @@
+package match
+
+import (
+	"sort"
+)
+
+// swissPairings pairs players with equal or similar scores. Rematches are
+// avoided unless there is no other way to pair everyone. With an odd number
+// of players, the lowest ranked player with the fewest byes sits out;
+// their index is returned as bye, which is -1 otherwise.
+func swissPairings(scores []float64, played map[[2]int]bool, byes []int) (pairs [][2]int, bye int) {
+	ranked := make([]int, len(scores))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	bye = -1
+	if len(ranked)%2 == 1 {
+		bye = ranked[len(ranked)-1]
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if byes[ranked[i]] < byes[bye] {
+				bye = ranked[i]
+			}
+		}
+		for i, p := range ranked {
+			if p == bye {
+				ranked = append(ranked[:i], ranked[i+1:]...)
+				break
+			}
+		}
+	}
+	if pairs, ok := pairUp(ranked, played); ok {
+		return pairs, bye
+	}
+	pairs, _ = pairUp(ranked, nil)
+	return pairs, bye
+}
+
+// pairUp pairs the highest ranked player with the next highest ranked player
+// they have not played yet, backtracking when the rest can not be paired.
+func pairUp(ranked []int, played map[[2]int]bool) ([][2]int, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+	top := ranked[0]
+	for i := 1; i < len(ranked); i++ {
+		opponent := ranked[i]
+		if played[[2]int{top, opponent}] {
+			continue
+		}
+		rest := make([]int, 0, len(ranked)-2)
+		rest = append(rest, ranked[1:i]...)
+		rest = append(rest, ranked[i+1:]...)
+		if pairs, ok := pairUp(rest, played); ok {
+			return append([][2]int{{top, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
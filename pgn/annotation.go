@@ -0,0 +1,167 @@
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Command returns the value of the [%name value] command in the comment.
+func Command(comment, name string) (string, bool) {
+	start, end := findCommand(comment, name)
+	if start < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(comment[start+len("[%"+name) : end-1]), true
+}
+
+// SetCommand returns the comment with the [%name value] command set,
+// replacing the one already there or adding it at the end.
+func SetCommand(comment, name, value string) string {
+	command := "[%" + name + " " + value + "]"
+	if start, end := findCommand(comment, name); start >= 0 {
+		return comment[:start] + command + comment[end:]
+	}
+	if comment == "" {
+		return command
+	}
+	return comment + " " + command
+}
+
+// findCommand returns where the command starts and ends in the comment, or
+// -1 if it is not there.
+func findCommand(comment, name string) (int, int) {
+	prefix := "[%" + name
+	for offset := 0; ; {
+		i := strings.Index(comment[offset:], prefix)
+		if i < 0 {
+			return -1, -1
+		}
+		start := offset + i
+		rest := comment[start+len(prefix):]
+		end := strings.IndexByte(rest, ']')
+		if end >= 0 && (rest[0] == ' ' || rest[0] == ']') {
+			return start, start + len(prefix) + end + 1
+		}
+		offset = start + len(prefix)
+	}
+}
+
+// ParseClock parses the h:mm:ss times of the [%clk] and [%emt] commands.
+// Seconds can have a fraction, like 0:00:05.3.
+func ParseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, errors.New("bad clock " + strconv.Quote(s))
+	}
+	var d time.Duration
+	for i, part := range parts {
+		unit := []time.Duration{time.Hour, time.Minute, time.Second}[3-len(parts)+i]
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil || f < 0 || (i < len(parts)-1 && f != math.Trunc(f)) {
+			return 0, errors.New("bad clock " + strconv.Quote(s))
+		}
+		d += time.Duration(f * float64(unit))
+	}
+	return d, nil
+}
+
+// FormatClock formats the time as h:mm:ss, with a fraction of a second if
+// there is one.
+func FormatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Millisecond)
+	s := fmt.Sprintf("%d:%02d:%02d", d/time.Hour, d/time.Minute%60, d/time.Second%60)
+	if ms := d / time.Millisecond % 1000; ms != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%03d", ms), "0")
+	}
+	return s
+}
+
+// Eval is an engine evaluation from White's point of view.
+type Eval = game.Eval
+
+// ParseEval parses the value of an [%eval] command, like "0.35" or "#-3".
+func ParseEval(s string) (Eval, error) {
+	if strings.HasPrefix(s, "#") {
+		mate, err := strconv.Atoi(s[1:])
+		if err != nil || mate == 0 {
+			return Eval{}, errors.New("bad eval " + strconv.Quote(s))
+		}
+		return Eval{Mate: mate}, nil
+	}
+	pawns, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(pawns, 0) || math.IsNaN(pawns) {
+		return Eval{}, errors.New("bad eval " + strconv.Quote(s))
+	}
+	return Eval{Centipawns: int(math.Round(pawns * 100))}, nil
+}
+
+// command returns the value of the command in the comment of the move.
+func (p *PGN) command(move int, name string) (string, bool) {
+	if move >= len(p.Comments) {
+		return "", false
+	}
+	return Command(p.Comments[move], name)
+}
+
+// setCommand sets the command in the comment of the move.
+func (p *PGN) setCommand(move int, name, value string) {
+	for len(p.Comments) < len(p.Moves) || len(p.Comments) <= move {
+		p.Comments = append(p.Comments, "")
+	}
+	p.Comments[move] = SetCommand(p.Comments[move], name, value)
+}
+
+// Clock returns the time left on the mover's clock after the move, from its
+// [%clk] command.
+func (p *PGN) Clock(move int) (time.Duration, bool) {
+	s, ok := p.command(move, "clk")
+	if !ok {
+		return 0, false
+	}
+	d, err := ParseClock(s)
+	return d, err == nil
+}
+
+// SetClock sets the [%clk] command of the move.
+func (p *PGN) SetClock(move int, d time.Duration) {
+	p.setCommand(move, "clk", FormatClock(d))
+}
+
+// Elapsed returns the time the move took, from its [%emt] command.
+func (p *PGN) Elapsed(move int) (time.Duration, bool) {
+	s, ok := p.command(move, "emt")
+	if !ok {
+		return 0, false
+	}
+	d, err := ParseClock(s)
+	return d, err == nil
+}
+
+// SetElapsed sets the [%emt] command of the move.
+func (p *PGN) SetElapsed(move int, d time.Duration) {
+	p.setCommand(move, "emt", FormatClock(d))
+}
+
+// Eval returns the evaluation after the move, from its [%eval] command.
+func (p *PGN) Eval(move int) (Eval, bool) {
+	s, ok := p.command(move, "eval")
+	if !ok {
+		return Eval{}, false
+	}
+	e, err := ParseEval(s)
+	return e, err == nil
+}
+
+// SetEval sets the [%eval] command of the move.
+func (p *PGN) SetEval(move int, e Eval) {
+	p.setCommand(move, "eval", e.String())
+}
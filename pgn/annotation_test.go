@@ -0,0 +1,162 @@
+package pgn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+func TestCommand(t *testing.T) {
+	comment := "good move [%clk 1:02:03] [%clock 9] [%eval #-3]"
+	if v, ok := Command(comment, "clk"); !ok || v != "1:02:03" {
+		t.Error("got", v, ok)
+	}
+	if v, ok := Command(comment, "eval"); !ok || v != "#-3" {
+		t.Error("got", v, ok)
+	}
+	if _, ok := Command(comment, "emt"); ok {
+		t.Error("found a command that is not there")
+	}
+	if c := SetCommand(comment, "clk", "0:00:01"); c != "good move [%clk 0:00:01] [%clock 9] [%eval #-3]" {
+		t.Error("got", c)
+	}
+	if c := SetCommand("", "emt", "0:00:01"); c != "[%emt 0:00:01]" {
+		t.Error("got", c)
+	}
+}
+
+func TestClockFormat(t *testing.T) {
+	tests := map[string]time.Duration{
+		"0:00:00":     0,
+		"1:02:03":     time.Hour + 2*time.Minute + 3*time.Second,
+		"0:04:59.9":   4*time.Minute + 59900*time.Millisecond,
+		"12:00:00.05": 12*time.Hour + 50*time.Millisecond,
+	}
+	for s, d := range tests {
+		if got := FormatClock(d); got != s {
+			t.Error(d, "formatted as", got, "wanted", s)
+		}
+		if got, err := ParseClock(s); err != nil || got != d {
+			t.Error(s, "parsed as", got, err, "wanted", d)
+		}
+	}
+	if d, err := ParseClock("5:07"); err != nil || d != 5*time.Minute+7*time.Second {
+		t.Error("got", d, err)
+	}
+	for _, bad := range []string{"", "a:00:00", "1:2:3:4", "1.5:00:00", "-1:00:00"} {
+		if _, err := ParseClock(bad); err == nil {
+			t.Error("parsed", bad)
+		}
+	}
+}
+
+func TestEvalFormat(t *testing.T) {
+	tests := map[string]Eval{
+		"0.35":  {Centipawns: 35},
+		"-1.20": {Centipawns: -120},
+		"#3":    {Mate: 3},
+		"#-3":   {Mate: -3},
+	}
+	for s, e := range tests {
+		if got := e.String(); got != s {
+			t.Error(e, "formatted as", got, "wanted", s)
+		}
+		if got, err := ParseEval(s); err != nil || got != e {
+			t.Error(s, "parsed as", got, err, "wanted", e)
+		}
+	}
+	for _, bad := range []string{"", "#", "#0", "abc"} {
+		if _, err := ParseEval(bad); err == nil {
+			t.Error("parsed", bad)
+		}
+	}
+}
+
+func TestClockRoundTrip(t *testing.T) {
+	tc := game.NewTimeControl(5*time.Minute, 0, 2*time.Second, false)
+	g := game.NewTimedGame(map[piece.Color]game.TimeControl{piece.White: tc, piece.Black: tc})
+	for i, s := range []string{"e2e4", "e7e5", "g1f3"} {
+		m := move.Parse(s)
+		m.Duration = time.Duration(i+1) * 1500 * time.Millisecond
+		g.MakeMove(m)
+	}
+	g.SetEval(2, Eval{Centipawns: 20})
+	p := EncodeSAN(g)
+	if p.Comments[1] != "[%clk 0:04:59] [%emt 0:00:03] [%eval 0.20]" {
+		t.Error("got", p.Comments)
+	}
+	read, err := Parse(p.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range g.Positions {
+		for _, c := range piece.Colors {
+			if decoded.Positions[i].Clocks[c] != g.Positions[i].Clocks[c] {
+				t.Error("ply", i, c, "has", decoded.Positions[i].Clocks[c], "wanted", g.Positions[i].Clocks[c])
+			}
+		}
+		if decoded.Positions[i].LastMove != g.Positions[i].LastMove {
+			t.Error("ply", i, "got", decoded.Positions[i].LastMove, "wanted", g.Positions[i].LastMove)
+		}
+	}
+	if e, ok := decoded.Eval(2); !ok || e.Centipawns != 20 {
+		t.Error("got", e, ok)
+	}
+}
+
+func TestUntimedClocks(t *testing.T) {
+	p, err := Parse(`[Event "no time control"]
+
+1. e4 {[%clk 0:03:01] [%eval 0.3]} 1... e5 {[%clk 0:02:55]} 2. Nf3 {[%clk 0:02:58]} *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := g.Positions[1].LastMove.Duration; d != 0 {
+		t.Error("the first move took", d, "with no clock to take it from")
+	}
+	if d := g.Positions[3].LastMove.Duration; d != 3*time.Second {
+		t.Error("white's second move took", d)
+	}
+	if e, ok := g.Eval(1); !ok || e.Centipawns != 30 {
+		t.Error("got", e, ok)
+	}
+	encoded := EncodeSAN(g)
+	if encoded.Comments[0] != "[%clk 0:03:01] [%eval 0.30]" || encoded.Comments[1] != "[%clk 0:02:55]" {
+		t.Error("got", encoded.Comments)
+	}
+}
+
+func TestDecodeClocksOnly(t *testing.T) {
+	p, err := Parse(`[TimeControl "180+2"]
+
+1. e4 {[%clk 0:03:01]} 1... e5 {[%clk 0:02:55.5]} *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := g.Positions[1].LastMove.Duration; d != time.Second {
+		t.Error("white's move took", d)
+	}
+	if d := g.Positions[2].LastMove.Duration; d != 6500*time.Millisecond {
+		t.Error("black's move took", d)
+	}
+	if c := g.Clock(piece.Black); c != 2*time.Minute+55500*time.Millisecond {
+		t.Error("black has", c)
+	}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 // Example of tag filtering in action:
-func ExampleFilterPGNs() {
+func ExampleFilter() {
 	// Make some PGNs to filter:
 	g1 := New()
 	g1.Tags["WhiteElo"] = "3000"
@@ -5,17 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
 	"github.com/andrewbackes/chess/position/move"
 )
 
 // PGN represents a game in Portable Game Notation.
 type PGN struct {
-	Tags         map[string]string
-	Moves        []string
+	Tags  map[string]string
+	Moves []string
+	// Comments holds the comment that follows each move, without the
+	// surrounding braces. It is either empty or as long as Moves.
+	Comments     []string
 	FirstMoveNum int
+	// movetext is the movetext the PGN was read from, variations included.
+	movetext string
+	// tree is set when the movetext was written by EncodeTree, in which case
+	// it is printed in place of the moves and comments.
+	tree bool
 }
 
 func (p PGN) String() string {
@@ -34,17 +44,39 @@ func (p PGN) String() string {
 		}
 		return false
 	}
-	for t, v := range p.Tags {
+	// The Seven Tag Roster comes first, in its standard order, and the
+	// remaining tags follow by name so the output does not vary.
+	var others []string
+	for t := range p.Tags {
 		if !alreadyPrinted(t) {
-			s += fmt.Sprint("[", t, " ", "\"", v, "\"]\n")
+			others = append(others, t)
 		}
 	}
+	sort.Strings(others)
+	for _, t := range others {
+		s += fmt.Sprint("[", t, " ", "\"", p.Tags[t], "\"]\n")
+	}
 	s += fmt.Sprintln()
-	for i, m := range p.Moves {
-		if i%2 == 0 {
-			s += fmt.Sprint(p.FirstMoveNum+(i/2), ". ")
+	// When the game starts with black to move, the first move is numbered "N...":
+	offset := 0
+	if fields := strings.Fields(p.Tags["FEN"]); len(fields) > 1 && fields[1] == "b" {
+		offset = 1
+	}
+	if p.tree {
+		s += p.movetext
+	} else {
+		for i, m := range p.Moves {
+			ply := i + offset
+			if ply%2 == 0 {
+				s += fmt.Sprint(p.FirstMoveNum+(ply/2), ". ")
+			} else if i == 0 {
+				s += fmt.Sprint(p.FirstMoveNum, "... ")
+			}
+			s += fmt.Sprint(m, " ")
+			if i < len(p.Comments) && p.Comments[i] != "" {
+				s += fmt.Sprint("{", p.Comments[i], "} ")
+			}
 		}
-		s += fmt.Sprint(m, " ")
 	}
 	s += fmt.Sprintln(p.Tags["Result"])
 	s += fmt.Sprintln()
@@ -73,13 +105,40 @@ func (p *PGN) UnmarshalText(text []byte) error {
 // or use ReadPGN() to load it from a file.
 func Decode(pgn *PGN) (*game.Game, error) {
 	g := game.New()
-	g.Tags = pgn.Tags
-	for _, san := range pgn.Moves {
+	// known is whose clocks are known before the move, so that the time it
+	// took can be told from the clock after it:
+	var known [2]bool
+	if tc, err := game.ParseTimeControl(pgn.Tags["TimeControl"]); err == nil && tc.Time > 0 {
+		g = game.NewTimedGame(map[piece.Color]game.TimeControl{piece.White: tc, piece.Black: tc})
+		known = [2]bool{true, true}
+	}
+	for k, v := range pgn.Tags {
+		g.Tags[k] = v
+	}
+	for i, san := range pgn.Moves {
 		move, err := g.Position().ParseMove(san)
 		if err != nil {
 			return nil, err
 		}
+		elapsed, timed := pgn.Elapsed(i)
+		clock, clocked := pgn.Clock(i)
+		if timed && !clocked {
+			move.Duration = elapsed
+		}
+		mover := g.ActiveColor()
 		g.MakeMove(move)
+		if clocked {
+			// The clock is what the move took off the clock it was made on:
+			p := g.Position()
+			if !timed && known[mover] {
+				elapsed = p.Clocks[mover] - clock
+			}
+			p.Clocks[mover], p.LastMove.Duration = clock, elapsed
+			known[mover] = true
+		}
+		if e, ok := pgn.Eval(i); ok {
+			g.SetEval(g.Ply(), e)
+		}
 	}
 	return g, nil
 }
@@ -100,18 +159,21 @@ func New() *PGN {
 func Encode(G *game.Game) *PGN {
 	pgn := New()
 	//G.appendTags()
-	pgn.Tags = G.Tags
-	pgn.Tags["Result"] = G.Result()
+	pgn.Tags = encodeTags(G)
 	foundFirstMove := false
 	for i := 0; i < len(G.Positions); i++ {
 		if !foundFirstMove && G.Positions[i].LastMove != move.Null {
 			pgn.FirstMoveNum = G.Positions[i].MoveNumber
+			if i > 0 {
+				pgn.FirstMoveNum = G.Positions[i-1].MoveNumber
+			}
 			foundFirstMove = true
 		}
 		if G.Positions[i].LastMove != move.Null {
 			pgn.Moves = append(pgn.Moves, G.Positions[i].LastMove.String())
 		}
 	}
+	encodeCommands(G, pgn)
 	return pgn
 }
 
@@ -123,21 +185,65 @@ func Encode(G *game.Game) *PGN {
 func EncodeSAN(G *game.Game) *PGN {
 	pgn := New()
 	//G.appendTags()
-	pgn.Tags = G.Tags
-	pgn.Tags["Result"] = G.Result()
+	pgn.Tags = encodeTags(G)
 	foundFirstMove := false
 	for i := 0; i < len(G.Positions); i++ {
 		if !foundFirstMove && G.Positions[i].LastMove != move.Null {
 			pgn.FirstMoveNum = G.Positions[i].MoveNumber
+			if i > 0 {
+				pgn.FirstMoveNum = G.Positions[i-1].MoveNumber
+			}
 			foundFirstMove = true
 		}
 		if G.Positions[i].LastMove != move.Null {
 			pgn.Moves = append(pgn.Moves, G.Positions[i-1].SAN(G.Positions[i].LastMove))
 		}
 	}
+	encodeCommands(G, pgn)
 	return pgn
 }
 
+// encodeTags returns a copy of the game's tags with the tags that follow from
+// the game, like its result, set.
+func encodeTags(G *game.Game) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range G.Tags {
+		tags[k] = v
+	}
+	tags["Result"] = G.Result()
+	if status := G.Status(); status != game.InProgress {
+		tags["Termination"] = status.Termination()
+	}
+	if tc := G.TimeControl(piece.White).String(); tc != "-" && tc == G.TimeControl(piece.Black).String() {
+		tags["TimeControl"] = tc
+	}
+	return tags
+}
+
+// encodeCommands adds [%clk] commands to the moves of timed games and to the
+// moves that carry a clock, [%emt] commands to the moves that were timed and
+// [%eval] commands to the moves the game has an evaluation for.
+func encodeCommands(G *game.Game, pgn *PGN) {
+	timed := G.TimeControl(piece.White).Time > 0 || G.TimeControl(piece.Black).Time > 0
+	n := 0
+	for i := 1; i < len(G.Positions); i++ {
+		p := G.Positions[i]
+		if p.LastMove == move.Null {
+			continue
+		}
+		if clock := p.Clocks[G.Positions[i-1].ActiveColor]; timed || clock > 0 {
+			pgn.SetClock(n, clock)
+		}
+		if p.LastMove.Duration > 0 {
+			pgn.SetElapsed(n, p.LastMove.Duration)
+		}
+		if e, ok := G.Eval(i); ok {
+			pgn.SetEval(n, e)
+		}
+		n++
+	}
+}
+
 // Parse reads a string containing a single PGN and returns a PGN object.
 // To read multiple PGNs from a string use:
 //     Read(strings.NewReader(multiPgnString))
@@ -159,81 +265,146 @@ func Read(file io.Reader) ([]*PGN, error) {
 	// Read line by line:
 	scanner := bufio.NewScanner(file)
 	readingmoves := false // flag
+	inComment := false    // whether a {comment} spans lines
 	currentGame := New()
+	var movetext []string
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
 			continue
 		}
-		if line[0] == '[' {
+		if line[0] == '[' && !inComment {
 			if readingmoves {
 				// since we are no longer reading moves, we know this is a new game
 				readingmoves = false
 				// so we need to sort out what to do with the game that we previously read:
+				appendMoves(currentGame, strings.Join(movetext, "\n"))
 				GameList = append(GameList, currentGame)
-				currentGame = New()
+				currentGame, movetext = New(), nil
 			}
 			key, value := splitTag(line)
 			currentGame.Tags[string(key)] = string(value)
 		} else {
 			readingmoves = true
-			appendMoves(currentGame, line)
+			movetext = append(movetext, string(line))
+			inComment = openComment(string(line), inComment)
 		}
 	}
+	appendMoves(currentGame, strings.Join(movetext, "\n"))
 	GameList = append(GameList, currentGame)
 	err := scanner.Err()
 	return GameList, err
 }
 
-func appendMoves(game *PGN, line []byte) {
-	// example: 1. e2e4 d7d5 2. b1c3 f7f5 {asd asd} 3. a2a3 ;asdasdasdasd"
-	l := removeComments(line)
-	//l = RemoveNumbering(line)
-	moves := strings.Split(string(l), " ")
-	for _, m := range moves {
-		if strings.Contains(m, ".") {
-			m = strings.Split(m, ".")[1]
-			m = strings.Trim(m, " ")
+// openComment returns whether a {comment} is still open at the end of the
+// line, given whether one was open at its start.
+func openComment(line string, open bool) bool {
+	for _, c := range line {
+		switch {
+		case open && c == '}':
+			open = false
+		case !open && c == '{':
+			open = true
+		case !open && c == ';':
+			return false
 		}
-		//if isMove(StripAnnotations(m)) {
-		if m != "1/2-1/2" && m != "1-0" && m != "0-1" && m != "*" && m != "" {
-			game.Moves = append(game.Moves, m)
+	}
+	return open
+}
+
+// appendMoves adds the moves of the movetext to the game, along with the
+// comments that follow them. Move numbers, NAGs and variations are skipped.
+func appendMoves(game *PGN, movetext string) {
+	// example: 1. e2e4 d7d5 2. b1c3 f7f5 {asd asd} 3. a2a3 ;asdasdasdasd
+	game.movetext += movetext
+	comments := make([]string, len(game.Moves), len(game.Moves)+len(game.Comments))
+	copy(comments, game.Comments)
+	variations := 0
+	tokenize(movetext, func(kind token, text string) {
+		switch {
+		case kind == startVariation:
+			variations++
+		case kind == endVariation && variations > 0:
+			variations--
+		case variations > 0:
+		case kind == comment && len(comments) > 0:
+			comments[len(comments)-1] = joinComments(comments[len(comments)-1], text)
+		case kind == san:
+			game.Moves = append(game.Moves, text)
+			comments = append(comments, "")
+		}
+	})
+	for _, c := range comments {
+		if c != "" {
+			game.Comments = comments
+			return
 		}
-		//}
+	}
+}
 
+func joinComments(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
 	}
+	return a + " " + b
 }
 
-func removeComments(line []byte) []byte {
-	marker := 0
-	i := 0
-	for {
-		if line[i] == ';' {
-			if i-1 < 0 {
-				return []byte{}
+// token is a kind of movetext element.
+type token int
+
+const (
+	san token = iota
+	comment
+	nag
+	startVariation
+	endVariation
+)
+
+// tokenize splits the movetext into moves, comments, NAGs and the parentheses
+// around variations. Move numbers and results are dropped, comments have
+// their whitespace collapsed and NAGs lose their "$".
+func tokenize(movetext string, emit func(kind token, text string)) {
+	for i := 0; i < len(movetext); {
+		switch c := movetext[i]; c {
+		case '{', ';':
+			end := map[byte]byte{'{': '}', ';': '\n'}[c]
+			j := strings.IndexByte(movetext[i+1:], end)
+			if j < 0 {
+				j = len(movetext) - i - 1
 			}
-			return line[:i-1]
-		}
-		if line[i] == '{' {
-			marker = i
-		}
-		if line[i] == '}' {
-			if marker-1 < 0 {
-				marker = marker + 1
+			if text := strings.Join(strings.Fields(movetext[i+1:i+1+j]), " "); text != "" {
+				emit(comment, text)
 			}
-			if i+1 > len(line)-1 {
-				line = line[:marker-1]
-			} else {
-				line = append(line[:marker-1], line[i+1:]...)
+			i += j + 2
+		case '(':
+			emit(startVariation, "(")
+			i++
+		case ')':
+			emit(endVariation, ")")
+			i++
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			j := strings.IndexAny(movetext[i:], " \t\n\r{};()")
+			if j < 0 {
+				j = len(movetext) - i
+			}
+			m := movetext[i : i+j]
+			i += j
+			// Drop move numbers, but not the dots of an "e.p." suffix:
+			if k := strings.LastIndex(m, "."); k >= 0 && strings.Trim(m[:k+1], "0123456789.") == "" {
+				m = m[k+1:]
+			}
+			switch {
+			case m == "e.p.":
+				// Written apart from the en passant capture it belongs to.
+			case strings.HasPrefix(m, "$"):
+				emit(nag, m[1:])
+			case m != "1/2-1/2" && m != "1-0" && m != "0-1" && m != "*" && m != "":
+				emit(san, m)
 			}
-			i = marker
-		}
-		i++
-		if i > len(line)-1 {
-			break
 		}
 	}
-	return line
 }
 
 /*
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/andrewbackes/chess/fen"
 	"github.com/andrewbackes/chess/game"
@@ -54,6 +55,7 @@ func TestPGNnullmoves(t *testing.T) {
 	expected := `[Result "1-0"]
 [Setup "1"]
 [FEN "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3"]
+[Termination "normal"]
 
 3. h5e5 1-0
 
@@ -61,6 +63,7 @@ func TestPGNnullmoves(t *testing.T) {
 	expectedAlt := `[Result "1-0"]
 [FEN "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3"]
 [Setup "1"]
+[Termination "normal"]
 
 3. h5e5 1-0
 
@@ -83,6 +86,7 @@ func TestPGNnullmoves(t *testing.T) {
 
 func TestPGNoutput(t *testing.T) {
 	expected := `[Result "1-0"]
+[Termination "normal"]
 
 1. e2e4 e7e5 2. d1h5 e8e7 3. h5e5 1-0
 
@@ -209,22 +213,48 @@ func TestFromPGN(t *testing.T) {
 
 func TestStripBracketComments(t *testing.T) {
 	moves := "1. e4 d5 { comment here } 2. d4 e5"
-	p := removeComments([]byte(moves))
-	if string(p) != "1. e4 d5 2. d4 e5" {
+	p := New()
+	appendMoves(p, moves)
+	if strings.Join(p.Moves, " ") != "e4 d5 d4 e5" || !reflect.DeepEqual(p.Comments, []string{"", "comment here", "", ""}) {
+		t.Log(p.Moves, p.Comments)
 		t.Fail()
 	}
 }
 
 func TestStripColonComments(t *testing.T) {
-	moves := "1. e4 d5 ; something here"
-	expected := "1. e4 d5"
-	p := removeComments([]byte(moves))
-	if string(p) != expected {
-		t.Log(string(p))
+	moves := "1. e4 d5 ; something here\n2. d4"
+	p := New()
+	appendMoves(p, moves)
+	if strings.Join(p.Moves, " ") != "e4 d5 d4" || !reflect.DeepEqual(p.Comments, []string{"", "something here", ""}) {
+		t.Log(p.Moves, p.Comments)
 		t.Fail()
 	}
 }
 
+func TestReadMovetext(t *testing.T) {
+	input := `[Event "comments"]
+
+1. e4 {[%clk 0:05:00]
+spanning lines} 1... e5 $1 (1... c5 {Sicilian} 2. Nf3) 2.Nf3 {a} {b} *
+[Event "next"]
+
+1. d4 *
+`
+	games, err := Read(strings.NewReader(input))
+	if err != nil || len(games) != 2 {
+		t.Fatal(games, err)
+	}
+	if strings.Join(games[0].Moves, " ") != "e4 e5 Nf3" {
+		t.Error("got moves", games[0].Moves)
+	}
+	if !reflect.DeepEqual(games[0].Comments, []string{"[%clk 0:05:00] spanning lines", "", "a b"}) {
+		t.Error("got comments", games[0].Comments)
+	}
+	if len(games[1].Moves) != 1 || games[1].Comments != nil {
+		t.Error("got", games[1])
+	}
+}
+
 func TestParsePGN(t *testing.T) {
 	input := `[Event "one"]
 [Round "1"]
@@ -288,7 +318,8 @@ func TestEncodeSAN(t *testing.T) {
 			&PGN{
 				FirstMoveNum: 1,
 				Tags: map[string]string{
-					"Result": "1-0",
+					"Result":      "1-0",
+					"Termination": "normal",
 				},
 				Moves: []string{"e4", "e5", "Qh5", "Ke7", "Qxe5#"},
 			},
@@ -299,7 +330,8 @@ func TestEncodeSAN(t *testing.T) {
 			&PGN{
 				FirstMoveNum: 1,
 				Tags: map[string]string{
-					"Result": "1-0",
+					"Result":      "1-0",
+					"Termination": "normal",
 				},
 				Moves: []string{"e4", "e6", "Bc4", "d5", "Bxd5", "exd5", "c4", "c6", "cxd5", "Qa5", "Qb3", "Bg4", "Nf3", "Nd7", "O-O", "O-O-O", "Qxb7+", "Kxb7", "d6", "Nh6", "Nc3", "f6", "Re1", "Nf7", "a4", "Nde5", "Ra3", "Nxf3+", "Kh1", "N7e5", "d7", "Rc8", "dxc8=Q+", "Kb6", "Qxg4", "c5", "Nb5", "c4", "b4", "cxb3", "d4", "b2", "d5", "b1=N", "d6", "Nf7", "d7", "Nbd2", "Rae3", "Nb3", "R3e2", "Nc5", "Rc2", "Nd3", "Qe6+", "Kb7", "d8=Q", "Nf3e5", "Qdc8#"},
 			},
@@ -310,9 +342,10 @@ func TestEncodeSAN(t *testing.T) {
 			[]string{"Qxe5"},
 			&PGN{
 				Tags: map[string]string{
-					"Result": "1-0",
-					"FEN":    "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3",
-					"Setup":  "1",
+					"Result":      "1-0",
+					"FEN":         "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3",
+					"Setup":       "1",
+					"Termination": "normal",
 				},
 				Moves:        []string{"Qxe5#"},
 				FirstMoveNum: 3,
@@ -348,3 +381,101 @@ func TestEncodeSAN(t *testing.T) {
 		})
 	}
 }
+
+func TestPGNBlackFirstWithComments(t *testing.T) {
+	expected := `[Result "*"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12"]
+[SetUp "1"]
+
+12... Kd7 {first} 13. e4 Kc6 {third} *
+
+`
+	f := "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12"
+	g, _ := decodeToGame(f)
+	g.Tags["SetUp"] = "1"
+	delete(g.Tags, "Setup")
+	for _, san := range []string{"Kd7", "e4", "Kc6"} {
+		m, _ := g.Position().ParseMove(san)
+		g.MakeMove(m)
+	}
+	p := EncodeSAN(g)
+	p.Comments = []string{"first", "", "third"}
+	got := p.String()
+	alt := strings.Replace(expected, "[FEN \""+f+"\"]\n[SetUp \"1\"]", "[SetUp \"1\"]\n[FEN \""+f+"\"]", 1)
+	if got != expected && got != alt {
+		t.Log("wanted:\n", expected)
+		t.Log("got:\n", got)
+		t.Fail()
+	}
+}
+
+func TestEncodeResignation(t *testing.T) {
+	g := game.New()
+	m, _ := g.Position().ParseMove("e4")
+	g.MakeMove(m)
+	g.Resign(piece.Black)
+	p := EncodeSAN(g)
+	if p.Tags["Result"] != "1-0" || p.Tags["Termination"] != "normal" {
+		t.Error(p.Tags)
+	}
+}
+
+func TestDecodeRealWorldSAN(t *testing.T) {
+	p, err := Parse(`[Result "*"]
+
+1. e4 a6 2. e5 d5 3. exd6 e.p. Nf6!? 4. Nf3 e6 5. ♗d3 ♗e7 6. 0-0 0-0 *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Ply() != 12 || g.Positions[5].LastMove != move.Parse("e5d6") {
+		t.Error("got", g.Ply(), g.Positions[5].LastMove)
+	}
+}
+
+func TestEncodeLeavesTags(t *testing.T) {
+	g := game.New()
+	g.Tags["Event"] = "test"
+	s := game.NewSession(g)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = EncodeSAN(s.Snapshot()).String()
+		}
+	}()
+	for _, san := range []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7"} {
+		m, _ := s.Position().ParseMove(san)
+		s.MakeMove(m)
+	}
+	<-done
+	Encode(g)
+	if len(g.Tags) != 1 {
+		t.Error("encoding changed the game's tags:", g.Tags)
+	}
+}
+
+func TestTimeControlRoundTrip(t *testing.T) {
+	p, err := Parse(`[TimeControl "40/7200:3600"]
+[Result "*"]
+
+1. e4 e5 *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Clock(piece.White) != 2*time.Hour || g.MovesLeft(piece.White) != 39 || g.TimeControl(piece.Black).Then == nil {
+		t.Error("got", g.Clock(piece.White), g.MovesLeft(piece.White), g.TimeControl(piece.Black))
+	}
+	if tc := EncodeSAN(g).Tags["TimeControl"]; tc != "40/7200:3600" {
+		t.Error("got", tc)
+	}
+}
@@ -0,0 +1,164 @@
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+)
+
+// suffixes are the move suffix annotations and the NAGs they stand for.
+var suffixes = []struct {
+	suffix string
+	nag    int
+}{{"!!", 3}, {"??", 4}, {"!?", 5}, {"?!", 6}, {"!", 1}, {"?", 2}}
+
+// DecodeTree returns the game of the PGN with its variations, comments and
+// NAGs. Unlike Decode, the game starts from the FEN tag if there is one.
+func DecodeTree(p *PGN) (*game.Tree, error) {
+	start := position.New()
+	if f, ok := p.Tags["FEN"]; ok {
+		var err error
+		if start, err = fen.Decode(f); err != nil {
+			return nil, err
+		}
+	}
+	t := game.NewTree(start)
+	for k, v := range p.Tags {
+		t.Tags[k] = v
+	}
+	movetext := p.movetext
+	if movetext == "" {
+		// Built rather than read, so there are no variations to lose:
+		for i, m := range p.Moves {
+			movetext += m + " "
+			if i < len(p.Comments) && p.Comments[i] != "" {
+				movetext += "{" + p.Comments[i] + "} "
+			}
+		}
+	}
+	n := t.Root
+	var variations []*game.Node
+	var err error
+	tokenize(movetext, func(kind token, text string) {
+		if err != nil {
+			return
+		}
+		switch kind {
+		case san:
+			var nags []int
+			for _, s := range suffixes {
+				if strings.HasSuffix(text, s.suffix) {
+					text, nags = strings.TrimSuffix(text, s.suffix), []int{s.nag}
+					break
+				}
+			}
+			m, e := n.Position.ParseMove(text)
+			if e != nil {
+				err = e
+				return
+			}
+			if n, err = n.AddMove(m); err != nil {
+				err = fmt.Errorf("%s: %v", text, err)
+				return
+			}
+			n.NAGs = append(n.NAGs, nags...)
+		case comment:
+			n.Comments = append(n.Comments, text)
+		case nag:
+			g, e := strconv.Atoi(text)
+			if e != nil {
+				err = errors.New("bad NAG $" + text)
+				return
+			}
+			n.NAGs = append(n.NAGs, g)
+		case startVariation:
+			if n.Parent == nil {
+				err = errors.New("variation before the first move")
+				return
+			}
+			variations = append(variations, n)
+			n = n.Parent
+		case endVariation:
+			if len(variations) == 0 {
+				err = errors.New("unbalanced parentheses")
+				return
+			}
+			n, variations = variations[len(variations)-1], variations[:len(variations)-1]
+		}
+	})
+	if err == nil && len(variations) > 0 {
+		err = errors.New("unbalanced parentheses")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// EncodeTree returns the PGN of the tree. Its moves and comments are those of
+// the main line, while printing it also prints the variations.
+func EncodeTree(t *game.Tree) *PGN {
+	p := New()
+	for k, v := range t.Tags {
+		p.Tags[k] = v
+	}
+	if _, ok := p.Tags["Result"]; !ok {
+		p.Tags["Result"] = "*"
+	}
+	line := t.Mainline()
+	p.FirstMoveNum = line[0].Position.MoveNumber
+	for _, n := range line[1:] {
+		p.Moves = append(p.Moves, n.Parent.Position.SAN(n.Move))
+		p.Comments = append(p.Comments, strings.Join(n.Comments, " "))
+	}
+	var b strings.Builder
+	for _, c := range t.Root.Comments {
+		b.WriteString("{" + c + "} ")
+	}
+	writeLine(&b, t.Root, true)
+	p.movetext = b.String()
+	p.tree = true
+	return p
+}
+
+// writeLine writes the moves that follow the node, main line first with the
+// variations of each move after it.
+func writeLine(b *strings.Builder, n *game.Node, number bool) {
+	for len(n.Children) > 0 {
+		next := n.Children[0]
+		number = writeMove(b, next, number)
+		for _, v := range n.Children[1:] {
+			var variation strings.Builder
+			writeLine(&variation, v, writeMove(&variation, v, true))
+			b.WriteString("(" + strings.TrimSpace(variation.String()) + ") ")
+			number = true
+		}
+		n = next
+	}
+}
+
+// writeMove writes the move of the node with its NAGs and comments. Black's
+// moves are numbered when number is set. It returns whether the next move
+// needs a number.
+func writeMove(b *strings.Builder, n *game.Node, number bool) bool {
+	p := n.Parent.Position
+	if p.ActiveColor == piece.White {
+		fmt.Fprint(b, p.MoveNumber, ". ")
+	} else if number {
+		fmt.Fprint(b, p.MoveNumber, "... ")
+	}
+	b.WriteString(p.SAN(n.Move) + " ")
+	for _, g := range n.NAGs {
+		fmt.Fprint(b, "$", g, " ")
+	}
+	for _, c := range n.Comments {
+		b.WriteString("{" + c + "} ")
+	}
+	return len(n.Comments) > 0
+}
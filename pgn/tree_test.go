@@ -0,0 +1,76 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/position/move"
+)
+
+const variations = `[Event "Variations"]
+[Result "*"]
+
+{Before the game} 1. e4 {King's pawn} e5 (1... c5 2. Nf3 (2. c3) 2... d6) 2. Nf3! $14 Nc6 *
+
+`
+
+func TestDecodeTree(t *testing.T) {
+	p, err := Parse(variations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := DecodeTree(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := tr.Mainline()
+	if len(main) != 5 || tr.Tags["Event"] != "Variations" {
+		t.Fatal("wrong main line", len(main))
+	}
+	if tr.Root.Comments[0] != "Before the game" || main[1].Comments[0] != "King's pawn" {
+		t.Error("wrong comments", tr.Root.Comments, main[1].Comments)
+	}
+	if len(main[3].NAGs) != 2 || main[3].NAGs[0] != 1 || main[3].NAGs[1] != 14 {
+		t.Error("wrong NAGs", main[3].NAGs)
+	}
+	c5 := main[1].Children[1]
+	if c5.Move != move.Parse("c7c5") || len(c5.Children) != 2 || c5.Next().Next().Move != move.Parse("d7d6") {
+		t.Error("wrong variation")
+	}
+	if c5.Children[1].Move != move.Parse("c2c3") {
+		t.Error("wrong nested variation")
+	}
+}
+
+func TestDecodeTreeErrors(t *testing.T) {
+	for _, movetext := range []string{"(1. d4) 1. e4 *", "1. e4 (1. d4 *", "1. e4 e5 2. Ke3 *"} {
+		p, err := Parse("[Result \"*\"]\n\n" + movetext + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeTree(p); err == nil {
+			t.Error("decoded", movetext)
+		}
+	}
+}
+
+func TestEncodeTree(t *testing.T) {
+	p, _ := Parse(variations)
+	tr, err := DecodeTree(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := EncodeTree(tr)
+	if strings.Join(encoded.Moves, " ") != "e4 e5 Nf3 Nc6" || encoded.Comments[0] != "King's pawn" {
+		t.Error("wrong main line", encoded.Moves, encoded.Comments)
+	}
+	// Export format numbers black's moves after comments and spells out NAGs:
+	want := strings.Replace(variations, "e5 (", "1... e5 (", 1)
+	want = strings.Replace(want, "Nf3!", "Nf3 $1", 1)
+	if encoded.String() != want {
+		t.Error("got", encoded.String(), "want", want)
+	}
+	if g, err := Decode(encoded); err != nil || g.Ply() != 4 {
+		t.Error("could not decode the main line", err)
+	}
+}
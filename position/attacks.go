@@ -0,0 +1,188 @@
+package position
+
+import (
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+var (
+	diagonals = [4][65]uint64{nw, ne, sw, se}
+	straights = [4][65]uint64{north, west, south, east}
+	rayScans  = [4]func(uint64) uint{bsf, bsf, bsr, bsr}
+
+	// seeValues are the piece values used by SEE, in centipawns.
+	seeValues = map[piece.Type]int{
+		piece.None:   0,
+		piece.Pawn:   100,
+		piece.Knight: 300,
+		piece.Bishop: 300,
+		piece.Rook:   500,
+		piece.Queen:  900,
+		piece.King:   20000,
+	}
+)
+
+// rayAttacks returns the squares reached along the given rays from s when
+// the squares in occ block them. A blocking square is included.
+func rayAttacks(s square.Square, occ uint64, directions [4][65]uint64) uint64 {
+	var attacks uint64
+	for i := 0; i < 4; i++ {
+		blockerIndex := rayScans[i](directions[i][s] & occ)
+		attacks |= directions[i][s] &^ directions[i][blockerIndex]
+	}
+	return attacks
+}
+
+// attackersTo returns the pieces of color c that attack s when only the
+// squares in occ are occupied. Pieces missing from occ do not attack, and
+// sliders behind them are uncovered.
+func (p *Position) attackersTo(s square.Square, c piece.Color, occ uint64) uint64 {
+	if c == piece.BothColors {
+		return p.attackersTo(s, piece.White, occ) | p.attackersTo(s, piece.Black, occ)
+	}
+	defender := []piece.Color{piece.Black, piece.White}[c]
+	b := p.bitBoard[c]
+	attackers := king_moves[s] & b[piece.King]
+	attackers |= pawn_captures[defender][s] & b[piece.Pawn]
+	attackers |= knight_moves[s] & b[piece.Knight]
+	attackers |= rayAttacks(s, occ, diagonals) & (b[piece.Bishop] | b[piece.Queen])
+	attackers |= rayAttacks(s, occ, straights) & (b[piece.Rook] | b[piece.Queen])
+	return attackers & occ
+}
+
+// attacks returns the squares the piece on s attacks when only the squares
+// in occ are occupied.
+func (p *Position) attacks(s square.Square, occ uint64) uint64 {
+	pc := p.OnSquare(s)
+	switch pc.Type {
+	case piece.Pawn:
+		return pawn_captures[pc.Color][s]
+	case piece.Knight:
+		return knight_moves[s]
+	case piece.Bishop:
+		return rayAttacks(s, occ, diagonals)
+	case piece.Rook:
+		return rayAttacks(s, occ, straights)
+	case piece.Queen:
+		return rayAttacks(s, occ, diagonals) | rayAttacks(s, occ, straights)
+	case piece.King:
+		return king_moves[s]
+	}
+	return 0
+}
+
+// AttackersTo returns a bitboard of every piece of the specified color that
+// attacks the square. Use piece.BothColors to get the attackers of both sides.
+// The square does not need to be occupied.
+func (p *Position) AttackersTo(s square.Square, c piece.Color) BitBoard {
+	return BitBoard(p.attackersTo(s, c, p.occupied(piece.BothColors)))
+}
+
+// Attacks returns a bitboard of the squares attacked by the piece on the
+// specified square, whether they are empty or occupied by either color.
+// Pawns attack only the squares they capture on. An empty square attacks
+// nothing.
+func (p *Position) Attacks(s square.Square) BitBoard {
+	return BitBoard(p.attacks(s, p.occupied(piece.BothColors)))
+}
+
+// XRayAttacks is like Attacks, except that a bishop, rook or queen also sees
+// through friendly pieces lined up behind it on the same line, so the rear
+// piece of a battery attacks what the front piece does.
+func (p *Position) XRayAttacks(s square.Square) BitBoard {
+	pc := p.OnSquare(s)
+	occ := p.occupied(piece.BothColors)
+	b := p.bitBoard[pc.Color]
+	var attacks uint64
+	switch pc.Type {
+	case piece.Bishop, piece.Queen, piece.Rook:
+		if pc.Type != piece.Rook {
+			attacks |= rayAttacks(s, occ&^(b[piece.Bishop]|b[piece.Queen]), diagonals)
+		}
+		if pc.Type != piece.Bishop {
+			attacks |= rayAttacks(s, occ&^(b[piece.Rook]|b[piece.Queen]), straights)
+		}
+	default:
+		attacks = p.attacks(s, occ)
+	}
+	return BitBoard(attacks)
+}
+
+// AttackedBy returns a bitboard of every square attacked by at least one
+// piece of the specified color.
+func (p *Position) AttackedBy(c piece.Color) BitBoard {
+	occ := p.occupied(piece.BothColors)
+	pieces := p.occupied(c)
+	var attacks uint64
+	for pieces != 0 {
+		from := bitscan(pieces)
+		attacks |= p.attacks(square.Square(from), occ)
+		pieces ^= (1 << from)
+	}
+	return BitBoard(attacks)
+}
+
+// SEE returns the static exchange evaluation of the move: the material, in
+// centipawns, that the moving side can expect to win (or lose, if negative)
+// from the sequence of captures on the destination square, when each side
+// recaptures with its least valuable piece and may stop whenever continuing
+// would cost it material. Pieces that join in from behind once the pieces in
+// front of them have captured are counted. Pawns are worth 100, knights and
+// bishops 300, rooks 500 and queens 900. The move is assumed to be pseudo
+// legal; pins are not taken into account.
+func (p *Position) SEE(m move.Move) int {
+	from, to := m.From(), m.To()
+	attacker := p.OnSquare(from)
+	if attacker.Type == piece.None {
+		return 0
+	}
+	occ := p.occupied(piece.BothColors)
+	var gain [33]int
+	gain[0] = seeValues[p.OnSquare(to).Type]
+	if attacker.Type == piece.Pawn && to == p.EnPassant && p.OnSquare(to).Type == piece.None {
+		gain[0] = seeValues[piece.Pawn]
+		occ &^= 1 << []square.Square{to - 8, to + 8}[attacker.Color]
+	}
+	onSquare := attacker.Type
+	if m.Promote != piece.None {
+		gain[0] += seeValues[m.Promote] - seeValues[piece.Pawn]
+		onSquare = m.Promote
+	}
+	occ &^= 1 << from
+	side := []piece.Color{piece.Black, piece.White}[attacker.Color]
+	d := 0
+	for {
+		attackers := p.attackersTo(to, side, occ)
+		if attackers == 0 {
+			break
+		}
+		var next uint
+		var t piece.Type
+		for t = piece.Pawn; t <= piece.King; t++ {
+			if b := attackers & p.bitBoard[side][t]; b != 0 {
+				next = bitscan(b)
+				break
+			}
+		}
+		other := []piece.Color{piece.Black, piece.White}[side]
+		if t == piece.King && p.attackersTo(to, other, occ&^(1<<next)) != 0 {
+			break
+		}
+		d++
+		gain[d] = seeValues[onSquare] - gain[d-1]
+		onSquare = t
+		if t == piece.Pawn && (to/8 == 0 || to/8 == 7) {
+			gain[d] += seeValues[piece.Queen] - seeValues[piece.Pawn]
+			onSquare = piece.Queen
+		}
+		occ &^= 1 << next
+		side = other
+	}
+	for ; d > 0; d-- {
+		if -gain[d] < gain[d-1] {
+			gain[d-1] = -gain[d]
+		}
+	}
+	return gain[0]
+}
@@ -0,0 +1,112 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// setup returns a position holding only the given pieces, with the given
+// color to move.
+func setup(active piece.Color, pieces map[square.Square]piece.Piece) *Position {
+	p := New()
+	p.Clear()
+	p.ActiveColor = active
+	for s, pc := range pieces {
+		p.QuickPut(pc, s)
+	}
+	return p
+}
+
+var (
+	wp, wn, wb = piece.New(piece.White, piece.Pawn), piece.New(piece.White, piece.Knight), piece.New(piece.White, piece.Bishop)
+	wr, wq, wk = piece.New(piece.White, piece.Rook), piece.New(piece.White, piece.Queen), piece.New(piece.White, piece.King)
+	bp, bn, bb = piece.New(piece.Black, piece.Pawn), piece.New(piece.Black, piece.Knight), piece.New(piece.Black, piece.Bishop)
+	br, bq, bk = piece.New(piece.Black, piece.Rook), piece.New(piece.Black, piece.Queen), piece.New(piece.Black, piece.King)
+)
+
+func TestAttackersTo(t *testing.T) {
+	p := setup(piece.White, map[square.Square]piece.Piece{
+		square.E1: wk, square.D4: wp, square.D3: wn, square.E8: wr, square.A1: wb, square.H5: wq,
+		square.H8: bk, square.F6: bp, square.E7: br,
+	})
+	if got, want := p.AttackersTo(square.E5, piece.White), NewBitBoard(square.D4, square.D3, square.H5); got != want {
+		t.Errorf("white attackers of e5:\n%vwant\n%v", got, want)
+	}
+	if got, want := p.AttackersTo(square.E5, piece.Black), NewBitBoard(square.F6, square.E7); got != want {
+		t.Errorf("black attackers of e5:\n%vwant\n%v", got, want)
+	}
+	if got, want := p.AttackersTo(square.E5, piece.BothColors), NewBitBoard(square.D4, square.D3, square.H5, square.F6, square.E7); got != want {
+		t.Errorf("attackers of e5:\n%vwant\n%v", got, want)
+	}
+	// f6 blocks the bishop on a1 from g7:
+	if got := p.AttackersTo(square.G7, piece.White); got != 0 {
+		t.Errorf("white attackers of g7:\n%vwant none", got)
+	}
+	for _, s := range []square.Square{square.E5, square.G7} {
+		for c := piece.White; c <= piece.Black; c++ {
+			if (p.AttackersTo(s, c) != 0) != p.Threatened(s, c) {
+				t.Errorf("AttackersTo(%v, %v) disagrees with Threatened", s, c)
+			}
+		}
+	}
+}
+
+func TestAttacks(t *testing.T) {
+	p := setup(piece.White, map[square.Square]piece.Piece{
+		square.A1: wr, square.A2: wq, square.D1: wk, square.H8: bk, square.A6: bp,
+	})
+	if got, want := p.Attacks(square.A1), NewBitBoard(square.A2, square.B1, square.C1, square.D1); got != want {
+		t.Errorf("rook attacks:\n%vwant\n%v", got, want)
+	}
+	if got, want := p.XRayAttacks(square.A1), NewBitBoard(square.A2, square.A3, square.A4, square.A5, square.A6, square.B1, square.C1, square.D1); got != want {
+		t.Errorf("rook x-ray attacks:\n%vwant\n%v", got, want)
+	}
+	if got, want := p.Attacks(square.A6), NewBitBoard(square.B5); got != want {
+		t.Errorf("pawn attacks:\n%vwant\n%v", got, want)
+	}
+	if got := p.Attacks(square.E4); got != 0 {
+		t.Errorf("empty square attacks:\n%v", got)
+	}
+	if !p.AttackedBy(piece.White).Has(square.A6) || p.AttackedBy(piece.Black).Has(square.A1) {
+		t.Error("AttackedBy is wrong")
+	}
+}
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		name   string
+		active piece.Color
+		pieces map[square.Square]piece.Piece
+		move   string
+		want   int
+	}{
+		{"undefended pawn", piece.White, map[square.Square]piece.Piece{square.E1: wk, square.E8: bk, square.D1: wr, square.D5: bp}, "d1d5", 100},
+		{"rook takes defended pawn", piece.White, map[square.Square]piece.Piece{square.E1: wk, square.H8: bk, square.D1: wr, square.D5: bp, square.E6: bp}, "d1d5", -400},
+		{"pawn takes knight", piece.White, map[square.Square]piece.Piece{square.E1: wk, square.H8: bk, square.E4: wp, square.D5: bn, square.D8: bq}, "e4d5", 200},
+		{"equal trade", piece.White, map[square.Square]piece.Piece{square.E1: wk, square.H8: bk, square.C3: wn, square.D5: bn, square.E6: bp}, "c3d5", 0},
+		{"rook battery", piece.White, map[square.Square]piece.Piece{square.E1: wk, square.H8: bk, square.D1: wr, square.D2: wr, square.D5: bp, square.D8: br}, "d2d5", 100},
+		{"queen behind bishop", piece.Black, map[square.Square]piece.Piece{square.E1: wk, square.H8: bk, square.B7: bb, square.A8: bq, square.E4: wn, square.F3: wp}, "b7e4", 100},
+		{"defended by king", piece.White, map[square.Square]piece.Piece{square.A1: wk, square.E8: bk, square.D1: wq, square.D7: bp}, "d1d7", -800},
+		{"king cannot recapture", piece.White, map[square.Square]piece.Piece{square.A1: wk, square.E8: bk, square.D1: wq, square.D2: wr, square.D7: bp}, "d1d7", 100},
+		{"promotion", piece.White, map[square.Square]piece.Piece{square.A1: wk, square.H1: bk, square.E7: wp}, "e7e8q", 800},
+		{"quiet move", piece.White, map[square.Square]piece.Piece{square.A1: wk, square.H8: bk, square.B1: wn, square.E4: bp}, "b1c3", 0},
+		{"hanging knight", piece.White, map[square.Square]piece.Piece{square.A1: wk, square.H8: bk, square.G1: wn, square.E4: bp}, "g1f3", -300},
+	}
+	for _, test := range tests {
+		p := setup(test.active, test.pieces)
+		if got := p.SEE(move.Parse(test.move)); got != test.want {
+			t.Errorf("%s: SEE(%s) = %d, want %d", test.name, test.move, got, test.want)
+		}
+	}
+}
+
+func TestSEEEnPassant(t *testing.T) {
+	p := setup(piece.White, map[square.Square]piece.Piece{square.E1: wk, square.E8: bk, square.E5: wp, square.D5: bp})
+	p.EnPassant = square.D6
+	if got := p.SEE(move.Parse("e5d6")); got != 100 {
+		t.Errorf("SEE(exd6) = %d, want 100", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/andrewbackes/chess/piece"
 	"github.com/andrewbackes/chess/position/square"
+	"math/bits"
+	"strings"
 )
 
 // BitBoard is a 64 bit integer where each bit represents a square on
@@ -28,6 +30,211 @@ func (b BitBoard) String() string {
 	return s
 }
 
+// Files and ranks of the board as bitboards.
+const (
+	FileH BitBoard = 0x0101010101010101 << iota
+	FileG
+	FileF
+	FileE
+	FileD
+	FileC
+	FileB
+	FileA
+)
+
+const (
+	Rank1 BitBoard = 0xFF << (8 * iota)
+	Rank2
+	Rank3
+	Rank4
+	Rank5
+	Rank6
+	Rank7
+	Rank8
+)
+
+var (
+	between [64][64]BitBoard
+	line    [64][64]BitBoard
+)
+
+func init() {
+	// Each ray is paired with the one pointing the opposite way.
+	rays := [8][65]uint64{north, east, ne, nw, south, west, sw, se}
+	for from := 0; from < 64; from++ {
+		for i, ray := range rays {
+			opposite := rays[(i+4)%8]
+			for to := 0; to < 64; to++ {
+				if ray[from]&(1<<uint(to)) != 0 {
+					between[from][to] = BitBoard(ray[from] &^ ray[to] &^ (1 << uint(to)))
+					line[from][to] = BitBoard(ray[from] | opposite[from] | (1 << uint(from)))
+				}
+			}
+		}
+	}
+}
+
+// NewBitBoard returns a bitboard with the given squares set.
+func NewBitBoard(squares ...square.Square) BitBoard {
+	var b BitBoard
+	for _, s := range squares {
+		b |= 1 << s
+	}
+	return b
+}
+
+// FileMask returns the file that the square is on.
+func FileMask(s square.Square) BitBoard {
+	return FileH << (s % 8)
+}
+
+// RankMask returns the rank that the square is on.
+func RankMask(s square.Square) BitBoard {
+	return Rank1 << (8 * (s / 8))
+}
+
+// DiagonalMask returns the diagonal, running from the a1 side to the h8
+// side, that the square is on.
+func DiagonalMask(s square.Square) BitBoard {
+	return BitBoard(ne[s] | sw[s] | (1 << s))
+}
+
+// AntiDiagonalMask returns the diagonal, running from the h1 side to the a8
+// side, that the square is on.
+func AntiDiagonalMask(s square.Square) BitBoard {
+	return BitBoard(nw[s] | se[s] | (1 << s))
+}
+
+// Between returns the squares strictly between the two squares when they
+// share a rank, file or diagonal, and an empty bitboard otherwise.
+func Between(a, b square.Square) BitBoard {
+	return between[a][b]
+}
+
+// Line returns the whole rank, file or diagonal that goes through both
+// squares, or an empty bitboard if they are not on one.
+func Line(a, b square.Square) BitBoard {
+	return line[a][b]
+}
+
+// Has returns whether the square is set.
+func (b BitBoard) Has(s square.Square) bool {
+	return b&(1<<s) != 0
+}
+
+// Set returns the bitboard with the square set.
+func (b BitBoard) Set(s square.Square) BitBoard {
+	return b | (1 << s)
+}
+
+// Unset returns the bitboard with the square cleared.
+func (b BitBoard) Unset(s square.Square) BitBoard {
+	return b &^ (1 << s)
+}
+
+// Count returns the number of squares that are set.
+func (b BitBoard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// LSB returns the lowest set square, which is the one nearest to h1, or
+// square.NoSquare if the bitboard is empty.
+func (b BitBoard) LSB() square.Square {
+	return square.Square(bits.TrailingZeros64(uint64(b)))
+}
+
+// MSB returns the highest set square, which is the one nearest to a8, or
+// square.NoSquare if the bitboard is empty.
+func (b BitBoard) MSB() square.Square {
+	if b == 0 {
+		return square.NoSquare
+	}
+	return square.Square(63 - bits.LeadingZeros64(uint64(b)))
+}
+
+// PopLSB returns the lowest set square and the bitboard without it. It is
+// the usual way to loop over a bitboard:
+//
+//	for b != 0 {
+//		s, b = b.PopLSB()
+//	}
+func (b BitBoard) PopLSB() (square.Square, BitBoard) {
+	s := b.LSB()
+	return s, b & (b - 1)
+}
+
+// Squares returns the set squares in increasing order.
+func (b BitBoard) Squares() []square.Square {
+	squares := make([]square.Square, 0, b.Count())
+	for b != 0 {
+		var s square.Square
+		s, b = b.PopLSB()
+		squares = append(squares, s)
+	}
+	return squares
+}
+
+// North returns the bitboard moved one rank towards the eighth rank.
+func (b BitBoard) North() BitBoard {
+	return b << 8
+}
+
+// South returns the bitboard moved one rank towards the first rank.
+func (b BitBoard) South() BitBoard {
+	return b >> 8
+}
+
+// East returns the bitboard moved one file towards the h-file. Squares on
+// the h-file fall off the board.
+func (b BitBoard) East() BitBoard {
+	return (b &^ FileH) >> 1
+}
+
+// West returns the bitboard moved one file towards the a-file. Squares on
+// the a-file fall off the board.
+func (b BitBoard) West() BitBoard {
+	return (b &^ FileA) << 1
+}
+
+// NorthEast returns the bitboard moved one square towards h8.
+func (b BitBoard) NorthEast() BitBoard {
+	return (b &^ FileH) << 7
+}
+
+// NorthWest returns the bitboard moved one square towards a8.
+func (b BitBoard) NorthWest() BitBoard {
+	return (b &^ FileA) << 9
+}
+
+// SouthEast returns the bitboard moved one square towards h1.
+func (b BitBoard) SouthEast() BitBoard {
+	return (b &^ FileH) >> 9
+}
+
+// SouthWest returns the bitboard moved one square towards a1.
+func (b BitBoard) SouthWest() BitBoard {
+	return (b &^ FileA) >> 7
+}
+
+// Pretty returns a diagram of the bitboard as seen from white's side, with
+// an 'x' on each set square and the ranks and files labeled.
+func (b BitBoard) Pretty() string {
+	var sb strings.Builder
+	for r := uint(8); r >= 1; r-- {
+		fmt.Fprintf(&sb, "%d ", r)
+		for f := uint(1); f <= 8; f++ {
+			if b.Has(square.New(f, r)) {
+				sb.WriteString(" x")
+			} else {
+				sb.WriteString(" .")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("   a b c d e f g h\n")
+	return sb.String()
+}
+
 type BitBoards map[piece.Color]map[piece.Type]uint64
 
 func (b BitBoards) MailBox() string {
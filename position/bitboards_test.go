@@ -0,0 +1,107 @@
+package position
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+func TestBitBoardSquares(t *testing.T) {
+	b := NewBitBoard(square.A8, square.E4, square.H1)
+	if b.Count() != 3 {
+		t.Errorf("Count() = %d, want 3", b.Count())
+	}
+	if b.LSB() != square.H1 || b.MSB() != square.A8 {
+		t.Errorf("LSB() = %v, MSB() = %v", b.LSB(), b.MSB())
+	}
+	if got, want := b.Squares(), []square.Square{square.H1, square.E4, square.A8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Squares() = %v, want %v", got, want)
+	}
+	if !b.Has(square.E4) || b.Unset(square.E4).Has(square.E4) || !BitBoard(0).Set(square.E4).Has(square.E4) {
+		t.Error("Has, Set or Unset is wrong")
+	}
+	var empty BitBoard
+	if empty.LSB() != square.NoSquare || empty.MSB() != square.NoSquare || len(empty.Squares()) != 0 {
+		t.Error("empty bitboard has squares")
+	}
+}
+
+func TestBitBoardShifts(t *testing.T) {
+	tests := []struct {
+		name     string
+		shift    func(BitBoard) BitBoard
+		from, to square.Square
+		offBoard square.Square
+	}{
+		{"north", BitBoard.North, square.E4, square.E5, square.E8},
+		{"south", BitBoard.South, square.E4, square.E3, square.E1},
+		{"east", BitBoard.East, square.E4, square.F4, square.H4},
+		{"west", BitBoard.West, square.E4, square.D4, square.A4},
+		{"north east", BitBoard.NorthEast, square.E4, square.F5, square.H4},
+		{"north west", BitBoard.NorthWest, square.E4, square.D5, square.A4},
+		{"south east", BitBoard.SouthEast, square.E4, square.F3, square.H5},
+		{"south west", BitBoard.SouthWest, square.E4, square.D3, square.A5},
+	}
+	for _, test := range tests {
+		if got := test.shift(NewBitBoard(test.from)); got != NewBitBoard(test.to) {
+			t.Errorf("%s of %v:\n%s", test.name, test.from, got.Pretty())
+		}
+		if got := test.shift(NewBitBoard(test.offBoard)); got != 0 {
+			t.Errorf("%s of %v wrapped:\n%s", test.name, test.offBoard, got.Pretty())
+		}
+	}
+}
+
+func TestMasks(t *testing.T) {
+	if FileMask(square.C5) != FileC || RankMask(square.C5) != Rank5 {
+		t.Error("wrong file or rank mask for c5")
+	}
+	if got, want := DiagonalMask(square.C1), NewBitBoard(square.C1, square.D2, square.E3, square.F4, square.G5, square.H6); got != want {
+		t.Errorf("diagonal of c1:\n%s", got.Pretty())
+	}
+	if got, want := AntiDiagonalMask(square.C1), NewBitBoard(square.C1, square.B2, square.A3); got != want {
+		t.Errorf("anti-diagonal of c1:\n%s", got.Pretty())
+	}
+	if got, want := Between(square.A1, square.D4), NewBitBoard(square.B2, square.C3); got != want {
+		t.Errorf("between a1 and d4:\n%s", got.Pretty())
+	}
+	if got, want := Between(square.E8, square.E5), NewBitBoard(square.E7, square.E6); got != want {
+		t.Errorf("between e8 and e5:\n%s", got.Pretty())
+	}
+	if Between(square.A1, square.B3) != 0 || Between(square.A1, square.B2) != 0 || Line(square.A1, square.B3) != 0 {
+		t.Error("unaligned or adjacent squares have squares between them")
+	}
+	if Line(square.B2, square.G7) != DiagonalMask(square.A1) || Line(square.E2, square.E7) != FileE || Line(square.H3, square.A3) != Rank3 {
+		t.Error("wrong line")
+	}
+}
+
+func TestPretty(t *testing.T) {
+	want := "8  . . . . . . . x\n" +
+		"7  . . . . . . . .\n" +
+		"6  . . . . . . . .\n" +
+		"5  . . . . . . . .\n" +
+		"4  . . . . x . . .\n" +
+		"3  . . . . . . . .\n" +
+		"2  . . . . . . . .\n" +
+		"1  x . . . . . . .\n" +
+		"   a b c d e f g h\n"
+	if got := NewBitBoard(square.A1, square.E4, square.H8).Pretty(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPositionBitBoards(t *testing.T) {
+	p := New()
+	if got := p.Pieces(piece.White, piece.Knight); got != NewBitBoard(square.B1, square.G1) {
+		t.Errorf("white knights:\n%s", got.Pretty())
+	}
+	if got := p.Pieces(piece.BothColors, piece.King); got != NewBitBoard(square.E1, square.E8) {
+		t.Errorf("kings:\n%s", got.Pretty())
+	}
+	if p.Occupied(piece.White) != Rank1|Rank2 || p.Occupied(piece.BothColors) != Rank1|Rank2|Rank7|Rank8 {
+		t.Error("wrong occupancy")
+	}
+}
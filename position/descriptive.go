@@ -0,0 +1,208 @@
+package position
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// fileNames are the descriptive names of the files, from the a-file, after
+// the pieces that start on them.
+var fileNames = [8]string{"QR", "QN", "QB", "Q", "K", "KB", "KN", "KR"}
+
+// descriptiveLetters are the piece letters of descriptive notation, which
+// names pawns too.
+var descriptiveLetters = PieceLetters{piece.Pawn: "P", piece.Knight: "N", piece.Bishop: "B", piece.Rook: "R", piece.Queen: "Q", piece.King: "K"}
+
+// Regexp explanation:                            (side)(file)(    piece    )
+var regexpDescriptivePiece = regexp.MustCompile(`^([KQ]?)([RNB]?)([KQRNBP])(?:\(([KQ]?[RNB]?[1-8])\))?$`)
+
+// Regexp explanation:                             (side)(file)(rank)
+var regexpDescriptiveSquare = regexp.MustCompile(`^([KQ]?)([RNB]?)([1-8])$`)
+
+// regexpDescriptivePromotion matches the promotion at the end of a move.
+var regexpDescriptivePromotion = regexp.MustCompile(`(?:=([QRBN])|\(([QRBN])\))$`)
+
+// descriptiveSquare returns the name of the square from the player's side of
+// the board, like "KB3".
+func descriptiveSquare(s square.Square, player piece.Color) string {
+	rank := rankOf(s) + 1
+	if player == piece.Black {
+		rank = 8 - rankOf(s)
+	}
+	return fileNames[fileOf(s)] + strconv.Itoa(rank)
+}
+
+// Descriptive returns the move in English descriptive notation, like "P-K4",
+// "N-KB3" or "PxP". Squares are named in full, and pieces are told apart by
+// their side of the board or by their square when that is needed. It returns
+// an empty string for illegal moves.
+func (p Position) Descriptive(m move.Move) string {
+	san := p.SAN(m)
+	if san == "" {
+		return ""
+	}
+	suffix := ""
+	switch san[len(san)-1] {
+	case '+':
+		suffix = " ch"
+	case '#':
+		suffix = " mate"
+	}
+	if strings.HasPrefix(san, "O-O") {
+		return strings.TrimRight(san, "+#") + suffix
+	}
+	from, to := m.From(), m.To()
+	mover := p.OnSquare(from)
+	target := p.OnSquare(to)
+	if mover.Type == piece.Pawn && to == p.EnPassant {
+		target = piece.New(1-mover.Color, piece.Pawn)
+	}
+	promotion := ""
+	if m.Promote != piece.None {
+		promotion = "=" + descriptiveLetters[m.Promote]
+	}
+	named := func(pc piece.Piece, s square.Square) string {
+		return descriptiveLetters[pc.Type] + "(" + descriptiveSquare(s, p.ActiveColor) + ")"
+	}
+	var candidates []string
+	if target.Type == piece.None {
+		name := descriptiveSquare(to, p.ActiveColor)
+		candidates = []string{
+			descriptiveLetters[mover.Type] + "-" + name,
+			named(mover, from) + "-" + name,
+		}
+	} else {
+		ml, tl := descriptiveLetters[mover.Type], descriptiveLetters[target.Type]
+		qm, qt := qualified(mover, from), qualified(target, to)
+		candidates = []string{ml + "x" + tl, qm + "x" + tl, ml + "x" + qt, qm + "x" + qt, named(mover, from) + "x" + named(target, to)}
+	}
+	for _, c := range candidates {
+		if parsed, err := p.ParseDescriptive(c + promotion); err == nil && parsed == m {
+			return c + promotion + suffix
+		}
+	}
+	return ""
+}
+
+// qualified returns the letter of the piece told apart from others by its
+// side of the board, or by its file for pawns, like "KN" or "QBP".
+func qualified(pc piece.Piece, s square.Square) string {
+	if pc.Type == piece.Pawn {
+		return fileNames[fileOf(s)] + "P"
+	}
+	return fileNames[fileOf(s)][:1] + descriptiveLetters[pc.Type]
+}
+
+// ParseDescriptive parses a legal move written in English descriptive
+// notation, like "P-K4", "N-KB3", "QxP", "KRPxP" or "P-K8=Q". Squares may
+// leave out their side, like "Q-R5", and pieces may be given their square,
+// like "N(KB3)-Q2", as long as only one legal move fits.
+func (p Position) ParseDescriptive(s string) (move.Move, error) {
+	d := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	for trimmed := ""; trimmed != d; {
+		trimmed = d
+		for _, suffix := range []string{"MATE", "CH", "DIS", "DBL", "E.P.", "EP", "+", "#", "!", "?", "."} {
+			d = strings.TrimSuffix(d, suffix)
+		}
+	}
+	switch strings.Replace(d, "0", "O", -1) {
+	case "O-O", "O-O-O":
+		return p.parseLegal(s, strings.Replace(d, "0", "O", -1))
+	}
+	promote := piece.None
+	if matched := regexpDescriptivePromotion.FindStringSubmatch(d); matched != nil {
+		d = strings.TrimSuffix(d, matched[0])
+		for t, l := range descriptiveLetters {
+			if l == matched[1]+matched[2] {
+				promote = t
+			}
+		}
+	}
+	i := strings.IndexAny(d, "-X")
+	if i < 0 {
+		return move.Null, &MoveError{Move: s, Err: ErrInvalidSAN}
+	}
+	capture := d[i] == 'X'
+	mover := regexpDescriptivePiece.FindStringSubmatch(d[:i])
+	var target []string
+	if capture {
+		target = regexpDescriptivePiece.FindStringSubmatch(d[i+1:])
+	} else {
+		target = regexpDescriptiveSquare.FindStringSubmatch(d[i+1:])
+	}
+	if mover == nil || target == nil {
+		return move.Null, &MoveError{Move: s, Err: ErrInvalidSAN}
+	}
+	var found []move.Move
+	for m := range p.LegalMoves() {
+		from, to := m.From(), m.To()
+		moving, taken := p.OnSquare(from), p.OnSquare(to)
+		if moving.Type == piece.Pawn && to == p.EnPassant {
+			taken = piece.New(1-moving.Color, piece.Pawn)
+		}
+		if !p.fitsPiece(mover, moving, from) {
+			continue
+		}
+		if capture && !p.fitsPiece(target, taken, to) {
+			continue
+		}
+		if !capture && (taken.Type != piece.None || !p.fitsSquare(target[1], target[2], target[3], to)) {
+			continue
+		}
+		if m.Promote != promote && !(promote == piece.None && m.Promote == piece.Queen) {
+			continue
+		}
+		found = append(found, m)
+	}
+	switch len(found) {
+	case 0:
+		return move.Null, &MoveError{Move: s, Err: ErrIllegalMove}
+	case 1:
+		return found[0], nil
+	}
+	return move.Null, &MoveError{Move: s, Err: ErrAmbiguousMove}
+}
+
+// fitsPiece returns whether the piece on the square fits its description,
+// given as the submatches of regexpDescriptivePiece. A pawn's qualifier
+// names its file while a piece's names its side of the board.
+func (p Position) fitsPiece(description []string, pc piece.Piece, s square.Square) bool {
+	side, file, letter, at := description[1], description[2], description[3], description[4]
+	if pc.Type == piece.None || descriptiveLetters[pc.Type] != letter {
+		return false
+	}
+	if at != "" {
+		named := regexpDescriptiveSquare.FindStringSubmatch(at)
+		return p.fitsSquare(named[1], named[2], named[3], s)
+	}
+	name := fileNames[fileOf(s)]
+	switch {
+	case side == "" && file == "":
+		return true
+	case pc.Type != piece.Pawn && file == "":
+		return name[:1] == side
+	case file == "":
+		return name == side
+	}
+	return strings.HasSuffix(name, file) && (side == "" || name[:1] == side)
+}
+
+// fitsSquare returns whether the square fits its description from the side
+// of the player to move. The side of the board may be left out of the
+// description.
+func (p Position) fitsSquare(side, file, rank string, s square.Square) bool {
+	name := descriptiveSquare(s, p.ActiveColor)
+	if name[len(name)-1:] != rank {
+		return false
+	}
+	name = name[:len(name)-1]
+	if file == "" {
+		return name == side
+	}
+	return strings.HasSuffix(name, file) && (side == "" || name[:1] == side)
+}
@@ -11,8 +11,19 @@ import (
 func (p *Position) LegalMoves() map[move.Move]struct{} {
 	legalMoves := make(map[move.Move]struct{})
 	ml := p.Moves()
+	// Only the pieces matter for check, so each move is tried on a scratch
+	// board instead of a full copy of the position.
+	temp := &Position{bitBoard: newBitboards()}
 	for mv := range ml {
-		temp := p.MakeMove(mv)
+		// Every type is reset, since a promotion may have left a piece
+		// of a type the position does not have.
+		for _, c := range piece.Colors {
+			for t := piece.Pawn; t <= piece.King; t++ {
+				temp.bitBoard[c][t] = p.bitBoard[c][t]
+			}
+		}
+		from, to, movingPiece, capturedPiece := temp.decompose(mv)
+		temp.adjustBoard(mv, from, to, movingPiece, capturedPiece)
 		if temp.Check(p.ActiveColor) == false {
 			legalMoves[mv] = struct{}{}
 		}
@@ -38,11 +49,12 @@ func (p *Position) Moves() map[move.Move]struct{} {
 }
 
 func (p *Position) genKnightMoves(toMove, notToMove piece.Color, add func(move.Move)) {
+	own := p.occupied(toMove)
 	//piece.Knights:
 	pieces := p.bitBoard[toMove][piece.Knight]
 	for pieces != 0 {
 		from := bitscan(pieces)
-		destinations := knight_moves[from] &^ p.occupied(toMove)
+		destinations := knight_moves[from] &^ own
 		for destinations != 0 {
 			to := bitscan(destinations)
 			add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -53,6 +65,8 @@ func (p *Position) genKnightMoves(toMove, notToMove piece.Color, add func(move.M
 }
 
 func (p *Position) genDiagnalMoves(toMove, notToMove piece.Color, add func(move.Move)) {
+	occupied := p.occupied(piece.BothColors)
+	own := p.occupied(toMove)
 	// piece.Bishops/piece.Queens:
 	pieces := p.bitBoard[toMove][piece.Bishop] | p.bitBoard[toMove][piece.Queen]
 	direction := [4][65]uint64{ne, nw, se, sw}
@@ -61,9 +75,9 @@ func (p *Position) genDiagnalMoves(toMove, notToMove piece.Color, add func(move.
 		from := bitscan(pieces)
 		for i := 0; i < 4; i++ {
 			destinations := direction[i][from]
-			blockerIndex := scan[i](destinations & p.occupied(piece.BothColors))
+			blockerIndex := scan[i](destinations & occupied)
 			destinations ^= direction[i][blockerIndex]
-			destinations &^= p.occupied(toMove)
+			destinations &^= own
 			for destinations != 0 {
 				to := bitscan(destinations)
 				add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -75,6 +89,8 @@ func (p *Position) genDiagnalMoves(toMove, notToMove piece.Color, add func(move.
 }
 
 func (p *Position) genStraightMoves(toMove, notToMove piece.Color, add func(move.Move)) {
+	occupied := p.occupied(piece.BothColors)
+	own := p.occupied(toMove)
 	// Rooks/piece.Queens:
 	pieces := p.bitBoard[toMove][piece.Rook] | p.bitBoard[toMove][piece.Queen]
 	direction := [4][65]uint64{north, west, south, east}
@@ -83,9 +99,9 @@ func (p *Position) genStraightMoves(toMove, notToMove piece.Color, add func(move
 		from := bitscan(pieces)
 		for i := 0; i < 4; i++ {
 			destinations := direction[i][from]
-			blockerIndex := scan[i](destinations & p.occupied(piece.BothColors))
+			blockerIndex := scan[i](destinations & occupied)
 			destinations ^= direction[i][blockerIndex]
-			destinations &^= p.occupied(toMove)
+			destinations &^= own
 			for destinations != 0 {
 				to := bitscan(destinations)
 				add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -97,10 +113,12 @@ func (p *Position) genStraightMoves(toMove, notToMove piece.Color, add func(move
 }
 
 func (p *Position) genKingMoves(toMove, notToMove piece.Color, castlingRights map[piece.Color]map[board.Side]bool, add func(move.Move)) {
+	occupied := p.occupied(piece.BothColors)
+	own := p.occupied(toMove)
 	pieces := p.bitBoard[toMove][piece.King]
 	{
 		from := bitscan(pieces)
-		destinations := king_moves[from] &^ p.occupied(toMove)
+		destinations := king_moves[from] &^ own
 		for destinations != 0 {
 			to := bitscan(destinations)
 			add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -108,7 +126,7 @@ func (p *Position) genKingMoves(toMove, notToMove piece.Color, castlingRights ma
 		}
 		// Castles:
 		if castlingRights[toMove][board.ShortSide] == true {
-			if square.Square(bsr(east[from]&p.occupied(piece.BothColors))) == []square.Square{square.H1, square.H8}[toMove] {
+			if square.Square(bsr(east[from]&occupied)) == []square.Square{square.H1, square.H8}[toMove] {
 				if (p.Threatened([]square.Square{square.F1, square.F8}[toMove], notToMove) == false) &&
 					(p.Threatened([]square.Square{square.G1, square.G8}[toMove], notToMove) == false) &&
 					(p.Threatened([]square.Square{square.E1, square.E8}[toMove], notToMove) == false) {
@@ -117,7 +135,7 @@ func (p *Position) genKingMoves(toMove, notToMove piece.Color, castlingRights ma
 			}
 		}
 		if castlingRights[toMove][board.LongSide] == true {
-			if square.Square(bsf(west[from]&p.occupied(piece.BothColors))) == []square.Square{square.A1, square.A8}[toMove] {
+			if square.Square(bsf(west[from]&occupied)) == []square.Square{square.A1, square.A8}[toMove] {
 				if (p.Threatened([]square.Square{square.D1, square.D8}[toMove], notToMove) == false) &&
 					(p.Threatened([]square.Square{square.C1, square.C8}[toMove], notToMove) == false) &&
 					(p.Threatened([]square.Square{square.E1, square.E8}[toMove], notToMove) == false) {
@@ -129,16 +147,18 @@ func (p *Position) genKingMoves(toMove, notToMove piece.Color, castlingRights ma
 }
 
 func (p *Position) genPawnMoves(toMove, notToMove piece.Color, enPassant square.Square, add func(move.Move)) {
+	occupied := p.occupied(piece.BothColors)
+	enemy := p.occupied(notToMove)
 	pieces := p.bitBoard[toMove][piece.Pawn] &^ pawns_spawn[notToMove] //&^ = AND_NOT
 	for pieces != 0 {
 		from := bitscan(pieces)
 		//advances:
-		advance := pawn_advances[toMove][from] &^ p.occupied(piece.BothColors)
+		advance := pawn_advances[toMove][from] &^ occupied
 		if advance != 0 {
 			to := bitscan(advance)
 			add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
 
-			advance = pawn_double_advances[toMove][from] &^ p.occupied(piece.BothColors)
+			advance = pawn_double_advances[toMove][from] &^ occupied
 			if advance != 0 {
 				to = bitscan(advance)
 				add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -149,7 +169,7 @@ func (p *Position) genPawnMoves(toMove, notToMove piece.Color, enPassant square.
 		if enPassant != square.NoSquare {
 			enpas = (1 << enPassant)
 		}
-		captures := pawn_captures[toMove][from] & (p.occupied(notToMove) | enpas)
+		captures := pawn_captures[toMove][from] & (enemy | enpas)
 		for captures != 0 {
 			to := bitscan(captures)
 			add(move.Move{Source: square.Square(from), Destination: square.Square(to), Promote: piece.None})
@@ -162,8 +182,8 @@ func (p *Position) genPawnMoves(toMove, notToMove piece.Color, enPassant square.
 	pieces = p.bitBoard[toMove][piece.Pawn] & pawns_spawn[notToMove]
 	for pieces != 0 {
 		from := bitscan(pieces)
-		destinations := pawn_advances[toMove][from] &^ p.occupied(piece.BothColors)
-		destinations |= pawn_captures[toMove][from] & p.occupied(notToMove)
+		destinations := pawn_advances[toMove][from] &^ occupied
+		destinations |= pawn_captures[toMove][from] & enemy
 		for destinations != 0 {
 			to := bitscan(destinations)
 			p := []piece.Type{piece.Queen, piece.Rook, piece.Bishop, piece.Knight}
@@ -179,6 +199,7 @@ func (p *Position) genPawnMoves(toMove, notToMove piece.Color, enPassant square.
 // Threatened returns whether or not the specified square is under attack
 // by the specified color.
 func (p *Position) Threatened(square square.Square, byWho piece.Color) bool {
+	occupied := p.occupied(piece.BothColors)
 	defender := []piece.Color{piece.Black, piece.White}[byWho]
 
 	// other king attacks:
@@ -196,18 +217,15 @@ func (p *Position) Threatened(square square.Square, byWho piece.Color) bool {
 		return true
 	}
 	// diagonal attacks:
-	direction := [4][65]uint64{nw, ne, sw, se}
-	scan := [4]func(uint64) uint{bsf, bsf, bsr, bsr}
 	for i := 0; i < 4; i++ {
-		blockerIndex := scan[i](direction[i][square] & p.occupied(piece.BothColors))
+		blockerIndex := rayScans[i](diagonals[i][square] & occupied)
 		if (1<<blockerIndex)&(p.bitBoard[byWho][piece.Bishop]|p.bitBoard[byWho][piece.Queen]) != 0 {
 			return true
 		}
 	}
 	// straight attacks:
-	direction = [4][65]uint64{north, west, south, east}
 	for i := 0; i < 4; i++ {
-		blockerIndex := scan[i](direction[i][square] & p.occupied(piece.BothColors))
+		blockerIndex := rayScans[i](straights[i][square] & occupied)
 		if (1<<blockerIndex)&(p.bitBoard[byWho][piece.Rook]|p.bitBoard[byWho][piece.Queen]) != 0 {
 			return true
 		}
@@ -75,3 +75,35 @@ func TestGenDiagCaps(t *testing.T) {
 func TestEnPassant(t *testing.T) {
 	// TODO
 }
+
+func BenchmarkLegalMoves(b *testing.B) {
+	p := New()
+	for i := 0; i < b.N; i++ {
+		p.LegalMoves()
+	}
+}
+
+func BenchmarkPolyglot(b *testing.B) {
+	p := New()
+	for i := 0; i < b.N; i++ {
+		p.Polyglot()
+	}
+}
+
+func TestLegalMovesAfterPromotion(t *testing.T) {
+	// The white king is in check, and only a promotion on c8 blocks it.
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A8)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.C7)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.E2)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H1)
+	for i := 0; i < 50; i++ {
+		for m := range b.LegalMoves() {
+			if b.MakeMove(m).Check(piece.White) {
+				t.Fatal(m, "leaves the king in check")
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package position
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// PieceLetters is how a language writes the pieces in algebraic notation.
+type PieceLetters map[piece.Type]string
+
+// The piece letters of some languages.
+var (
+	English    = PieceLetters{piece.Knight: "N", piece.Bishop: "B", piece.Rook: "R", piece.Queen: "Q", piece.King: "K"}
+	German     = PieceLetters{piece.Knight: "S", piece.Bishop: "L", piece.Rook: "T", piece.Queen: "D", piece.King: "K"}
+	French     = PieceLetters{piece.Knight: "C", piece.Bishop: "F", piece.Rook: "T", piece.Queen: "D", piece.King: "R"}
+	Spanish    = PieceLetters{piece.Knight: "C", piece.Bishop: "A", piece.Rook: "T", piece.Queen: "D", piece.King: "R"}
+	Italian    = PieceLetters{piece.Knight: "C", piece.Bishop: "A", piece.Rook: "T", piece.Queen: "D", piece.King: "R"}
+	Dutch      = PieceLetters{piece.Knight: "P", piece.Bishop: "L", piece.Rook: "T", piece.Queen: "D", piece.King: "K"}
+	Portuguese = PieceLetters{piece.Knight: "C", piece.Bishop: "B", piece.Rook: "T", piece.Queen: "D", piece.King: "R"}
+	Swedish    = PieceLetters{piece.Knight: "S", piece.Bishop: "L", piece.Rook: "T", piece.Queen: "D", piece.King: "K"}
+)
+
+// translate rewrites the piece letters of a move from one language to
+// another.
+func translate(s string, from, to PieceLetters) string {
+	var b strings.Builder
+	for _, r := range s {
+		letter := string(r)
+		for t, l := range from {
+			if l == letter {
+				letter = to[t]
+				break
+			}
+		}
+		b.WriteString(letter)
+	}
+	return b.String()
+}
+
+// parseLegal parses the move with ParseMove, as normalized, and makes sure it
+// is legal. Errors name the move as it was written.
+func (p Position) parseLegal(written, normalized string) (move.Move, error) {
+	m, err := p.ParseMove(normalized)
+	if e, ok := err.(*MoveError); ok {
+		e.Move = written
+		return m, e
+	}
+	if _, legal := p.LegalMoves()[m]; !legal {
+		return move.Null, &MoveError{Move: written, Err: ErrIllegalMove}
+	}
+	return m, nil
+}
+
+// LocalSAN returns the move in SAN with the piece letters of a language, like
+// "Sf3" in German. It returns an empty string for illegal moves.
+func (p Position) LocalSAN(m move.Move, letters PieceLetters) string {
+	return translate(p.SAN(m), English, letters)
+}
+
+// ParseLocalSAN parses a legal move written in algebraic notation with the
+// piece letters of a language. It is as lenient as ParseMove.
+func (p Position) ParseLocalSAN(s string, letters PieceLetters) (move.Move, error) {
+	return p.parseLegal(s, translate(s, letters, English))
+}
+
+// figurineLetters returns the figurines of the player's pieces as piece letters.
+func figurineLetters(c piece.Color) PieceLetters {
+	letters := make(PieceLetters)
+	for t := range English {
+		letters[t] = piece.New(c, t).Figurine()
+	}
+	return letters
+}
+
+// FigurineSAN returns the move in figurine algebraic notation, which is SAN
+// with the pieces drawn as figurines, like "♘f3". It returns an empty
+// string for illegal moves.
+func (p Position) FigurineSAN(m move.Move) string {
+	return p.LocalSAN(m, figurineLetters(p.ActiveColor))
+}
+
+// ParseFigurineSAN parses a legal move written in figurine algebraic
+// notation. Figurines of either color are understood.
+func (p Position) ParseFigurineSAN(s string) (move.Move, error) {
+	return p.parseLegal(s, s)
+}
+
+// LAN returns the move in long algebraic notation, which names both squares,
+// like "Ng1-f3" or "e4xd5". It returns an empty string for illegal moves.
+func (p Position) LAN(m move.Move) string {
+	san := p.SAN(m)
+	if san == "" || strings.HasPrefix(san, "O-O") {
+		return san
+	}
+	lan := English[p.OnSquare(m.From()).Type] + m.From().String()
+	if strings.Contains(san, "x") {
+		lan += "x"
+	} else {
+		lan += "-"
+	}
+	lan += m.To().String()
+	if m.Promote != piece.None {
+		lan += "=" + English[m.Promote]
+	}
+	if last := san[len(san)-1]; last == '+' || last == '#' {
+		lan += string(last)
+	}
+	return lan
+}
+
+// ParseLAN parses a legal move written in long algebraic notation. It is as
+// lenient as ParseMove.
+func (p Position) ParseLAN(s string) (move.Move, error) {
+	return p.parseLegal(s, s)
+}
+
+// iccfPromotions are the digits ICCF numeric notation gives promotions.
+var iccfPromotions = map[piece.Type]string{piece.Queen: "1", piece.Rook: "2", piece.Bishop: "3", piece.Knight: "4"}
+
+// regexpICCF matches the files and ranks of the squares of a move, followed
+// by the promotion.
+var regexpICCF = regexp.MustCompile("^([1-8])([1-8])([1-8])([1-8])([1-4]?)$")
+
+// ICCF returns the move in the numeric notation of the International
+// Correspondence Chess Federation, which numbers the files and ranks of both
+// squares, like "5254" for e2-e4. It returns an empty string for illegal
+// moves.
+func (p Position) ICCF(m move.Move) string {
+	m.Duration = 0
+	if _, legal := p.LegalMoves()[m]; !legal {
+		return ""
+	}
+	s := ""
+	for _, sq := range []square.Square{m.From(), m.To()} {
+		s += strconv.Itoa(fileOf(sq)+1) + strconv.Itoa(rankOf(sq)+1)
+	}
+	return s + iccfPromotions[m.Promote]
+}
+
+// ParseICCF parses a legal move written in ICCF numeric notation. A pawn
+// reaching the last rank without a promotion digit promotes to a queen.
+func (p Position) ParseICCF(s string) (move.Move, error) {
+	matched := regexpICCF.FindStringSubmatch(strings.TrimSpace(s))
+	if matched == nil {
+		return move.Null, &MoveError{Move: s, Err: ErrInvalidSAN}
+	}
+	digit := func(i int) uint {
+		d, _ := strconv.Atoi(matched[i])
+		return uint(d)
+	}
+	m := move.Move{Source: square.New(digit(1), digit(2)), Destination: square.New(digit(3), digit(4))}
+	for t, d := range iccfPromotions {
+		if d == matched[5] {
+			m.Promote = t
+		}
+	}
+	if m.Promote == piece.None && p.OnSquare(m.From()).Type == piece.Pawn && (digit(4) == 1 || digit(4) == 8) {
+		m.Promote = piece.Queen
+	}
+	if _, legal := p.LegalMoves()[m]; !legal {
+		return move.Null, &MoveError{Move: s, Err: ErrIllegalMove}
+	}
+	return m, nil
+}
+
+// fileOf returns the file of the square, 0 for the a-file.
+func fileOf(s square.Square) int {
+	return 7 - int(s)%8
+}
+
+// rankOf returns the rank of the square, 0 for the first rank.
+func rankOf(s square.Square) int {
+	return int(s) / 8
+}
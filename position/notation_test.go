@@ -0,0 +1,107 @@
+package position
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andrewbackes/chess/position/move"
+)
+
+func TestNotations(t *testing.T) {
+	ruy := []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}
+	tests := []struct {
+		moves                               []string
+		pcn                                 string
+		lan, figurine, german, french, iccf string
+	}{
+		{nil, "g1f3", "Ng1-f3", "♘f3", "Sf3", "Cf3", "7163"},
+		{nil, "e2e4", "e2-e4", "e4", "e4", "e4", "5254"},
+		{[]string{"e4", "d5"}, "e4d5", "e4xd5", "exd5", "exd5", "exd5", "5445"},
+		{ruy, "g8f6", "Ng8-f6", "♞f6", "Sf6", "Cf6", "7866"},
+		{append(ruy, "Nf6"), "e1g1", "O-O", "O-O", "O-O", "O-O", "5171"},
+		{[]string{"f3", "e5", "g4"}, "d8h4", "Qd8-h4#", "♛h4#", "Dh4#", "Dh4#", "4884"},
+	}
+	for _, test := range tests {
+		p := played(t, test.moves...)
+		m := move.Parse(test.pcn)
+		got := []string{p.LAN(m), p.FigurineSAN(m), p.LocalSAN(m, German), p.LocalSAN(m, French), p.ICCF(m)}
+		want := []string{test.lan, test.figurine, test.german, test.french, test.iccf}
+		parsers := []func(string) (move.Move, error){
+			p.ParseLAN,
+			p.ParseFigurineSAN,
+			func(s string) (move.Move, error) { return p.ParseLocalSAN(s, German) },
+			func(s string) (move.Move, error) { return p.ParseLocalSAN(s, French) },
+			p.ParseICCF,
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %s, want %s", test.pcn, got[i], want[i])
+			}
+			if parsed, err := parsers[i](want[i]); err != nil || parsed != m {
+				t.Errorf("%s: %s parsed as %s, %v", test.pcn, want[i], parsed, err)
+			}
+		}
+	}
+}
+
+func TestICCFPromotion(t *testing.T) {
+	p := played(t, "b4", "a5", "bxa5", "b6", "axb6", "Nc6", "b7", "Rb8")
+	m := move.Parse("b7c8n")
+	if p.ICCF(m) != "27384" {
+		t.Error("got", p.ICCF(m))
+	}
+	if parsed, _ := p.ParseICCF("2738"); parsed != move.Parse("b7c8q") {
+		t.Error("got", parsed)
+	}
+	if _, err := p.ParseICCF("1113"); !errors.Is(err, ErrIllegalMove) {
+		t.Error("got", err)
+	}
+}
+
+func TestDescriptive(t *testing.T) {
+	ruy := []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}
+	tests := []struct {
+		moves       []string
+		pcn         string
+		descriptive string
+		also        []string
+	}{
+		{nil, "e2e4", "P-K4", []string{"p-k4", "KP-K4"}},
+		{nil, "g1f3", "N-KB3", []string{"KN-KB3", "N-KB3!"}},
+		{[]string{"e4"}, "e7e5", "P-K4", nil},
+		{[]string{"e4", "e5"}, "g1f3", "N-KB3", nil},
+		{ruy[:4], "f1b5", "B-QN5", []string{"B-N5"}},
+		{ruy, "a7a6", "P-QR3", []string{"QRP-QR3"}},
+		{[]string{"e4", "d5"}, "e4d5", "PxP", []string{"KPxP", "PxQP", "P(K4)xP(Q5)"}},
+		{[]string{"e4", "d5", "exd5"}, "d8d5", "QxP", nil},
+		{[]string{"e4", "a6", "e5", "d5"}, "e5d6", "PxP", []string{"PxP e.p.", "PxPep"}},
+		{[]string{"e4", "d5", "d4", "e5"}, "e4d5", "KPxP", []string{"PxQP"}},
+		{append(ruy, "Nf6"), "e1g1", "O-O", []string{"0-0"}},
+		{[]string{"f3", "e5", "g4"}, "d8h4", "Q-KR5 mate", []string{"Q-R5ch"}},
+		{[]string{"b4", "a5", "bxa5", "b6", "axb6", "Nc6", "b7", "Rb8"}, "b7c8r", "PxB=R", []string{"PxB(R)"}},
+	}
+	for _, test := range tests {
+		p := played(t, test.moves...)
+		m := move.Parse(test.pcn)
+		if got := p.Descriptive(m); got != test.descriptive {
+			t.Errorf("%s: got %s, want %s", test.pcn, got, test.descriptive)
+		}
+		for _, s := range append(test.also, test.descriptive) {
+			if parsed, err := p.ParseDescriptive(s); err != nil || parsed != m {
+				t.Errorf("%s parsed as %s, %v", s, parsed, err)
+			}
+		}
+	}
+	if _, err := played(t).ParseDescriptive("N-B3"); !errors.Is(err, ErrAmbiguousMove) {
+		t.Error("got", err)
+	}
+	if _, err := played(t, ruy...).ParseDescriptive("P-R3"); !errors.Is(err, ErrAmbiguousMove) {
+		t.Error("got", err)
+	}
+	if _, err := played(t).ParseDescriptive("P-K5"); !errors.Is(err, ErrIllegalMove) {
+		t.Error("got", err)
+	}
+	if _, err := played(t).ParseDescriptive("e4"); !errors.Is(err, ErrInvalidSAN) {
+		t.Error("got", err)
+	}
+}
@@ -16,23 +16,130 @@ var regexpPCN = regexp.MustCompile("^\\s*([a-h][1-8])([a-h][1-8])([QBNRqbnr]?)\\
 // Regexp explanation:                   (  piece  )( file )( rank )(cap )(   dest   )(    promotion    )( chk )
 var regexpSAN = regexp.MustCompile("^\\s*([BKNPQR]?)([a-h]?)([1-8]?)([x]?)([a-h][1-8])([=]?[BNPQRbnpqr]?)([+#]?)\\s*$")
 
+// regexpStrictSAN matches SAN exactly as the PGN standard writes it.
+var regexpStrictSAN = regexp.MustCompile("^(O-O|O-O-O|[KQRBN][a-h]?[1-8]?x?[a-h][1-8]|([a-h]x)?[a-h][1-8](=[QRBN])?)[+#]?$")
+
+// regexpColonCapture matches a capture written with ":" before the
+// destination square.
+var regexpColonCapture = regexp.MustCompile(":([a-h][1-8])")
+
+// regexpPromotionSuffix matches a promotion written as "(Q)" or "/Q" at the
+// end of a move.
+var regexpPromotionSuffix = regexp.MustCompile("(?:\\(([QRBNqrbn])\\)|/([QRBNqrbn]))$")
+
+var (
+	// ErrInvalidSAN means a move is not written in a notation that is
+	// understood.
+	ErrInvalidSAN = errors.New("invalid move notation")
+	// ErrIllegalMove means no legal move fits what was written.
+	ErrIllegalMove = errors.New("illegal move")
+	// ErrAmbiguousMove means more than one legal move fits what was written.
+	ErrAmbiguousMove = errors.New("ambiguous move")
+	// ErrNonStandardSAN means a legal move is not written the way SAN
+	// requires, which only ParseSAN rejects.
+	ErrNonStandardSAN = errors.New("move is not in standard algebraic notation")
+)
+
+// MoveError is the error returned for moves that could not be parsed. Err is
+// one of ErrInvalidSAN, ErrIllegalMove, ErrAmbiguousMove and
+// ErrNonStandardSAN.
+type MoveError struct {
+	Move string
+	Err  error
+	// Want is how the move should have been written, for ErrNonStandardSAN.
+	Want string
+}
+
+func (e *MoveError) Error() string {
+	switch e.Err {
+	case ErrInvalidSAN:
+		return "could not parse '" + e.Move + "'"
+	case ErrIllegalMove, ErrAmbiguousMove:
+		// Unwrap tells the two apart, the message is the one ParseMove
+		// has always returned.
+		return "could not find source square of '" + e.Move + "'"
+	}
+	return "'" + e.Move + "' should be written '" + e.Want + "'"
+}
+
+// Unwrap returns the reason the move could not be parsed.
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// figurines are the chess symbols that stand for piece letters.
+var figurines = strings.NewReplacer(
+	"♔", "K", "♕", "Q", "♖", "R", "♗", "B", "♘", "N", "♙", "",
+	"♚", "K", "♛", "Q", "♜", "R", "♝", "B", "♞", "N", "♟", "",
+)
+
+// ParseSAN parses a move written in standard algebraic notation exactly as
+// the PGN standard requires, with the check or mate suffix and only as much
+// disambiguation as needed. The move must be legal. Use ParseMove to accept
+// moves the way people and programs actually write them.
+func (p Position) ParseSAN(san string) (move.Move, error) {
+	if !regexpStrictSAN.MatchString(san) {
+		return move.Null, &MoveError{Move: san, Err: ErrInvalidSAN}
+	}
+	m, err := p.ParseLegalMove(san)
+	if err != nil {
+		return move.Null, err
+	}
+	if want := p.SAN(m); want != san {
+		return move.Null, &MoveError{Move: san, Err: ErrNonStandardSAN, Want: want}
+	}
+	return m, nil
+}
+
+// ParseLegalMove parses the move like ParseMove does, and also checks that it
+// is a legal move in the position.
+func (p Position) ParseLegalMove(san string) (move.Move, error) {
+	m, err := p.ParseMove(san)
+	if err != nil {
+		return move.Null, err
+	}
+	if _, legal := p.LegalMoves()[m]; !legal {
+		return move.Null, &MoveError{Move: san, Err: ErrIllegalMove}
+	}
+	return m, nil
+}
+
 // ParseMove transforms a move written in standard algebraic notation (SAN)
 // to a move written in Pure Coordinate Notation (PCN).
 //
+// ParseMove is lenient about how the move is written. Besides SAN and PCN it
+// accepts castling written with zeros, figurines for pieces, ":" for
+// captures, missing or superfluous check and mate suffixes, promotions
+// without "=" or written as "(Q)" or "/Q", an "e.p." suffix and annotations
+// like "!?". Errors are of type *MoveError.
+//
 // ParseMove will not check the legality of the move and/or promotion.
 // If move is a valid promotion move (in SAN or PCN) and promotion is ommited, a promotion to Queen is returned.
 func (p Position) ParseMove(san string) (move.Move, error) {
+	written := san
 
 	// Check for null move:
 	if san == "0000" {
 		return move.Parse(san), nil
 	}
 	color := p.ActiveColor
+
+	// Reduce the ways of writing a move to the SAN the rest expects:
+	san = figurines.Replace(strings.TrimSpace(san))
+	san = strings.TrimRight(san, "!?+#")
+	for _, ep := range []string{"e.p.", "ep"} {
+		if strings.HasSuffix(san, ep) {
+			san = strings.TrimSpace(strings.TrimSuffix(san, ep))
+		}
+	}
+	san = regexpColonCapture.ReplaceAllString(san, "x$1")
+	san = regexpPromotionSuffix.ReplaceAllString(san, "$1$2")
+
 	// Check for castling:
-	if san == "O-O" {
+	switch strings.ToUpper(strings.Replace(san, "0", "O", -1)) {
+	case "O-O":
 		return move.Parse([]string{"e1g1", "e8g8"}[color]), nil
-	}
-	if san == "O-O-O" {
+	case "O-O-O":
 		return move.Parse([]string{"e1c1", "e8c8"}[color]), nil
 	}
 
@@ -60,7 +167,7 @@ func (p Position) ParseMove(san string) (move.Move, error) {
 
 	matched := regexpSAN.FindStringSubmatch(san)
 	if len(matched) == 0 {
-		return move.Parse(san), errors.New("could not parse '" + san + "'")
+		return move.Parse(san), &MoveError{Move: written, Err: ErrInvalidSAN}
 	}
 
 	piece := matched[1]
@@ -77,7 +184,7 @@ func (p Position) ParseMove(san string) (move.Move, error) {
 
 	origin, err := p.originOfPiece(piece, color, destination, fromFile, fromRank)
 	if err != nil {
-		return move.Parse(san), errors.New("could not find source square of '" + san + "'")
+		return move.Parse(san), &MoveError{Move: written, Err: err}
 	}
 
 	// Some engines don't tell you to promote to queen, so assume so in that case:
@@ -141,13 +248,13 @@ func (p Position) originOfPiece(pc string, color piece.Color, destination, fromF
 			if exactSquare == "" {
 				exactSquare = sq
 			} else {
-				return "", errors.New("Notation: Can not find source square.")
+				return "", ErrAmbiguousMove
 			}
 		}
 	}
 
 	if exactSquare == "" {
-		return "", errors.New("Notation: Can not find source square.")
+		return "", ErrIllegalMove
 	}
 
 	return exactSquare, nil
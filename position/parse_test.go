@@ -147,7 +147,7 @@ var testParseMoveGroups []testParseMoveTestCases = []testParseMoveTestCases{
 			{"SAN-Move-Mate-Black", active(piece.Black), "Bc3#", "d4c3", nil},
 			{"SAN-Move-InvalidMissingMate-White", active(piece.White), "Bc6", "d5c6", nil},
 			{"SAN-Move-InvalidMissingMate-Black", active(piece.Black), "Bc3", "d4c3", nil},
-			{"SAN-Move-InvalidSuffixCharacter-White", active(piece.White), "Kd2!", "", errors.New("could not parse 'Kd2!'")},
+			{"SAN-Move-AnnotationSuffix-White", active(piece.White), "Kd2!", "e1d2", nil},
 			{"SAN-Move-InvalidSuffixCharacter-Black", active(piece.Black), "Kd7@", "", errors.New("could not parse 'Kd7@'")},
 		},
 	},
@@ -1635,3 +1635,89 @@ func benchmarkParseMove(b *testing.B, benches []benchParseMove) {
 		_, _ = m, err
 	}
 }
+
+// played returns the position after the moves.
+func played(t *testing.T, moves ...string) *Position {
+	p := New()
+	for _, san := range moves {
+		m, err := p.ParseMove(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p = p.MakeMove(m)
+	}
+	return p
+}
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		moves []string
+		san   string
+		want  string
+		err   error
+	}{
+		{nil, "Nf3", "g1f3", nil},
+		{nil, "Ng1f3", "", ErrNonStandardSAN},
+		{nil, "nf3", "", ErrInvalidSAN},
+		{nil, "Nf3!", "", ErrInvalidSAN},
+		{nil, "Nf4", "", ErrIllegalMove},
+		{nil, "O-O", "", ErrIllegalMove},
+		{[]string{"Nf3", "e5", "d3", "e4"}, "Nd2", "", ErrAmbiguousMove},
+		{[]string{"Nf3", "e5", "d3", "e4"}, "Nbd2", "b1d2", nil},
+		{[]string{"Nf3", "e5", "d3", "e4"}, "N1d2", "", ErrNonStandardSAN},
+		{[]string{"e4", "f5"}, "Qh5", "", ErrNonStandardSAN},
+		{[]string{"e4", "f5"}, "Qh5+", "d1h5", nil},
+		{[]string{"f3", "e5", "g4"}, "Qh4#", "d8h4", nil},
+		{[]string{"e4", "a6", "e5", "d5"}, "exd6", "e5d6", nil},
+		{[]string{"Nc3", "h6", "Nb5", "h5", "e4", "d5"}, "Nd6+", "b5d6", nil},
+	}
+	for _, test := range tests {
+		m, err := played(t, test.moves...).ParseSAN(test.san)
+		if !errors.Is(err, test.err) || (err == nil && m.String() != test.want) {
+			t.Errorf("%v %s: got %s, %v", test.moves, test.san, m, err)
+		}
+	}
+	_, err := played(t, "e4", "f5").ParseSAN("Qh5")
+	if e, ok := err.(*MoveError); !ok || e.Want != "Qh5+" {
+		t.Error("got", err)
+	}
+}
+
+func TestParseMoveLenient(t *testing.T) {
+	castle := []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5"}
+	enPassant := []string{"e4", "a6", "e5", "d5"}
+	promotion := []string{"h4", "g5", "hxg5", "h6", "gxh6", "Nf6", "h7", "Rg8"}
+	tests := []struct {
+		moves []string
+		move  string
+		want  string
+	}{
+		{castle, "0-0", "e1g1"},
+		{castle, "o-o", "e1g1"},
+		{castle, "O-O+", "e1g1"},
+		{nil, "♘f3", "g1f3"},
+		{nil, "♙e4", "e2e4"},
+		{nil, "Nf3!?", "g1f3"},
+		{nil, "Nf3+", "g1f3"},
+		{nil, " Ng1-f3 ", "g1f3"},
+		{enPassant, "exd6 e.p.", "e5d6"},
+		{enPassant, "exd6ep", "e5d6"},
+		{enPassant, "e5:d6", "e5d6"},
+		{enPassant, "ed6", "e5d6"},
+		{promotion, "h8(N)", "h7h8n"},
+		{promotion, "h8/N", "h7h8n"},
+		{promotion, "h7:g8(Q)", "h7g8q"},
+		{promotion, "h7h8(n)", "h7h8n"},
+	}
+	for _, test := range tests {
+		m, err := played(t, test.moves...).ParseMove(test.move)
+		if err != nil || m.String() != test.want {
+			t.Errorf("%v %s: got %s, %v", test.moves, test.move, m, err)
+		}
+	}
+	for _, junk := range []string{"e(2)/e4", "e2/e4", "(e4)", "Nf:3", "e4:"} {
+		if m, err := New().ParseMove(junk); err == nil {
+			t.Errorf("%s: got %s, want an error", junk, m)
+		}
+	}
+}
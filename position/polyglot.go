@@ -14,12 +14,14 @@ type Hash uint64
 func (p *Position) Polyglot() Hash {
 	var hash uint64
 	// pieces:
-	for s := square.Square(0); s <= square.LastSquare; s++ {
-		if pp := p.OnSquare(s); pp.Type != piece.None {
-			pc := pieceToPG(pp)
-			file, row := indexToFR(int(s))
-			index := 64*pc + 8*row + file
-			hash ^= randomPiece[index]
+	for _, c := range piece.Colors {
+		for t := piece.Pawn; t <= piece.King; t++ {
+			pc := pieceToPG(piece.New(c, t))
+			for b := p.bitBoard[c][t]; b != 0; b &= b - 1 {
+				file, row := indexToFR(int(bitscan(b)))
+				index := 64*pc + 8*row + file
+				hash ^= randomPiece[index]
+			}
 		}
 	}
 
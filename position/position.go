@@ -1,5 +1,10 @@
 // Package position is for working with chess positions. It holds the state of
 // a chess game at a particular move.
+//
+// MakeMove returns a new position rather than changing the one it is called
+// on, and no other method changes a position apart from Clear, Reset, Put and
+// QuickPut. A position that is no longer being set up can therefore be read
+// from any number of goroutines at once. Use Copy to get one to change.
 package position
 
 import (
@@ -73,6 +78,16 @@ func New() *Position {
 
 // Copy makes an exact copy of the position.
 func Copy(p *Position) *Position {
+	n := copyBoard(p)
+	n.ThreeFoldCount = make(map[Hash]int, len(p.ThreeFoldCount))
+	for k, v := range p.ThreeFoldCount {
+		n.ThreeFoldCount[k] = v
+	}
+	return n
+}
+
+// copyBoard copies everything but the repetition counts.
+func copyBoard(p *Position) *Position {
 	n := &Position{
 		bitBoard:       newBitboards(),
 		MoveNumber:     p.MoveNumber,
@@ -80,14 +95,10 @@ func Copy(p *Position) *Position {
 		EnPassant:      p.EnPassant,
 		FiftyMoveCount: p.FiftyMoveCount,
 		CastlingRights: make(map[piece.Color]map[board.Side]bool),
-		ThreeFoldCount: make(map[Hash]int),
 		MovesLeft:      make(map[piece.Color]int),
 		Clocks:         make(map[piece.Color]time.Duration),
 		LastMove:       p.LastMove,
 	}
-	for k, v := range p.ThreeFoldCount {
-		n.ThreeFoldCount[k] = v
-	}
 	for _, color := range piece.Colors {
 		for k, v := range p.bitBoard[color] {
 			n.bitBoard[color][k] = v
@@ -215,6 +226,21 @@ func (p *Position) occupied(c piece.Color) uint64 {
 	return mask
 }
 
+// Occupied returns the squares holding pieces of the specified color. Use
+// piece.BothColors to get every occupied square.
+func (p *Position) Occupied(c piece.Color) BitBoard {
+	return BitBoard(p.occupied(c))
+}
+
+// Pieces returns the squares holding pieces of the specified color and type.
+// Use piece.BothColors to include the pieces of both sides.
+func (p *Position) Pieces(c piece.Color, t piece.Type) BitBoard {
+	if c == piece.BothColors {
+		return BitBoard(p.bitBoard[piece.White][t] | p.bitBoard[piece.Black][t])
+	}
+	return BitBoard(p.bitBoard[c][t])
+}
+
 func (p *Position) decompose(m move.Move) (from, to square.Square, movingPiece, capturedPiece piece.Piece) {
 	return m.From(), m.To(), p.OnSquare(m.From()), p.OnSquare(m.To())
 }
@@ -225,22 +251,36 @@ func (p *Position) decompose(m move.Move) (from, to square.Square, movingPiece,
 // or invalid you will get undetermined behavior.
 func (p *Position) MakeMove(m move.Move) *Position {
 	q := Copy(p)
-	from, to, movingPiece, capturedPiece := q.decompose(m)
-	q.adjustMoveCounter(movingPiece, capturedPiece)
-	q.adjustCastlingRights(movingPiece, from, to)
-	q.adjustEnPassant(movingPiece, from, to)
-	q.adjustBoard(m, from, to, movingPiece, capturedPiece)
-	q.Clocks[q.ActiveColor] -= m.Duration
-	q.MovesLeft[q.ActiveColor]--
-	q.ActiveColor = (q.ActiveColor + 1) % 2
-	if q.ActiveColor == piece.White {
-		q.MoveNumber++
-	}
-	q.LastMove = m
+	q.play(m)
 	q.adjustThreeFoldCounter()
 	return q
 }
 
+// Play makes the move like MakeMove, but does not keep track of repetitions:
+// the ThreeFoldCount of the new position is nil. It saves copying the counts
+// of the whole game for every move, which is what searches that keep their
+// own history of the positions they pass through need.
+func (p *Position) Play(m move.Move) *Position {
+	q := copyBoard(p)
+	q.play(m)
+	return q
+}
+
+func (p *Position) play(m move.Move) {
+	from, to, movingPiece, capturedPiece := p.decompose(m)
+	p.adjustMoveCounter(movingPiece, capturedPiece)
+	p.adjustCastlingRights(movingPiece, from, to)
+	p.adjustEnPassant(movingPiece, from, to)
+	p.adjustBoard(m, from, to, movingPiece, capturedPiece)
+	p.Clocks[p.ActiveColor] -= m.Duration
+	p.MovesLeft[p.ActiveColor]--
+	p.ActiveColor = (p.ActiveColor + 1) % 2
+	if p.ActiveColor == piece.White {
+		p.MoveNumber++
+	}
+	p.LastMove = m
+}
+
 func (p *Position) adjustThreeFoldCounter() {
 	hash := p.Polyglot()
 	if p.FiftyMoveCount == 0 {
@@ -351,60 +391,58 @@ func (p *Position) Find(pc piece.Piece) map[square.Square]struct{} {
 	return s
 }
 
-func (p *Position) InsufficientMaterial() bool {
-	/*
-		BUG!
-		TODO:
-		  	-(Any number of additional bishops of either color on the same color of square due to underpromotion do not affect the situation.)
-	*/
-	loneKing := []bool{
-		p.occupied(piece.White)&p.bitBoard[piece.White][piece.King] == p.occupied(piece.White),
-		p.occupied(piece.Black)&p.bitBoard[piece.Black][piece.King] == p.occupied(piece.Black)}
-
-	if !loneKing[piece.White] && !loneKing[piece.Black] {
+// lightSquares has the bits of the light squares set. H1 is a light square.
+const lightSquares uint64 = 0xAA55AA55AA55AA55
+
+// heavyMaterial returns whether either side has a pawn, rook or queen.
+func (p *Position) heavyMaterial() bool {
+	for c := piece.White; c <= piece.Black; c++ {
+		if p.bitBoard[c][piece.Pawn]|p.bitBoard[c][piece.Rook]|p.bitBoard[c][piece.Queen] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadPosition returns whether neither player can checkmate by any sequence of
+// legal moves. Only the material is considered, so positions that are dead
+// because of the pawn structure are not detected.
+func (p *Position) DeadPosition() bool {
+	if p.heavyMaterial() {
 		return false
 	}
+	knights := p.bitBoard[piece.White][piece.Knight] | p.bitBoard[piece.Black][piece.Knight]
+	bishops := p.bitBoard[piece.White][piece.Bishop] | p.bitBoard[piece.Black][piece.Bishop]
+	if popcount(knights|bishops) <= 1 {
+		return true
+	}
+	// Any number of bishops that all move on the same color:
+	return knights == 0 && (bishops&lightSquares == 0 || bishops&^lightSquares == 0)
+}
 
-	for color := piece.White; color <= piece.Black; color++ {
-		otherColor := []piece.Color{piece.Black, piece.White}[color]
-		if loneKing[color] {
-			// King vs King:
-			if loneKing[otherColor] {
-				return true
-			}
-			// King vs King & Knight
-			if popcount(p.bitBoard[otherColor][piece.Knight]) == 1 {
-				mask := p.bitBoard[otherColor][piece.King] | p.bitBoard[otherColor][piece.Knight]
-				occuppied := p.occupied(otherColor)
-				if occuppied&mask == occuppied {
-					return true
-				}
-			}
-			// King vs King & Bishop
-			if popcount(p.bitBoard[otherColor][piece.Bishop]) == 1 {
-				mask := p.bitBoard[otherColor][piece.King] | p.bitBoard[otherColor][piece.Bishop]
-				occuppied := p.occupied(otherColor)
-				if occuppied&mask == occuppied {
-					return true
-				}
-			}
-		}
-		// King vs King & oppoSite bishop
-		kingBishopMask := p.bitBoard[color][piece.King] | p.bitBoard[color][piece.Bishop]
-		if (p.occupied(color)&kingBishopMask == p.occupied(color)) && (popcount(p.bitBoard[color][piece.Bishop]) == 1) {
-			mask := p.bitBoard[otherColor][piece.King] | p.bitBoard[otherColor][piece.Bishop]
-			occuppied := p.occupied(otherColor)
-			if (occuppied&mask == occuppied) && (popcount(p.bitBoard[otherColor][piece.Bishop]) == 1) {
-				color1 := bitscan(p.bitBoard[color][piece.Bishop]) % 2
-				color2 := bitscan(p.bitBoard[otherColor][piece.Bishop]) % 2
-				if color1 == color2 {
-					return true
-				}
-			}
-		}
+// InsufficientMaterial returns whether neither player has the material left
+// to checkmate. It reports the same positions as DeadPosition.
+func (p *Position) InsufficientMaterial() bool {
+	return p.DeadPosition()
+}
 
+// CannotForceMate returns whether neither player has the material to force a
+// checkmate. This includes dead positions as well as positions like two
+// knights against a king, where a win needs the opponent's help.
+func (p *Position) CannotForceMate() bool {
+	if p.heavyMaterial() {
+		return false
 	}
-	return false
+	for c := piece.White; c <= piece.Black; c++ {
+		knights, bishops := p.bitBoard[c][piece.Knight], p.bitBoard[c][piece.Bishop]
+		switch {
+		case bishops == 0 && popcount(knights) <= 2:
+		case knights == 0 && (bishops&lightSquares == 0 || bishops&^lightSquares == 0):
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 // Check returns whether or not the specified color is in check.
@@ -414,109 +452,73 @@ func (p *Position) Check(color piece.Color) bool {
 	return p.Threatened(kingsq, opponent)
 }
 
-// Returns a string that is a SAN representation of the input move from the current position.
-// If the input move is not a legal move, empty string is returned.
+// SAN returns the move in standard algebraic notation, as the PGN standard
+// writes it. If the input move is not a legal move, empty string is returned.
 func (p Position) SAN(m move.Move) string {
+	m.Duration = 0 // how long the move took does not change what it is
 	legalMoves := p.LegalMoves()
 	if _, exists := legalMoves[m]; !exists {
 		return ""
 	}
 
-	movingPiece := p.OnSquare(m.From())
-
-	// Castling.
-	if movingPiece.Type == piece.King {
-		fromFile, toFile := int(m.From())%8, int(m.To())%8
-		if fromFile == int(square.E1)%8 {
-			if toFile == int(square.G1)%8 {
-				return "O-O"
-			}
-			if toFile == int(square.C1)%8 {
-				return "O-O-O"
+	var san string
+	mover := p.OnSquare(m.From())
+	capture := p.OnSquare(m.To()).Type != piece.None || (mover.Type == piece.Pawn && m.To() == p.EnPassant)
+	switch {
+	case mover.Type == piece.King && m.From() == m.To()+2:
+		san = "O-O"
+	case mover.Type == piece.King && m.To() == m.From()+2:
+		san = "O-O-O"
+	default:
+		if mover.Type == piece.Pawn {
+			if capture {
+				san = m.From().String()[:1]
 			}
+		} else {
+			san = strings.ToUpper(mover.Type.String()) + p.disambiguation(m, legalMoves)
 		}
-	}
-
-	mF, mR := m.Source.Algebraic()[0], m.Source.Algebraic()[1]
-	otherMoves, sameRank, sameFile := false, false, false
-	// Loop through all legal moves and check if there are some other moves with the same piece type to destination square from the same file or rank.
-	for lm, _ := range legalMoves {
-		// Check if the current legal move is from the same source square as input move.
-		if lm.Source == m.Source {
-			// It is the same source square, do not consider it as the other move we are searching for.
-			continue
-		}
-		// Current legal move is from some other source square as the one in input.
-
-		// Check if the move is with the same piece type.
-		if p.OnSquare(lm.Source).Type != movingPiece.Type {
-			// Piece type differs, this is not the move we are searching for.
-			continue
-		}
-		// Current legal move is from other source square with the same piece type as the input move.
-
-		// Check if the move is to the same destination as the input move.
-		if lm.Destination != m.Destination {
-			// Destination differs, do not continue.
-			continue
-		}
-		// Current legal move is from other source square to the same destination, with the same piece type as the input move.
-		otherMoves = true
-
-		// Mark if the other move is from the same file or rank as the input move.
-		lmF, lmR := lm.Source.Algebraic()[0], lm.Source.Algebraic()[1]
-		if mR == lmR {
-			sameRank = true
-		}
-		if mF == lmF {
-			sameFile = true
+		if capture {
+			san += "x"
 		}
-
-		// If there are other moves from the same file and rank, we don't need to look for another moves.
-		if sameFile && sameRank {
-			break
+		san += m.To().String()
+		if m.Promote != piece.None {
+			san += "=" + strings.ToUpper(m.Promote.String())
 		}
 	}
 
-	// Parts of SAN for output.
-	var pieceUpper, fromFile, fromRank, capture, promotion, check string
-
-	if movingPiece.Type != piece.Pawn {
-		pieceUpper = strings.ToUpper(movingPiece.Type.String())
-	}
-
-	if otherMoves {
-		if !sameFile {
-			fromFile = string(mF)
-		} else if !sameRank {
-			fromRank = string(mR)
-		} else {
-			fromFile = string(mF)
-			fromRank = string(mR)
+	next := p.MakeMove(m)
+	if next.Check(next.ActiveColor) {
+		if len(next.LegalMoves()) == 0 {
+			return san + "#"
 		}
+		return san + "+"
 	}
+	return san
+}
 
-	capturedPiece := p.OnSquare(m.To())
-	if p.EnPassant == m.To() {
-		capturedPiece = piece.New((p.ActiveColor+1)%2, piece.Pawn)
-	}
-	if capturedPiece.Type != piece.None {
-		capture = "x"
-		if movingPiece.Type == piece.Pawn && fromFile == "" {
-			fromFile = string(m.Source.Algebraic()[0])
+// disambiguation returns what SAN adds after the piece letter of the move to
+// tell it apart from the legal moves of other pieces of the same type to the
+// same square: the file it comes from if that is enough, else the rank, else
+// both.
+func (p Position) disambiguation(m move.Move, legalMoves map[move.Move]struct{}) string {
+	from := m.From().String()
+	others, sameFile, sameRank := false, false, false
+	for lm := range legalMoves {
+		if lm.To() != m.To() || lm.From() == m.From() || p.OnSquare(lm.From()).Type != p.OnSquare(m.From()).Type {
+			continue
 		}
+		other := lm.From().String()
+		others = true
+		sameFile = sameFile || other[0] == from[0]
+		sameRank = sameRank || other[1] == from[1]
 	}
-	if m.Promote != piece.None {
-		promotion = "=" + strings.ToUpper(m.Promote.String())
-	}
-	nextPosition := p.MakeMove(m)
-	if nextPosition.Check(nextPosition.ActiveColor) {
-		if len(nextPosition.LegalMoves()) == 0 {
-			check = "#"
-		} else {
-			check = "+"
-		}
+	switch {
+	case !others:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
 	}
-
-	return pieceUpper + fromFile + fromRank + capture + m.To().String() + promotion + check
+	return from
 }
@@ -111,6 +111,43 @@ func TestKandBvKandOpB(t *testing.T) {
 	if b.InsufficientMaterial() != false {
 		t.Fail()
 	}
+	if b.CannotForceMate() != true {
+		t.Error("mate can not be forced with opposite colored bishops")
+	}
+}
+
+func TestDeadPosition(t *testing.T) {
+	tests := []struct {
+		pieces       map[square.Square]piece.Piece
+		dead, unable bool
+	}{
+		{map[square.Square]piece.Piece{}, true, true},
+		{map[square.Square]piece.Piece{square.C3: piece.New(piece.White, piece.Knight)}, true, true},
+		// Bishops of both players on dark squares:
+		{map[square.Square]piece.Piece{
+			square.A1: piece.New(piece.White, piece.Bishop),
+			square.C1: piece.New(piece.White, piece.Bishop),
+			square.H2: piece.New(piece.Black, piece.Bishop)}, true, true},
+		{map[square.Square]piece.Piece{
+			square.C3: piece.New(piece.White, piece.Knight),
+			square.D3: piece.New(piece.White, piece.Knight)}, false, true},
+		{map[square.Square]piece.Piece{
+			square.C3: piece.New(piece.White, piece.Knight),
+			square.C1: piece.New(piece.White, piece.Bishop)}, false, false},
+		{map[square.Square]piece.Piece{square.C2: piece.New(piece.White, piece.Pawn)}, false, false},
+	}
+	for _, test := range tests {
+		b := New()
+		b.Clear()
+		b.QuickPut(piece.New(piece.White, piece.King), square.E4)
+		b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+		for s, pc := range test.pieces {
+			b.QuickPut(pc, s)
+		}
+		if b.DeadPosition() != test.dead || b.CannotForceMate() != test.unable {
+			t.Error(test.pieces, "got", b.DeadPosition(), b.CannotForceMate(), "wanted", test.dead, test.unable)
+		}
+	}
 }
 
 /*
@@ -190,6 +227,23 @@ func TestLongCastle(t *testing.T) {
 	}
 }
 
+func TestPlay(t *testing.T) {
+	p := New().MakeMove(move.Parse("g1f3"))
+	for _, m := range []string{"b8c6", "f3g1", "c6b8", "g1f3"} {
+		played, made := p.Play(move.Parse(m)), p.MakeMove(move.Parse(m))
+		if !played.Equals(made) || played.MoveNumber != made.MoveNumber || played.FiftyMoveCount != made.FiftyMoveCount {
+			t.Errorf("%s: got\n%v\nwant\n%v", m, played, made)
+		}
+		if played.ThreeFoldCount != nil {
+			t.Errorf("%s: got counts %v", m, played.ThreeFoldCount)
+		}
+		p = made
+	}
+	if p.ThreeFoldCount[p.Polyglot()] != 2 {
+		t.Error("got", p.ThreeFoldCount)
+	}
+}
+
 func TestBitBoardPrint(t *testing.T) {
 	b := New()
 	expected := `00000000
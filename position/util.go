@@ -1,41 +1,24 @@
 package position
 
+import (
+	"math/bits"
+)
+
 func popcount(b uint64) uint {
-	var count uint
-	for i := uint(0); i < 64; i++ {
-		if (b & (1 << i)) != 0 {
-			count++
-		}
-	}
-	return count
+	return uint(bits.OnesCount64(b))
 }
 
 func bitscan(b uint64) uint {
-	for i := uint(0); i < 64; i++ {
-		if (b & (1 << i)) != 0 {
-			return i
-		}
-	}
-	return 64
+	return uint(bits.TrailingZeros64(b))
 }
 
 func bsf(b uint64) uint {
-	for i := uint(0); i < 64; i++ {
-		if (b & (1 << i)) != 0 {
-			return i
-		}
-	}
-	return 64
+	return uint(bits.TrailingZeros64(b))
 }
 
 func bsr(b uint64) uint {
-	for i := uint(63); i > 0; i-- {
-		if (b & (1 << i)) != 0 {
-			return i
-		}
-	}
-	if b&1 != 0 {
-		return 0
+	if b == 0 {
+		return 64
 	}
-	return 64
+	return uint(63 - bits.LeadingZeros64(b))
 }
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Move ordering scores. The transposition table move is tried first, then
+// captures and promotions, then killers, then the rest by history.
+const (
+	hashMoveScore = 1 << 30
+	captureScore  = 1 << 28
+	killerScore   = 1 << 26
+)
+
+// captured returns the type of piece the move captures, or piece.None.
+func captured(p *position.Position, m move.Move) piece.Type {
+	victim := p.OnSquare(m.To()).Type
+	if victim == piece.None && m.To() == p.EnPassant && p.OnSquare(m.From()).Type == piece.Pawn {
+		return piece.Pawn
+	}
+	return victim
+}
+
+// tactical returns whether the move captures or promotes.
+func tactical(p *position.Position, m move.Move) bool {
+	return m.Promote != piece.None || captured(p, m) != piece.None
+}
+
+type scoredMove struct {
+	move.Move
+	score int
+}
+
+// order returns the pseudo legal moves of the position, best looking first.
+// With tacticalOnly set only captures and promotions are returned.
+func (e *Engine) order(p *position.Position, hashMove move.Move, ply int, tacticalOnly bool) []move.Move {
+	moves := p.Moves()
+	scored := make([]scoredMove, 0, len(moves))
+	for m := range moves {
+		s := scoredMove{Move: m}
+		victim := captured(p, m)
+		switch {
+		case m == hashMove:
+			s.score = hashMoveScore
+		case victim != piece.None || m.Promote != piece.None:
+			// Most valuable victim, least valuable attacker.
+			s.score = captureScore + int(victim)*16 - int(p.OnSquare(m.From()).Type) + int(m.Promote)*64
+		case tacticalOnly:
+			continue
+		case m == e.killers[ply][0]:
+			s.score = killerScore + 1
+		case m == e.killers[ply][1]:
+			s.score = killerScore
+		default:
+			s.score = e.history[p.ActiveColor][m.From()][m.To()]
+		}
+		scored = append(scored, s)
+	}
+	// Ties are broken by the squares so that searches are repeatable, as
+	// maps are iterated in random order.
+	sort.Slice(scored, func(i, j int) bool {
+		a, b := scored[i], scored[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.Promote < b.Promote
+	})
+	ordered := make([]move.Move, len(scored))
+	for i := range scored {
+		ordered[i] = scored[i].Move
+	}
+	return ordered
+}
+
+// remember updates the killers and history with a quiet move that caused a
+// beta cutoff.
+func (e *Engine) remember(p *position.Position, m move.Move, depth, ply int) {
+	if e.killers[ply][0] != m {
+		e.killers[ply][1] = e.killers[ply][0]
+		e.killers[ply][0] = m
+	}
+	h := &e.history[p.ActiveColor][m.From()][m.To()]
+	*h += depth * depth
+	if *h >= killerScore {
+		for c := range e.history {
+			for from := range e.history[c] {
+				for to := range e.history[c][from] {
+					e.history[c][from][to] /= 2
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,449 @@
+// Package search is a chess engine written in Go. It searches positions with
+// iterative deepening alpha-beta and a quiescence search, using a
+// transposition table keyed on the Polyglot hash, null move pruning and
+// killer and history move ordering, and scores them with the eval package.
+//
+// Progress is reported with the keys of engines.SearchInfo, so results from
+// this engine and from UCI engines can be handled by the same code:
+//
+//	e := search.New(64)
+//	info, err := e.Search(ctx, g.Position(), search.LimitsFor(g), nil)
+//	m := move.Parse(info.BestMove)
+package search
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/eval"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+var (
+	// ErrNoMoves is returned when searching a position that is checkmate or
+	// stalemate.
+	ErrNoMoves = errors.New("search: the position has no legal moves")
+	// ErrUnknownOption is returned when setting an option the engine does
+	// not have.
+	ErrUnknownOption = errors.New("search: unknown option")
+)
+
+const (
+	// maxPly is the deepest the search goes, quiescence included.
+	maxPly = 128
+	// mate is the score of being checkmated at the root. Being mated n plies
+	// from the root scores n-mate.
+	mate      = 32000
+	mateBound = mate - maxPly
+	infinity  = mate + 1
+	// maxHash is the largest transposition table, in megabytes, that can be
+	// set as an option.
+	maxHash = 65536
+	// nullReduction is how much shallower the null move is searched.
+	nullReduction = 2
+	// checkEvery is how many nodes are searched between looking at the
+	// clock and the context.
+	checkEvery = 2048
+)
+
+// Engine searches for the best move. It keeps its transposition table,
+// killers and history between searches. An engine is not safe for use by
+// several goroutines at once; use one engine per goroutine.
+type Engine struct {
+	// Weights are the evaluation terms. Nil means eval.Default.
+	Weights *eval.Weights
+
+	table   *table
+	hash    int
+	killers [maxPly][2]move.Move
+	history [2][64][64]int
+	pv      [maxPly][maxPly]move.Move
+	pvLen   [maxPly]int
+	// seen counts the positions of the game up to the root, and path holds
+	// the positions the search went through to get to each ply.
+	seen map[position.Hash]int
+	path [maxPly]position.Hash
+
+	ctx    context.Context
+	limits Limits
+	start  time.Time
+	// No new iteration is started after halfway, and the search stops at
+	// the deadline. They are zero when there is no time limit.
+	halfway  time.Time
+	deadline time.Time
+	nodes    uint64
+	seldepth int
+	stopped  bool
+}
+
+// New returns an engine with a transposition table of the given size in
+// megabytes.
+func New(hashMegabytes int) *Engine {
+	e := &Engine{}
+	e.SetHashSize(hashMegabytes)
+	return e
+}
+
+// SetHashSize replaces the transposition table with an empty one of the
+// given size in megabytes.
+func (e *Engine) SetHashSize(megabytes int) {
+	if megabytes < 1 {
+		megabytes = 1
+	}
+	e.table, e.hash = newTable(megabytes), megabytes
+}
+
+// Clear forgets everything learned in earlier searches. Call it before
+// searching positions from a new game.
+func (e *Engine) Clear() {
+	e.table.clear()
+	e.killers = [maxPly][2]move.Move{}
+	e.history = [2][64][64]int{}
+}
+
+// Options returns the options of the engine, for serving it over UCI.
+func (e *Engine) Options() []engines.UCIOption {
+	return []engines.UCIOption{
+		{Name: "Hash", Type: "spin", Default: strconv.Itoa(e.hash), Min: 1, Max: maxHash},
+		{Name: "Clear Hash", Type: "button"},
+	}
+}
+
+// SetOption changes one of the options returned by Options. Names are not
+// case sensitive, as in UCI.
+func (e *Engine) SetOption(name, value string) error {
+	switch {
+	case strings.EqualFold(name, "Hash"):
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHash {
+			return errors.New("search: bad hash size " + strconv.Quote(value))
+		}
+		e.SetHashSize(n)
+	case strings.EqualFold(name, "Clear Hash"):
+		e.Clear()
+	default:
+		return ErrUnknownOption
+	}
+	return nil
+}
+
+// Search looks for the best move in the position until a limit is reached or
+// the context is done. The best move found so far is returned either way.
+// After each iteration info, when it is not nil, is called with a line of
+// analysis holding depth, seldepth, score, time, nodes, nps, hashfull and pv.
+// The same lines make up the Analysis of the result.
+func (e *Engine) Search(ctx context.Context, p *position.Position, l Limits, info func(map[string]string)) (*engines.SearchInfo, error) {
+	legal := e.order(p, move.Null, 0, false)
+	for i := 0; i < len(legal); i++ {
+		if p.Play(legal[i]).Check(p.ActiveColor) {
+			legal = append(legal[:i], legal[i+1:]...)
+			i--
+		}
+	}
+	if len(legal) == 0 {
+		return nil, ErrNoMoves
+	}
+	e.ctx, e.start = ctx, time.Now()
+	e.setLimits(l, e.start)
+	e.seen = p.ThreeFoldCount
+	e.nodes, e.stopped = 0, false
+	e.killers = [maxPly][2]move.Move{}
+
+	si := &engines.SearchInfo{}
+	pv := []move.Move{legal[0]}
+	for depth := 1; depth < maxPly; depth++ {
+		e.seldepth = 0
+		score := e.negamax(p, depth, -infinity, infinity, 0, false)
+		if e.stopped {
+			break
+		}
+		pv = append([]move.Move(nil), e.pv[0][:e.pvLen[0]]...)
+		line := e.analysis(depth, score, pv)
+		si.Analysis = append(si.Analysis, line)
+		if info != nil {
+			info(line)
+		}
+		if e.limits.Depth > 0 && depth >= e.limits.Depth {
+			break
+		}
+		if !e.halfway.IsZero() && (time.Now().After(e.halfway) || len(legal) == 1) {
+			break
+		}
+		if e.limits.Depth == 0 && e.limits.Nodes == 0 && e.halfway.IsZero() && depth == maxPly-1 {
+			// An infinite search has nothing left to look at, but must not
+			// answer before it is told to stop, or to play when pondering.
+			select {
+			case <-ctx.Done():
+			case hit := <-e.limits.PonderHit:
+				e.setLimits(hit, time.Now())
+			}
+		}
+	}
+	si.BestMove = pv[0].String()
+	if len(pv) > 1 {
+		si.Ponder = pv[1].String()
+	}
+	return si, nil
+}
+
+// setLimits bounds the search by l, with its time counted from now.
+func (e *Engine) setLimits(l Limits, now time.Time) {
+	e.limits = l
+	soft, hard := budget(l)
+	e.halfway, e.deadline = time.Time{}, time.Time{}
+	if soft > 0 {
+		e.halfway = now.Add(soft / 2)
+	}
+	if hard > 0 {
+		e.deadline = now.Add(hard)
+	}
+}
+
+// analysis returns a line of analysis in the format of engines.SearchInfo.
+func (e *Engine) analysis(depth, score int, pv []move.Move) map[string]string {
+	elapsed := time.Since(e.start)
+	moves := make([]string, len(pv))
+	for i, m := range pv {
+		moves[i] = m.String()
+	}
+	nps := uint64(0)
+	if elapsed > 0 {
+		nps = e.nodes * uint64(time.Second) / uint64(elapsed)
+	}
+	return map[string]string{
+		"depth":    strconv.Itoa(depth),
+		"seldepth": strconv.Itoa(e.seldepth),
+		"score":    uciScore(score),
+		"time":     strconv.FormatInt(int64(elapsed/time.Millisecond), 10),
+		"nodes":    strconv.FormatUint(e.nodes, 10),
+		"nps":      strconv.FormatUint(nps, 10),
+		"hashfull": strconv.Itoa(e.table.full()),
+		"pv":       strings.Join(moves, " "),
+	}
+}
+
+// uciScore writes a score as "cp <centipawns>" or "mate <moves>", negative
+// moves meaning the side to move gets mated.
+func uciScore(score int) string {
+	switch {
+	case score > mateBound:
+		return "mate " + strconv.Itoa((mate-score+1)/2)
+	case score < -mateBound:
+		return "mate " + strconv.Itoa(-(mate+score)/2)
+	}
+	return "cp " + strconv.Itoa(score)
+}
+
+// stop returns whether the search has to stop.
+func (e *Engine) stop() bool {
+	if e.stopped {
+		return true
+	}
+	if e.limits.Nodes > 0 && e.nodes >= e.limits.Nodes {
+		e.stopped = true
+	}
+	if e.nodes%checkEvery == 0 {
+		select {
+		case <-e.ctx.Done():
+			e.stopped = true
+		case hit := <-e.limits.PonderHit:
+			e.setLimits(hit, time.Now())
+		default:
+		}
+		if !e.deadline.IsZero() && time.Now().After(e.deadline) {
+			e.stopped = true
+		}
+	}
+	return e.stopped
+}
+
+// evaluate returns the static score from the point of view of the side to
+// move.
+func (e *Engine) evaluate(p *position.Position) int {
+	var score int
+	if e.Weights != nil {
+		score = e.Weights.Evaluate(p)
+	} else {
+		score = eval.Evaluate(p)
+	}
+	if p.ActiveColor == piece.Black {
+		return -score
+	}
+	return score
+}
+
+// drawn returns whether the position at the ply is a draw by the fifty move
+// rule, repetition or lack of material. A position that was seen once before,
+// earlier in the game or in the search, counts as a repetition.
+func (e *Engine) drawn(p *position.Position, hash position.Hash, ply int) bool {
+	if p.FiftyMoveCount >= 100 || p.InsufficientMaterial() {
+		return true
+	}
+	// Only the positions since the last capture or pawn move can come back:
+	reversible := int(p.FiftyMoveCount)
+	for i := ply - 1; i >= 0 && i >= ply-reversible; i-- {
+		if e.path[i] == hash {
+			return true
+		}
+	}
+	return reversible >= ply && e.seen[hash] > 0
+}
+
+// negamax is an alpha-beta principal variation search. It returns the score
+// of the position from the point of view of the side to move.
+func (e *Engine) negamax(p *position.Position, depth, alpha, beta, ply int, nullAllowed bool) int {
+	e.pvLen[ply] = ply
+	if e.stop() {
+		return 0
+	}
+	hash := p.Polyglot()
+	if ply > 0 && e.drawn(p, hash, ply) {
+		return 0
+	}
+	e.path[ply] = hash
+	if ply >= maxPly-1 {
+		return e.evaluate(p)
+	}
+	inCheck := p.Check(p.ActiveColor)
+	if inCheck {
+		depth++
+	}
+	if depth <= 0 {
+		return e.quiesce(p, alpha, beta, ply)
+	}
+	e.nodes++
+
+	hashMove := move.Null
+	if entry, ok := e.table.probe(hash); ok {
+		hashMove = entry.move
+		if ply > 0 && int(entry.depth) >= depth {
+			score := fromTable(entry.score, ply)
+			switch {
+			case entry.kind == exact,
+				entry.kind == lowerBound && score >= beta,
+				entry.kind == upperBound && score <= alpha:
+				return score
+			}
+		}
+	}
+
+	if nullAllowed && !inCheck && depth > nullReduction && beta < mateBound && e.hasPieces(p) {
+		q := position.Copy(p)
+		q.ActiveColor = 1 - p.ActiveColor
+		q.EnPassant = square.NoSquare
+		score := -e.negamax(q, depth-1-nullReduction, -beta, -beta+1, ply+1, false)
+		if e.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+	}
+
+	best, bestMove, legal := -infinity, move.Null, 0
+	startAlpha := alpha
+	for _, m := range e.order(p, hashMove, ply, false) {
+		q := p.Play(m)
+		if q.Check(p.ActiveColor) {
+			continue
+		}
+		legal++
+		var score int
+		if legal == 1 {
+			score = -e.negamax(q, depth-1, -beta, -alpha, ply+1, true)
+		} else {
+			score = -e.negamax(q, depth-1, -alpha-1, -alpha, ply+1, true)
+			if score > alpha && score < beta {
+				score = -e.negamax(q, depth-1, -beta, -alpha, ply+1, true)
+			}
+		}
+		if e.stopped {
+			return 0
+		}
+		if score > best {
+			best, bestMove = score, m
+		}
+		if score > alpha {
+			alpha = score
+			e.pv[ply][ply] = m
+			copy(e.pv[ply][ply+1:], e.pv[ply+1][ply+1:e.pvLen[ply+1]])
+			e.pvLen[ply] = e.pvLen[ply+1]
+		}
+		if score >= beta {
+			if !tactical(p, m) {
+				e.remember(p, m, depth, ply)
+			}
+			break
+		}
+	}
+	if legal == 0 {
+		if inCheck {
+			return ply - mate
+		}
+		return 0
+	}
+
+	kind := exact
+	switch {
+	case best >= beta:
+		kind = lowerBound
+	case best <= startAlpha:
+		kind = upperBound
+	}
+	e.table.store(entry{key: hash, move: bestMove, score: toTable(best, ply), depth: int16(depth), kind: uint8(kind)})
+	return best
+}
+
+// quiesce searches captures and promotions until the position is quiet, so
+// that positions are not evaluated in the middle of an exchange.
+func (e *Engine) quiesce(p *position.Position, alpha, beta, ply int) int {
+	e.pvLen[ply] = ply
+	if e.stop() {
+		return 0
+	}
+	e.nodes++
+	if ply > e.seldepth {
+		e.seldepth = ply
+	}
+	standPat := e.evaluate(p)
+	if ply >= maxPly-1 || standPat >= beta {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+	for _, m := range e.order(p, move.Null, ply, true) {
+		if m.Promote == piece.None && p.SEE(m) < 0 {
+			continue
+		}
+		q := p.Play(m)
+		if q.Check(p.ActiveColor) {
+			continue
+		}
+		score := -e.quiesce(q, -beta, -alpha, ply+1)
+		if e.stopped {
+			return 0
+		}
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// hasPieces returns whether the side to move has more than pawns and a king.
+// Null moves are not tried without pieces, where zugzwang is common.
+func (e *Engine) hasPieces(p *position.Position) bool {
+	c := p.ActiveColor
+	return p.Pieces(c, piece.Knight)|p.Pieces(c, piece.Bishop)|p.Pieces(c, piece.Rook)|p.Pieces(c, piece.Queen) != 0
+}
@@ -0,0 +1,173 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+func decode(t *testing.T, f string) *position.Position {
+	p, err := fen.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBestMove(t *testing.T) {
+	tests := []struct {
+		name, fen string
+		depth     int
+		want      string
+		score     string
+	}{
+		{"back rank mate", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 2, "a1a8", "mate 1"},
+		{"hanging queen", "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1", 3, "d1d5", ""},
+		{"mate in two", "k7/8/2K5/8/8/8/8/7R w - - 0 1", 4, "", "mate 2"},
+		{"avoid losing the queen", "3rk3/8/8/8/8/8/8/3QK3 w - - 0 1", 3, "", ""},
+	}
+	for _, test := range tests {
+		p := decode(t, test.fen)
+		info, err := New(1).Search(context.Background(), p, Limits{Depth: test.depth}, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		last := info.Analysis[len(info.Analysis)-1]
+		if test.want != "" && info.BestMove != test.want {
+			t.Errorf("%s: got %s, want %s (%v)", test.name, info.BestMove, test.want, last)
+		}
+		if test.score != "" && last["score"] != test.score {
+			t.Errorf("%s: scored %s, want %s", test.name, last["score"], test.score)
+		}
+		if last["depth"] == "" || last["nodes"] == "" || last["pv"] == "" {
+			t.Errorf("%s: incomplete analysis %v", test.name, last)
+		}
+		if test.name == "avoid losing the queen" {
+			if score, _ := info.Score(); score < 0 {
+				t.Errorf("%s: %s scores %d", test.name, info.BestMove, score)
+			}
+		}
+	}
+}
+
+func TestNoMoves(t *testing.T) {
+	for _, f := range []string{"R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1", "7k/5Q2/8/8/8/8/8/6K1 b - - 0 1"} {
+		if _, err := New(1).Search(context.Background(), decode(t, f), Limits{Depth: 1}, nil); err != ErrNoMoves {
+			t.Errorf("%s: got %v, want ErrNoMoves", f, err)
+		}
+	}
+}
+
+func TestSearchStops(t *testing.T) {
+	p := position.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	info, err := New(1).Search(ctx, p, Limits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v to stop", elapsed)
+	}
+	if _, ok := p.LegalMoves()[move.Parse(info.BestMove)]; !ok {
+		t.Errorf("best move %q is not legal", info.BestMove)
+	}
+
+	start = time.Now()
+	if _, err := New(1).Search(context.Background(), p, Limits{MoveTime: 100 * time.Millisecond}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v to stop after 100ms", elapsed)
+	}
+
+	var lines int
+	info, err = New(1).Search(context.Background(), p, Limits{Nodes: 500}, func(map[string]string) { lines++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != len(info.Analysis) {
+		t.Errorf("info was called %d times for %d lines of analysis", lines, len(info.Analysis))
+	}
+
+	// A ponder search goes on until it gets limits of its own.
+	hit := make(chan Limits, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		hit <- Limits{MoveTime: 50 * time.Millisecond}
+	}()
+	start = time.Now()
+	if _, err := New(1).Search(context.Background(), p, Limits{PonderHit: hit}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("took %v to stop with a 50ms ponderhit after 50ms", elapsed)
+	}
+}
+
+func TestRepetitionIsDrawn(t *testing.T) {
+	g := game.New()
+	for _, san := range []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1"} {
+		m, err := g.Position().ParseMove(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.MakeMove(m)
+	}
+	e := New(1)
+	e.seen = g.Position().ThreeFoldCount
+	e.path[0] = g.Position().Polyglot()
+	// Black can repeat the starting position a third time.
+	q := g.Position().Play(move.Parse("f6g8"))
+	if !e.drawn(q, q.Polyglot(), 1) {
+		t.Error("repeating the position is not a draw")
+	}
+
+	// Positions the search went through count too, up to the last pawn move.
+	p := position.New()
+	e.seen = p.ThreeFoldCount
+	for ply, m := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+		e.path[ply] = p.Polyglot()
+		p = p.Play(move.Parse(m))
+	}
+	if !e.drawn(p, p.Polyglot(), 4) {
+		t.Error("repeating a position of the search is not a draw")
+	}
+	p.FiftyMoveCount = 3
+	if e.drawn(p, p.Polyglot(), 4) {
+		t.Error("repeating a position from before a pawn move is a draw")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		limits     Limits
+		soft, hard time.Duration
+	}{
+		{Limits{}, 0, 0},
+		{Limits{MoveTime: time.Second}, time.Second, time.Second},
+		{Limits{Clock: 30*time.Second + overhead}, time.Second, 4 * time.Second},
+		{Limits{Clock: 40*time.Second + overhead, MovesToGo: 40, Control: game.TimeControl{Increment: 4 * time.Second}}, 4 * time.Second, 40 * time.Second / 3},
+		{Limits{Clock: 10*time.Second + overhead, MovesToGo: 1}, 10 * time.Second, 10 * time.Second},
+	}
+	for _, test := range tests {
+		if soft, hard := budget(test.limits); soft != test.soft || hard != test.hard {
+			t.Errorf("%+v: got %v and %v, want %v and %v", test.limits, soft, hard, test.soft, test.hard)
+		}
+	}
+	g := game.NewTimedGame(map[piece.Color]game.TimeControl{
+		piece.White: game.NewTimeControl(time.Minute, 40, 0, true),
+		piece.Black: game.NewTimeControl(time.Minute, 40, 0, true),
+	})
+	if l := LimitsFor(g); l.Clock != time.Minute || l.MovesToGo != 40 || l.Control.Moves != 40 {
+		t.Errorf("LimitsFor gave %+v", l)
+	}
+}
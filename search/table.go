@@ -0,0 +1,96 @@
+package search
+
+import (
+	"unsafe"
+
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Kinds of scores stored in the transposition table.
+const (
+	exact = iota + 1
+	lowerBound
+	upperBound
+)
+
+// entry is what the transposition table remembers about a position.
+type entry struct {
+	key   position.Hash
+	move  move.Move
+	score int32
+	depth int16
+	kind  uint8
+}
+
+// table is a transposition table keyed on the Polyglot hash of positions.
+type table struct {
+	entries []entry
+}
+
+func newTable(megabytes int) *table {
+	if megabytes < 1 {
+		megabytes = 1
+	}
+	return &table{entries: make([]entry, megabytes<<20/int(unsafe.Sizeof(entry{})))}
+}
+
+func (t *table) probe(key position.Hash) (entry, bool) {
+	e := t.entries[uint64(key)%uint64(len(t.entries))]
+	return e, e.kind != 0 && e.key == key
+}
+
+// store keeps the entry unless the slot holds a deeper search of the same
+// position.
+func (t *table) store(e entry) {
+	slot := &t.entries[uint64(e.key)%uint64(len(t.entries))]
+	if slot.key == e.key && slot.depth > e.depth && e.kind != exact {
+		return
+	}
+	*slot = e
+}
+
+func (t *table) clear() {
+	for i := range t.entries {
+		t.entries[i] = entry{}
+	}
+}
+
+// full returns how full the table is in permill, by sampling its first
+// thousand entries.
+func (t *table) full() int {
+	n := 1000
+	if len(t.entries) < n {
+		n = len(t.entries)
+	}
+	used := 0
+	for _, e := range t.entries[:n] {
+		if e.kind != 0 {
+			used++
+		}
+	}
+	return used * 1000 / n
+}
+
+// toTable makes mate scores relative to the node they are stored at, so they
+// stay right when the position is reached at another ply.
+func toTable(score, ply int) int32 {
+	switch {
+	case score > mateBound:
+		score += ply
+	case score < -mateBound:
+		score -= ply
+	}
+	return int32(score)
+}
+
+func fromTable(score int32, ply int) int {
+	s := int(score)
+	switch {
+	case s > mateBound:
+		s -= ply
+	case s < -mateBound:
+		s += ply
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Limits bound a search. The search stops at whichever limit is reached
+// first. With no limits at all it goes on until its context is done.
+type Limits struct {
+	// Depth is the deepest iteration to search, in plies.
+	Depth int
+	// Nodes is how many positions to visit at most.
+	Nodes uint64
+	// MoveTime is exactly how long to think.
+	MoveTime time.Duration
+
+	// Clock is the time left for the side to move. When it is set, and
+	// MoveTime is not, the engine decides how long to think from it and the
+	// time control.
+	Clock time.Duration
+	// Control is the time control being played, usually from game.Stage.
+	Control game.TimeControl
+	// MovesToGo is how many moves are left until the next time control. When
+	// it is zero the engine assumes it has to play many more moves on the
+	// clock it has.
+	MovesToGo int
+
+	// PonderHit, when set, is how a search started while pondering learns
+	// that the opponent played the expected move. The limits received on it
+	// replace these ones, with their time counted from when they arrive.
+	PonderHit <-chan Limits
+}
+
+// LimitsFor returns limits that have the side to move in the game think as
+// long as its clock and time control allow.
+func LimitsFor(g *game.Game) Limits {
+	c := g.ActiveColor()
+	return Limits{
+		Clock:     g.Clock(c),
+		Control:   g.Stage(c),
+		MovesToGo: g.MovesLeft(c),
+	}
+}
+
+const (
+	// movesToGoGuess is how many more moves sudden death games are expected
+	// to last.
+	movesToGoGuess = 30
+	// overhead is kept back for the time it takes to send the move.
+	overhead = 20 * time.Millisecond
+)
+
+// budget returns how long to think. No new iteration is started after soft,
+// and the search is stopped at hard. Zero means no limit.
+func budget(l Limits) (soft, hard time.Duration) {
+	if l.MoveTime > 0 {
+		return l.MoveTime, l.MoveTime
+	}
+	if l.Clock <= 0 {
+		return 0, 0
+	}
+	movesToGo := l.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = movesToGoGuess
+	}
+	left := l.Clock - overhead
+	if left < time.Millisecond {
+		left = time.Millisecond
+	}
+	soft = left/time.Duration(movesToGo) + l.Control.Increment*3/4
+	hard = soft * 4
+	if max := left / 3; hard > max {
+		hard = max
+	}
+	if movesToGo == 1 {
+		hard = left
+	}
+	if soft > hard {
+		soft = hard
+	}
+	return soft, hard
+}
@@ -0,0 +1,344 @@
+// Package suite runs EPD test suites, like WAC, STS or Arasan, against
+// engines. It checks the moves the engines settle on against the bm and am
+// operations, scores STS style point tables and reports how many positions
+// were solved and how quickly.
+package suite
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/epd"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+var (
+	// ErrNoLimit is returned when neither a time nor a depth limit is set.
+	ErrNoLimit = errors.New("suite: a time or depth limit is needed")
+	// ErrNoBestMove is returned for positions the engine gave no move for,
+	// usually because it crashed.
+	ErrNoBestMove = errors.New("suite: the engine gave no best move")
+	// ErrNothingToCheck is returned for positions with neither bm, am nor a
+	// point table.
+	ErrNothingToCheck = errors.New("suite: the position has no bm, am or c0 points")
+)
+
+// Limit is how long the engine thinks about each position. The search stops
+// at whichever limit is reached first.
+type Limit struct {
+	Time time.Duration
+	// Depth stops the search once the engine reports reaching it.
+	Depth int
+}
+
+// Suite is a set of test positions to run an engine on.
+type Suite struct {
+	Positions []*epd.EPD
+	// Start starts a new instance of the engine. Positions that are run at
+	// the same time each get their own instance.
+	Start func() (engines.Engine, error)
+	Limit Limit
+	// Concurrency is how many positions are run at the same time. Zero is
+	// treated as one.
+	Concurrency int
+	// StopTimeout is how long the engine has to give its move once it is told
+	// to stop. After that the position fails with engines.ErrTimedOut and the
+	// engine is closed. Zero is treated as three seconds.
+	StopTimeout time.Duration
+}
+
+// stopTimeout is the StopTimeout used when none is set.
+const stopTimeout = 3 * time.Second
+
+func (s *Suite) stopTimeout() time.Duration {
+	if s.StopTimeout > 0 {
+		return s.StopTimeout
+	}
+	return stopTimeout
+}
+
+// Result is how the engine did on one position.
+type Result struct {
+	EPD *epd.EPD
+	// Move is the move the engine settled on.
+	Move move.Move
+	// Depth is the last depth the engine reported.
+	Depth int
+	// Solved is whether the move is one of the best moves and none of the
+	// moves to avoid. Without either it is whether the move scored full
+	// points.
+	Solved bool
+	// SolvedIn is how long the engine took to find the move it settled on,
+	// for solved positions.
+	SolvedIn time.Duration
+	// Points is what the move scored from the position's point table, out
+	// of MaxPoints.
+	Points, MaxPoints int
+	Err               error
+}
+
+// Report is the outcome of running a suite.
+type Report struct {
+	// Results of every position in the order of the suite.
+	Results []Result
+	// Solved is how many positions were solved.
+	Solved int
+	// Points is the sum of the points of every position, out of MaxPoints.
+	Points, MaxPoints int
+}
+
+// SolveRate returns the share of the positions that were solved.
+func (r *Report) SolveRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	return float64(r.Solved) / float64(len(r.Results))
+}
+
+// Run runs the engine on every position of the suite. Canceling the context
+// stops the searches in progress; positions that were not finished are not
+// reported.
+func (s *Suite) Run(ctx context.Context) (*Report, error) {
+	if s.Limit.Time <= 0 && s.Limit.Depth <= 0 {
+		return nil, ErrNoLimit
+	}
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]Result, len(s.Positions))
+	done := make([]bool, len(s.Positions))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var e engines.Engine
+			defer func() {
+				if e != nil {
+					e.Close()
+				}
+			}()
+			for i := range jobs {
+				var r Result
+				if e == nil {
+					var err error
+					if e, err = s.Start(); err != nil {
+						r = Result{EPD: s.Positions[i], Err: err}
+					}
+				}
+				if e != nil {
+					r = s.run(ctx, e, s.Positions[i])
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if r.Err != nil && e != nil {
+					// The engine may be in any state, so the next position
+					// gets a fresh one:
+					e.Close()
+					e = nil
+				}
+				mu.Lock()
+				results[i], done[i] = r, true
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range s.Positions {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	report := &Report{}
+	for i, r := range results {
+		if !done[i] {
+			continue
+		}
+		report.Results = append(report.Results, r)
+		if r.Solved {
+			report.Solved++
+		}
+		report.Points += r.Points
+		report.MaxPoints += r.MaxPoints
+	}
+	return report, ctx.Err()
+}
+
+// run has the engine search the position within the limit.
+func (s *Suite) run(ctx context.Context, e engines.Engine, test *epd.EPD) Result {
+	r := Result{EPD: test}
+	if err := e.NewGame(); err != nil {
+		r.Err = err
+		return r
+	}
+	output, err := e.Think(test.Position)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	start := time.Now()
+	var expired <-chan time.Time
+	if s.Limit.Time > 0 {
+		timer := time.NewTimer(s.Limit.Time)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	// Once told to stop, the engine only gets so long to answer, so one that
+	// hangs does not hold up the suite:
+	var grace <-chan time.Time
+	stop := func() {
+		if grace == nil {
+			e.Stop()
+			grace = time.After(s.stopTimeout())
+		}
+	}
+	canceled := ctx.Done()
+	found, foundAt := "", time.Duration(0)
+	best := ""
+	for output != nil {
+		select {
+		case line, ok := <-output:
+			if !ok {
+				output = nil
+				break
+			}
+			words := strings.Fields(line)
+			if len(words) > 1 && words[0] == "bestmove" {
+				best = words[1]
+				continue
+			}
+			if depth, pv := parseInfo(words); pv != "" {
+				if depth > r.Depth {
+					r.Depth = depth
+				}
+				if pv != found {
+					found, foundAt = pv, time.Since(start)
+				}
+				if s.Limit.Depth > 0 && depth >= s.Limit.Depth {
+					stop()
+				}
+			}
+		case <-expired:
+			stop()
+		case <-canceled:
+			canceled = nil
+			stop()
+		case <-grace:
+			r.Err = engines.ErrTimedOut
+			return r
+		}
+	}
+	if best == "" {
+		r.Err = ErrNoBestMove
+		return r
+	}
+	r.Move = move.Parse(best)
+	if best != found {
+		foundAt = time.Since(start)
+	}
+	r.Solved, r.Points, r.MaxPoints, r.Err = check(test, r.Move)
+	if r.Solved {
+		r.SolvedIn = foundAt
+	}
+	return r
+}
+
+// parseInfo returns the depth and the first move of the principal variation
+// of an info line.
+func parseInfo(words []string) (depth int, pv string) {
+	if len(words) == 0 || words[0] != "info" {
+		return 0, ""
+	}
+	for i := 1; i+1 < len(words); i++ {
+		switch words[i] {
+		case "depth":
+			depth, _ = strconv.Atoi(words[i+1])
+		case "pv":
+			return depth, words[i+1]
+		}
+	}
+	return depth, ""
+}
+
+// check scores the move against the bm and am operations and the point
+// table of the position.
+func check(test *epd.EPD, m move.Move) (solved bool, points, max int, err error) {
+	table, err := Points(test)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	for pm, p := range table {
+		if pm == m {
+			points = p
+		}
+		if p > max {
+			max = p
+		}
+	}
+	bm, bmErr := test.BestMoves()
+	am, amErr := test.AvoidMoves()
+	for _, e := range []error{bmErr, amErr} {
+		if e != nil && e != epd.ErrNoOperation {
+			return false, points, max, e
+		}
+	}
+	switch {
+	case bmErr != nil && amErr != nil && len(table) == 0:
+		return false, 0, 0, ErrNothingToCheck
+	case bmErr != nil && amErr != nil:
+		return points == max, points, max, nil
+	}
+	solved = bmErr != nil || contains(bm, m)
+	solved = solved && (amErr != nil || !contains(am, m))
+	return solved, points, max, nil
+}
+
+func contains(moves []move.Move, m move.Move) bool {
+	for _, c := range moves {
+		if c == m {
+			return true
+		}
+	}
+	return false
+}
+
+// Points returns the point table of an STS style position, which its c0
+// operation gives as "Qd2=10, Bxf6=5, h4=3". Positions without one have an
+// empty table.
+func Points(test *epd.EPD) (map[move.Move]int, error) {
+	table := make(map[move.Move]int)
+	c0, err := test.Text("c0")
+	if err != nil || !strings.Contains(c0, "=") {
+		return table, nil
+	}
+	for _, entry := range strings.Split(c0, ",") {
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			continue
+		}
+		p, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+		if err != nil {
+			// Not a point table, just a comment:
+			return make(map[move.Move]int), nil
+		}
+		m, err := test.Position.ParseLegalMove(strings.TrimSpace(entry[:i]))
+		if err != nil {
+			return nil, err
+		}
+		table[m] = p
+	}
+	return table, nil
+}
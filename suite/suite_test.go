@@ -0,0 +1,216 @@
+package suite
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/epd"
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// fakeEngine is an in-process UCI engine. When told to think it reports the
+// moves lines returns for the position, one per depth, and answers "stop"
+// with the last of them. It hangs up when there are none.
+func fakeEngine(conn net.Conn, lines func(*position.Position) []string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var p *position.Position
+	var best string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "uci":
+			conn.Write([]byte("uciok\n"))
+		case "isready":
+			conn.Write([]byte("readyok\n"))
+		case "position":
+			p, _ = fen.Decode(strings.Join(words[2:8], " "))
+		case "go":
+			moves := lines(p)
+			if len(moves) == 0 {
+				return
+			}
+			for i, m := range moves {
+				conn.Write([]byte("info depth " + strconv.Itoa(i+1) + " score cp 0 pv " + m + "\n"))
+			}
+			best = moves[len(moves)-1]
+		case "stop":
+			conn.Write([]byte("bestmove " + best + "\n"))
+		case "quit":
+			return
+		}
+	}
+}
+
+func fakeStart(lines func(*position.Position) []string) func() (engines.Engine, error) {
+	return func() (engines.Engine, error) {
+		client, server := net.Pipe()
+		go fakeEngine(server, lines)
+		return engines.AttachUCIEngine("fake", client, engines.Options{})
+	}
+}
+
+func decode(t *testing.T, lines ...string) []*epd.EPD {
+	var positions []*epd.EPD
+	for _, line := range lines {
+		e, err := epd.Decode(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions = append(positions, e)
+	}
+	return positions
+}
+
+const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - "
+
+func TestRun(t *testing.T) {
+	s := Suite{
+		Positions: decode(t,
+			start+`bm e4; id "best";`,
+			start+`bm d4; id "missed";`,
+			start+`am e4; id "avoided";`,
+			start+`id "points"; c0 "e4=10, d4=7, Nf3=5";`,
+			start+`id "again"; bm e4;`,
+		),
+		Start: fakeStart(func(p *position.Position) []string {
+			return []string{"d2d4", "e2e4", "e2e4"}
+		}),
+		Limit:       Limit{Depth: 3, Time: time.Minute},
+		Concurrency: 3,
+	}
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 5 || report.Solved != 3 || report.Points != 10 || report.MaxPoints != 10 {
+		t.Fatal("got", report)
+	}
+	solved := map[string]bool{"best": true, "missed": false, "avoided": false, "points": true, "again": true}
+	for _, r := range report.Results {
+		if r.Solved != solved[r.EPD.ID()] || r.Move != move.Parse("e2e4") || r.Depth != 3 {
+			t.Error(r.EPD.ID(), "got", r.Solved, r.Move, r.Depth, r.Err)
+		}
+	}
+	if report.SolveRate() != 0.6 {
+		t.Error("got", report.SolveRate())
+	}
+}
+
+func TestRunTimeLimit(t *testing.T) {
+	s := Suite{
+		Positions: decode(t, start+`bm e4;`, start+`bm Nf3;`),
+		Start: fakeStart(func(p *position.Position) []string {
+			return []string{"e2e4"}
+		}),
+		Limit: Limit{Time: 20 * time.Millisecond},
+	}
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Solved != 1 || !report.Results[0].Solved || report.Results[1].Solved {
+		t.Error("got", report.Results)
+	}
+	if _, err := (&Suite{}).Run(context.Background()); err != ErrNoLimit {
+		t.Error("got", err)
+	}
+}
+
+func TestRunCrash(t *testing.T) {
+	s := Suite{
+		Positions: decode(t, start+`bm e4;`, start+`bm e4;`),
+		Start: fakeStart(func(p *position.Position) []string {
+			return nil
+		}),
+		Limit: Limit{Depth: 1},
+	}
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.Results {
+		if r.Err == nil || r.Solved {
+			t.Error("got", r)
+		}
+	}
+}
+
+// hungEngine is an in-process UCI engine that never gives a move.
+func hungEngine(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch strings.TrimSpace(line) {
+		case "uci":
+			conn.Write([]byte("uciok\n"))
+		case "isready":
+			conn.Write([]byte("readyok\n"))
+		case "quit":
+			return
+		}
+	}
+}
+
+func TestRunHung(t *testing.T) {
+	s := Suite{
+		Positions: decode(t, start+`bm e4;`, start+`bm e4;`),
+		Start: func() (engines.Engine, error) {
+			client, server := net.Pipe()
+			go hungEngine(server)
+			return engines.AttachUCIEngine("hung", client, engines.Options{})
+		},
+		Limit:       Limit{Time: 10 * time.Millisecond},
+		StopTimeout: 20 * time.Millisecond,
+	}
+	report, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatal("got", report.Results)
+	}
+	for _, r := range report.Results {
+		if r.Err != engines.ErrTimedOut {
+			t.Error("got", r.Err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.Limit = Limit{Depth: 1}
+	if _, err := s.Run(ctx); err != context.DeadlineExceeded {
+		t.Error("got", err)
+	}
+}
+
+func TestPoints(t *testing.T) {
+	table, err := Points(decode(t, start+`c0 "e4=10, Nf3=4";`)[0])
+	if err != nil || table[move.Parse("e2e4")] != 10 || table[move.Parse("g1f3")] != 4 {
+		t.Error("got", table, err)
+	}
+	if table, _ := Points(decode(t, start+`c0 "a comment";`)[0]); len(table) != 0 {
+		t.Error("got", table)
+	}
+	if _, err := Points(decode(t, start+`c0 "e5=10";`)[0]); err == nil {
+		t.Error("accepted an illegal move")
+	}
+}
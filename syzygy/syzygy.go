@@ -0,0 +1,471 @@
+// Package syzygy probes Syzygy endgame tablebases. It reads the WDL (.rtbw)
+// and DTZ (.rtbz) files of a directory and answers whether positions with
+// few enough pieces are won, drawn or lost, and how to play them:
+//
+//	tb, err := syzygy.Open("/path/to/syzygy")
+//	...
+//	defer tb.Close()
+//	w, err := tb.ProbeWDL(p)
+//	best, err := tb.BestMove(p)
+//
+// Tables are read when they are first needed. A Tablebase is safe for use by
+// several goroutines at once.
+package syzygy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+var (
+	// ErrNoTable is returned when the tables for a position are missing,
+	// usually because it has too many pieces.
+	ErrNoTable = errors.New("syzygy: no table for the position")
+	// ErrCastling is returned for positions with castling rights, which are
+	// not in the tables.
+	ErrCastling = errors.New("syzygy: the tables have no positions with castling rights")
+	// ErrNoMoves is returned when looking for the best move of a position
+	// that is checkmate or stalemate.
+	ErrNoMoves = errors.New("syzygy: the position has no legal moves")
+	// ErrCorrupt is returned when a table file can not be read.
+	ErrCorrupt = errors.New("syzygy: corrupt table")
+)
+
+// WDL is the result of a position for the side to move, counting the
+// fifty-move rule.
+type WDL int
+
+// Possible results. A cursed win is a win that takes too long, and is drawn
+// by the fifty-move rule, and a blessed loss is its opposite.
+const (
+	Loss WDL = iota - 2
+	BlessedLoss
+	Draw
+	CursedWin
+	Win
+)
+
+func (w WDL) String() string {
+	return map[WDL]string{
+		Loss:        "loss",
+		BlessedLoss: "blessed loss",
+		Draw:        "draw",
+		CursedWin:   "cursed win",
+		Win:         "win",
+	}[w]
+}
+
+// Tablebase is a set of Syzygy table files.
+type Tablebase struct {
+	mu sync.Mutex
+	// tables are keyed on both material keys of each table.
+	tables    [2]map[string]*table
+	maxPieces int
+}
+
+// Open finds the table files in the directories. Files are only read when a
+// position needs them.
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{tables: [2]map[string]*table{{}, {}}}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			for k, ext := range extensions {
+				key := strings.TrimSuffix(e.Name(), ext)
+				if key == e.Name() || !validKey(key) || tb.tables[k][key] != nil {
+					continue
+				}
+				t := newTable(kind(k), filepath.Join(dir, e.Name()), key)
+				tb.tables[k][t.key], tb.tables[k][t.key2] = t, t
+				if t.pieceCount > tb.maxPieces {
+					tb.maxPieces = t.pieceCount
+				}
+			}
+		}
+	}
+	return tb, nil
+}
+
+// validKey reports whether a file name is the material of a table, like KRvK.
+func validKey(key string) bool {
+	sides := strings.Split(key, "v")
+	if len(sides) != 2 || len(sides[0])+len(sides[1]) > maxPieces {
+		return false
+	}
+	for _, side := range sides {
+		if !strings.HasPrefix(side, "K") || strings.Trim(side[1:], "QRBNP") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxPieces returns the most pieces, kings included, of any table.
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// Close closes the table files that were read.
+func (tb *Tablebase) Close() error {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	var err error
+	for _, tables := range tb.tables {
+		for key, t := range tables {
+			if key == t.key {
+				if e := t.close(); e != nil {
+					err = e
+				}
+			}
+		}
+	}
+	return err
+}
+
+// ProbeWDL returns the result of the position for the side to move. It does
+// not look at the fifty-move counter of the position, only at whether a win
+// takes too long to be won before the fifty-move rule.
+func (tb *Tablebase) ProbeWDL(p *position.Position) (WDL, error) {
+	if err := probeable(p); err != nil {
+		return Draw, err
+	}
+	w, _, err := tb.search(p, false)
+	return w, err
+}
+
+// ProbeDTZ returns the distance to zeroing: how many plies it takes, playing
+// best, for the position to be won or lost with a capture or a pawn move that
+// resets the fifty-move counter. It is positive when the side to move wins
+// and negative when it loses. Cursed wins and blessed losses are 100 plies
+// further away, and drawn positions are zero.
+func (tb *Tablebase) ProbeDTZ(p *position.Position) (int, error) {
+	if err := probeable(p); err != nil {
+		return 0, err
+	}
+	dtz, _, err := tb.probeDTZ(p)
+	return dtz, err
+}
+
+// Result is what the tables tell about a move.
+type Result struct {
+	Move move.Move
+	// WDL is the result of the move for the side making it, and DTZ is how
+	// many plies, counting the move, it takes to zero the fifty-move counter.
+	WDL WDL
+	DTZ int
+}
+
+// Moves returns every legal move of the position, best first. Winning moves
+// that stay clear of the fifty-move rule, given the fifty-move counter of the
+// position, come first, the quickest to zero first. Losing moves come last,
+// the slowest to lose first.
+func (tb *Tablebase) Moves(p *position.Position) ([]Result, error) {
+	if err := probeable(p); err != nil {
+		return nil, err
+	}
+	var results []Result
+	ranks := map[move.Move]int{}
+	counter := int(p.FiftyMoveCount)
+	for m := range p.LegalMoves() {
+		q := p.MakeMove(m)
+		r := Result{Move: m}
+		if zeroing(p, m) {
+			w, _, err := tb.search(q, false)
+			if err != nil {
+				return nil, err
+			}
+			r.WDL, r.DTZ = -w, beforeZeroing(-w)
+		} else {
+			dtz, w, err := tb.probeDTZ(q)
+			if err != nil {
+				return nil, err
+			}
+			r.WDL, r.DTZ = -w, -dtz+sign(-dtz)
+		}
+		if r.DTZ == 2 && mated(q) {
+			r.DTZ = 1
+		}
+		switch {
+		case r.DTZ > 0 && r.DTZ+counter <= 99:
+			ranks[m] = 1000
+		case r.DTZ > 0:
+			ranks[m] = 1000 - (r.DTZ + counter)
+		case r.DTZ < 0 && -2*r.DTZ+counter < 100:
+			ranks[m] = -1000
+		case r.DTZ < 0:
+			ranks[m] = -1000 + (-r.DTZ + counter)
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case ranks[a.Move] != ranks[b.Move]:
+			return ranks[a.Move] > ranks[b.Move]
+		case a.DTZ != b.DTZ:
+			// The quickest win, or the slowest loss.
+			return a.DTZ < b.DTZ
+		}
+		return a.Move.String() < b.Move.String()
+	})
+	return results, nil
+}
+
+// BestMove returns the best move of the position: the quickest win that is
+// not spoiled by the fifty-move rule, or else a draw, or else the slowest
+// loss.
+func (tb *Tablebase) BestMove(p *position.Position) (Result, error) {
+	results, err := tb.Moves(p)
+	if err == nil && len(results) == 0 {
+		err = ErrNoMoves
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+// probeable checks that the position can be in the tables.
+func probeable(p *position.Position) error {
+	for _, sides := range p.CastlingRights {
+		for _, ok := range sides {
+			if ok {
+				return ErrCastling
+			}
+		}
+	}
+	if p.Occupied(piece.BothColors).Count() > maxPieces {
+		return ErrNoTable
+	}
+	return nil
+}
+
+// search resolves captures, and when zeroing is set pawn moves too, before
+// looking the position up, since the tables do not hold the right value for
+// positions where en passant is possible, or where the best move zeroes. It
+// reports whether the best move zeroes the fifty-move counter.
+func (tb *Tablebase) search(p *position.Position, zeroing bool) (WDL, bool, error) {
+	moves := p.LegalMoves()
+	if len(moves) == 0 {
+		if p.Check(p.ActiveColor) {
+			return Loss, false, nil
+		}
+		return Draw, false, nil
+	}
+	best, searched := Loss, 0
+	for m := range moves {
+		if !capture(p, m) && (!zeroing || p.OnSquare(m.From()).Type != piece.Pawn) {
+			continue
+		}
+		searched++
+		w, _, err := tb.search(p.MakeMove(m), false)
+		if err != nil {
+			return Draw, false, err
+		}
+		if -w > best {
+			best = -w
+			if best == Win {
+				return Win, true, nil
+			}
+		}
+	}
+	if searched == len(moves) {
+		return best, true, nil
+	}
+	v, err := tb.probe(p, wdl, Draw)
+	if err != nil {
+		return Draw, false, err
+	}
+	if best >= WDL(v) {
+		return best, best > Draw, nil
+	}
+	return WDL(v), false, nil
+}
+
+// probeDTZ returns the DTZ and the result of a position.
+func (tb *Tablebase) probeDTZ(p *position.Position) (int, WDL, error) {
+	w, zeroes, err := tb.search(p, true)
+	if err != nil || w == Draw {
+		return 0, w, err
+	}
+	if len(p.LegalMoves()) == 0 {
+		return -1, w, nil
+	}
+	if zeroes {
+		return beforeZeroing(w), w, nil
+	}
+	dtz, err := tb.probe(p, dtz, w)
+	if err == nil {
+		if w == CursedWin || w == BlessedLoss {
+			dtz += 100
+		}
+		return dtz * sign(int(w)), w, nil
+	}
+	if err != errOtherSide {
+		return 0, w, err
+	}
+
+	// The table only holds the other side to move, so look one move ahead
+	// for the quickest win, or the slowest loss.
+	best := 0xFFFF
+	for m := range p.LegalMoves() {
+		q := p.MakeMove(m)
+		var d int
+		if zeroing(p, m) {
+			v, _, err := tb.search(q, false)
+			if err != nil {
+				return 0, w, err
+			}
+			d = -beforeZeroing(v)
+		} else {
+			v, _, err := tb.probeDTZ(q)
+			if err != nil {
+				return 0, w, err
+			}
+			d = -v + sign(-v)
+		}
+		if d == 2 && mated(q) {
+			d = 1
+		}
+		if d < best && sign(d) == sign(int(w)) {
+			best = d
+		}
+	}
+	return best, w, nil
+}
+
+// beforeZeroing returns the DTZ of a position whose best move zeroes the
+// fifty-move counter.
+func beforeZeroing(w WDL) int {
+	return map[WDL]int{Win: 1, CursedWin: 101, BlessedLoss: -101, Loss: -1}[w]
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+func capture(p *position.Position, m move.Move) bool {
+	return p.OnSquare(m.To()).Type != piece.None || m.To() == p.EnPassant && p.OnSquare(m.From()).Type == piece.Pawn
+}
+
+func zeroing(p *position.Position, m move.Move) bool {
+	return capture(p, m) || p.OnSquare(m.From()).Type == piece.Pawn
+}
+
+func mated(p *position.Position) bool {
+	return p.Check(p.ActiveColor) && len(p.LegalMoves()) == 0
+}
+
+// errOtherSide is returned when a DTZ table holds only the other side to
+// move.
+var errOtherSide = errors.New("syzygy: other side to move")
+
+// materialKey returns the material of the position, like KRvKN.
+func materialKey(p *position.Position) string {
+	var sides [2]string
+	for _, c := range piece.Colors {
+		sides[c] = "K"
+		for _, t := range []piece.Type{piece.Queen, piece.Rook, piece.Bishop, piece.Knight, piece.Pawn} {
+			sides[c] += strings.Repeat(strings.ToUpper(t.String()), p.Pieces(c, t).Count())
+		}
+	}
+	return sides[0] + "v" + sides[1]
+}
+
+// lookup returns the table of the kind for the material key.
+func (tb *Tablebase) lookup(k kind, key string) (*table, error) {
+	tb.mu.Lock()
+	t := tb.tables[k][key]
+	tb.mu.Unlock()
+	if t == nil {
+		return nil, ErrNoTable
+	}
+	return t, t.open()
+}
+
+// tableSquare turns a square into the numbering of the tables.
+func tableSquare(s square.Square) int {
+	return int(s) ^ 7
+}
+
+// probe looks the position up in a table. For DTZ tables w is the result of
+// the position.
+func (tb *Tablebase) probe(p *position.Position, k kind, w WDL) (int, error) {
+	if p.Occupied(piece.BothColors).Count() == 2 {
+		return int(Draw), nil
+	}
+	key := materialKey(p)
+	t, err := tb.lookup(k, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// The tables have white as the stronger side, and only hold white to
+	// move when both sides have the same material. Otherwise the colors
+	// are swapped and the board flipped.
+	black := p.ActiveColor == piece.Black
+	flip := key != t.key || t.key == t.key2 && black
+	flipColor, flipSquares, stm := 0, 0, 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+	}
+	if flip != black {
+		stm = 1
+	}
+
+	var squares, pieces []int
+	var leaders position.BitBoard
+	file := 0
+	if t.hasPawns {
+		c := piece.Color((t.get(0, 0).pieces[0] ^ flipColor) >> 3)
+		leaders = p.Pieces(c, piece.Pawn)
+		for _, s := range leaders.Squares() {
+			squares = append(squares, tableSquare(s)^flipSquares)
+			pieces = append(pieces, int(piece.Pawn)|int(c)<<3^flipColor)
+		}
+		first := 0
+		for i := range squares {
+			if mapPawns[squares[i]] > mapPawns[squares[first]] {
+				first = i
+			}
+		}
+		squares[0], squares[first] = squares[first], squares[0]
+		file = squares[0] & 7
+		if file > 3 {
+			file = 7 - file
+		}
+	}
+	if k == dtz && t.get(stm, file).flags&flagSTM != stm && (t.key != t.key2 || t.hasPawns) {
+		return 0, errOtherSide
+	}
+	leads := len(squares)
+	for _, s := range (p.Occupied(piece.BothColors) &^ leaders).Squares() {
+		pc := p.OnSquare(s)
+		squares = append(squares, tableSquare(s)^flipSquares)
+		pieces = append(pieces, int(pc.Type)|int(pc.Color)<<3^flipColor)
+	}
+	d := t.get(stm, file)
+	v, err := t.decompress(d, t.encode(d, squares, pieces, leads))
+	if err != nil || k == wdl {
+		return v - 2, err
+	}
+	return t.mapDTZ(file, v, w), nil
+}
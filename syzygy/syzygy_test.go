@@ -0,0 +1,319 @@
+package syzygy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+func decode(t *testing.T, f string) *position.Position {
+	p, err := fen.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestIndexTables(t *testing.T) {
+	codes := map[int]bool{}
+	for i := range mapKK {
+		for s := range mapKK[i] {
+			codes[mapKK[i][s]] = true
+		}
+	}
+	if len(codes) != 462 || !codes[461] {
+		t.Errorf("mapKK has %d codes", len(codes))
+	}
+	for s, want := range map[int]int{1: 0, 3: 2, 19: 5, 0: 6, 9: 7, 18: 8, 27: 9} {
+		if mapA1D1D4[s] != want {
+			t.Errorf("mapA1D1D4[%d] = %d, want %d", s, mapA1D1D4[s], want)
+		}
+	}
+	if mapPawns[8] != 47 || mapPawns[15] != 46 || mapPawns[11] != 11 {
+		t.Errorf("mapPawns is %v", mapPawns)
+	}
+	if binomial[2][4] != 6 || binomial[5][63] != 7028847 {
+		t.Errorf("binomial is %v", binomial)
+	}
+}
+
+// classes checks that positions, given as squares of pieces, have the same
+// index when they are symmetric and different ones otherwise.
+func classes(t *testing.T, name string, index func([]int) (uint64, bool), symmetries []func(int) int) {
+	byIndex := map[uint64][]int{}
+	sq := make([]int, 3)
+	for sq[0] = 0; sq[0] < 64; sq[0]++ {
+		for sq[1] = 0; sq[1] < 64; sq[1]++ {
+			for sq[2] = 0; sq[2] < 64; sq[2]++ {
+				idx, ok := index(append([]int{}, sq...))
+				if !ok {
+					continue
+				}
+				if other, seen := byIndex[idx]; seen && !symmetric(other, sq, symmetries) {
+					t.Fatalf("%s: %v and %v both have index %d", name, other, sq, idx)
+				}
+				byIndex[idx] = append([]int{}, sq...)
+				for _, f := range symmetries {
+					mirrored := []int{f(sq[0]), f(sq[1]), f(sq[2])}
+					if i, _ := index(mirrored); i != idx {
+						t.Fatalf("%s: %v has index %d, but its mirror %v has %d", name, sq, idx, mirrored, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func symmetric(a, b []int, symmetries []func(int) int) bool {
+	for _, f := range symmetries {
+		if f(a[0]) == b[0] && f(a[1]) == b[1] && f(a[2]) == b[2] {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEncode(t *testing.T) {
+	mirror := func(s int) int { return s ^ 7 }
+	flip := func(s int) int { return s ^ 56 }
+	transpose := func(s int) int { return (s>>3 | s<<3) & 63 }
+	var pawnless []func(int) int
+	for _, f := range []func(int) int{nil, mirror, flip, func(s int) int { return mirror(flip(s)) }} {
+		for _, g := range []func(int) int{nil, transpose} {
+			f, g := f, g
+			pawnless = append(pawnless, func(s int) int {
+				if g != nil {
+					s = g(s)
+				}
+				if f != nil {
+					s = f(s)
+				}
+				return s
+			})
+		}
+	}
+	legal := func(sq []int) bool {
+		return sq[0] != sq[1] && sq[0] != sq[2] && sq[1] != sq[2] && distance(sq[0], sq[2]) > 1
+	}
+
+	kqk := newTable(wdl, "", "KQvK")
+	d := kqk.get(0, 0)
+	d.pieces = [maxPieces]int{6, 5, 14}
+	kqk.setGroups(d, [2]int{0, 0xF}, 0)
+	if d.groupLen[0] != 3 || d.groupIdx[1] != 31332 {
+		t.Fatalf("KQvK has groups %v of %v", d.groupLen, d.groupIdx)
+	}
+	classes(t, "KQvK", func(sq []int) (uint64, bool) {
+		if !legal(sq) {
+			return 0, false
+		}
+		idx := kqk.encode(d, sq, []int{6, 5, 14}, 0)
+		if idx >= d.groupIdx[1] {
+			t.Fatalf("KQvK: %v has index %d", sq, idx)
+		}
+		return idx, true
+	}, pawnless)
+
+	kpk := newTable(wdl, "", "KPvK")
+	for f := 0; f < 4; f++ {
+		d := kpk.get(0, f)
+		d.pieces = [maxPieces]int{1, 6, 14}
+		kpk.setGroups(d, [2]int{0, 0xF}, f)
+	}
+	classes(t, "KPvK", func(sq []int) (uint64, bool) {
+		if sq[0] < 8 || sq[0] >= 56 || !legal(sq) {
+			return 0, false
+		}
+		file := sq[0] & 7
+		if file > 3 {
+			file = 7 - file
+		}
+		d := kpk.get(0, file)
+		idx := kpk.encode(d, sq, []int{1, 6, 14}, 1)
+		if idx >= d.groupIdx[3] {
+			t.Fatalf("KPvK: %v has index %d", sq, idx)
+		}
+		// Keep the files apart.
+		return idx<<2 | uint64(file), true
+	}, []func(int) int{func(s int) int { return s }, mirror})
+}
+
+// lr packs the two symbols a symbol expands to.
+func lr(left, right int) []byte {
+	return []byte{byte(left), byte(left>>8&0xF | right&0xF<<4), byte(right >> 4)}
+}
+
+func TestDecompress(t *testing.T) {
+	// Symbols 0, 1 and 2 are the values 0, 2 and 4, symbol 3 expands to 2 2
+	// and symbol 4 to 0 3. Symbols 0 and 1 have the codes 000 and 001, and
+	// symbols 2, 3 and 4 have 01, 10 and 11.
+	d := &pairs{blockSize: 32, span: 8, minSymLen: 2, maxSymLen: 3, lowestSym: []uint16{2, 0}}
+	d.setCode()
+	for _, b := range [][]byte{lr(0, 0xFFF), lr(2, 0xFFF), lr(4, 0xFFF), lr(2, 2), lr(0, 3)} {
+		d.btree = append(d.btree, b...)
+	}
+	if err := d.setSymlen(); err != nil {
+		t.Fatal(err)
+	}
+	// Block 0 is 4, and block 1 is 1 0 3 2 1.
+	d.blockLength = []byte{2, 0, 5, 0}
+	d.sparseIndex = []byte{1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 12, 0}
+	data := make([]byte, 64)
+	data[0], data[32], data[33] = 0xC0, 0x22, 0x48
+	tb := &table{r: bytes.NewReader(data)}
+	for idx, want := range []int{0, 4, 4, 2, 0, 4, 4, 4, 2} {
+		if got, err := tb.decompress(d, uint64(idx)); got != want || err != nil {
+			t.Errorf("value %d is %d, %v; want %d", idx, got, err, want)
+		}
+	}
+}
+
+// writeTables writes KQvK tables where every position with white to move is
+// a win with a DTZ of 7, and every position with black to move is a loss.
+func writeTables(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"KQvK.rtbw": {0x71, 0xE8, 0x23, 0x5D, 1, 0, 0x66, 0x55, 0xEE, 0, 0x80, 4, 0x80, 0},
+		"KQvK.rtbz": {0xD7, 0x66, 0x0C, 0xA5, 1, 0, 6, 5, 14, 0, 0x80, 3},
+		"README":    []byte("not a table"),
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProbe(t *testing.T) {
+	tb, err := Open(writeTables(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+	if tb.MaxPieces() != 3 {
+		t.Errorf("got %d pieces", tb.MaxPieces())
+	}
+	tests := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"8/8/8/3k4/8/8/8/4K2Q w - - 0 1", Win, 7},
+		{"8/8/8/3k4/8/8/8/4K2Q b - - 0 1", Loss, -8},
+		{"4k2q/8/8/8/3K4/8/8/8 b - - 0 1", Win, 7},
+		{"4k2q/8/8/8/3K4/8/8/8 w - - 0 1", Loss, -8},
+		{"8/8/8/8/8/8/6kQ/4K3 b - - 0 1", Draw, 0},
+		{"8/8/8/3k4/8/8/8/4K3 w - - 0 1", Draw, 0},
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", Loss, -1},
+	}
+	for _, test := range tests {
+		p := decode(t, test.fen)
+		if w, err := tb.ProbeWDL(p); w != test.wdl || err != nil {
+			t.Errorf("%s: got %v, %v; want %v", test.fen, w, err, test.wdl)
+		}
+		if dtz, err := tb.ProbeDTZ(p); dtz != test.dtz || err != nil {
+			t.Errorf("%s: got a DTZ of %d, %v; want %d", test.fen, dtz, err, test.dtz)
+		}
+	}
+
+	best, err := tb.BestMove(decode(t, "8/8/8/3k4/8/8/8/4K2Q w - - 0 1"))
+	if err != nil || best.WDL != Win || best.DTZ != 9 {
+		t.Errorf("got %+v, %v", best, err)
+	}
+	moves, err := tb.Moves(decode(t, "8/8/8/3k4/8/8/8/4K2Q w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := moves[len(moves)-1]; last.WDL != Draw || last.DTZ != 0 {
+		t.Errorf("hanging the queen gives %+v", last)
+	}
+
+	for f, want := range map[string]error{
+		"8/8/8/3k4/8/8/8/4K2R w - - 0 1": ErrNoTable,
+		"r3k3/8/8/8/8/8/8/4K3 b q - 0 1": ErrCastling,
+	} {
+		if _, err := tb.ProbeWDL(decode(t, f)); err != want {
+			t.Errorf("%s: got %v, want %v", f, err, want)
+		}
+	}
+	if _, err := tb.BestMove(decode(t, "7k/6Q1/6K1/8/8/8/8/8 b - - 0 1")); err != ErrNoMoves {
+		t.Errorf("got %v for a checkmate", err)
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "KQvK.rtbw"), []byte{0x71, 0xE8, 0x23}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+	if _, err := tb.ProbeWDL(decode(t, "8/8/8/3k4/8/8/8/4K2Q w - - 0 1")); err != ErrCorrupt {
+		t.Errorf("got %v", err)
+	}
+}
+
+// TestTables probes real tables, which are not part of the repository. Set
+// SYZYGY_PATH to the directories of a 3-4-5 piece set to run it. Positions
+// whose tables are not there are skipped.
+func TestTables(t *testing.T) {
+	dirs := filepath.SplitList(os.Getenv("SYZYGY_PATH"))
+	if len(dirs) == 0 {
+		t.Skip("SYZYGY_PATH is not set")
+	}
+	tb, err := Open(dirs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+	tests := []struct {
+		table string
+		fen   string
+		wdl   WDL
+		best  []string
+	}{
+		{"KQvK", "k7/8/1K6/8/8/8/8/7Q w - - 0 1", Win, []string{"h1h8", "h1b7"}},
+		{"KPvK", "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", Win, nil},
+		{"KPvK", "4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", Loss, nil},
+		{"KNNvK", "8/8/8/3k4/8/8/8/1NN1K3 w - - 0 1", Draw, nil},
+		{"KRvK", "8/8/8/3k4/8/8/8/R3K3 b - - 0 1", Loss, nil},
+	}
+	probed := 0
+	for _, test := range tests {
+		if tb.tables[wdl][test.table] == nil || tb.tables[dtz][test.table] == nil {
+			continue
+		}
+		probed++
+		p := decode(t, test.fen)
+		if w, err := tb.ProbeWDL(p); w != test.wdl || err != nil {
+			t.Errorf("%s: got %v, %v; want %v", test.fen, w, err, test.wdl)
+		}
+		best, err := tb.BestMove(p)
+		if err != nil || best.WDL != test.wdl {
+			t.Errorf("%s: got %+v, %v", test.fen, best, err)
+		}
+		if test.best == nil {
+			continue
+		}
+		found := false
+		for _, m := range test.best {
+			found = found || best.Move == move.Parse(m)
+		}
+		if !found || best.DTZ != 1 {
+			t.Errorf("%s: got %+v, want one of %v", test.fen, best, test.best)
+		}
+	}
+	if probed == 0 {
+		t.Skip("no tables to probe in", dirs)
+	}
+}
@@ -0,0 +1,762 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Squares in this file are numbered as in the tables, from a1 = 0 to h8 = 63,
+// and pieces are coded as their piece.Type, plus 8 for black pieces.
+
+// kind is the kind of a table file.
+type kind int
+
+const (
+	wdl kind = iota
+	dtz
+)
+
+var (
+	extensions = [2]string{".rtbw", ".rtbz"}
+	magics     = [2][4]byte{{0x71, 0xE8, 0x23, 0x5D}, {0xD7, 0x66, 0x0C, 0xA5}}
+)
+
+// Flags of the pairs data of a table.
+const (
+	flagSTM         = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+const maxPieces = 7
+
+// Index tables shared by every table.
+var (
+	// binomial[k][n] is the number of ways to choose k of n squares.
+	binomial [maxPieces][64]uint64
+	// mapPawns maps a2-h7 to 0-47 so that the leading pawn, the one nearest
+	// to the edge and then the lowest, has the highest value.
+	mapPawns [64]int
+	// leadPawnIdx and leadPawnsSize encode the leading pawns of each file.
+	leadPawnIdx   [6][64]uint64
+	leadPawnsSize [6][4]uint64
+	// mapB1H1H7 maps the squares below the a1-h8 diagonal to 0-27.
+	mapB1H1H7 [64]int
+	// mapA1D1D4 maps the a1-d1-d4 triangle to 0-9, the diagonal last.
+	mapA1D1D4 [64]int
+	// mapKK maps the 462 ways to place two kings, the first one in the
+	// a1-d1-d4 triangle.
+	mapKK [10][64]int
+)
+
+// offDiagonal is negative below the a1-h8 diagonal, zero on it and positive
+// above it.
+func offDiagonal(s int) int {
+	return s>>3 - s&7
+}
+
+func init() {
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offDiagonal(s) < 0 {
+			mapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for s := 0; s < 64; s++ {
+		switch {
+		case s&7 > 3 || s>>3 > 3:
+		case offDiagonal(s) < 0:
+			mapA1D1D4[s] = code
+			code++
+		case offDiagonal(s) == 0:
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		mapA1D1D4[s] = code
+		code++
+	}
+
+	type pair struct{ i, s int }
+	var bothOnDiagonal []pair
+	code = 0
+	for i := 0; i < 10; i++ {
+		for s1 := 0; s1 < 64; s1++ {
+			if s1&7 > 3 || s1>>3 > 3 || offDiagonal(s1) > 0 || mapA1D1D4[s1] != i {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case distance(s1, s2) <= 1:
+				case offDiagonal(s1) == 0 && offDiagonal(s2) > 0:
+				case offDiagonal(s1) == 0 && offDiagonal(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{i, s2})
+				default:
+					mapKK[i][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.i][p.s] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < maxPieces && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47
+	for leads := 1; leads <= 5; leads++ {
+		for f := 0; f < 4; f++ {
+			var idx uint64
+			for r := 1; r <= 6; r++ {
+				s := r*8 + f
+				if leads == 1 {
+					mapPawns[s] = available
+					mapPawns[s^7] = available - 1
+					available -= 2
+				}
+				leadPawnIdx[leads][s] = idx
+				idx += binomial[leads-1][mapPawns[s]]
+			}
+			leadPawnsSize[leads][f] = idx
+		}
+	}
+}
+
+func distance(a, b int) int {
+	df, dr := a&7-b&7, a>>3-b>>3
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
+
+// pairs is how the values of one side, and for tables with pawns one file,
+// are stored: compressed with recursive pairing and a canonical Huffman code.
+type pairs struct {
+	flags int
+	// value is the only value when flagSingleValue is set.
+	value int
+
+	blockSize, span      uint64
+	numBlocks            int
+	minSymLen, maxSymLen int
+	lowestSym            []uint16
+	base64               []uint64
+	// btree holds the two symbols each symbol expands to, 12 bits each. A
+	// leaf holds its value on the left and 0xFFF on the right.
+	btree []byte
+	// symlen is how many values, minus one, each symbol expands to.
+	symlen []int
+	// sparseIndex holds, every span values, the block and the offset in
+	// the block of the value, and blockLength how many values, minus one,
+	// each block holds.
+	sparseIndex, blockLength         []byte
+	sparseIndexSize, blockLengthSize int
+	// data is where the compressed blocks start in the file.
+	data int64
+
+	// pieces are the pieces in the order they are encoded. They are split
+	// into groups of groupLen pieces, the last group followed by a zero.
+	pieces   [maxPieces]int
+	groupLen [maxPieces + 1]int
+	groupIdx [maxPieces + 1]uint64
+	// mapIdx points into the DTZ map for wins, losses, cursed wins and
+	// blessed losses.
+	mapIdx [4]int
+}
+
+func (d *pairs) left(sym int) int {
+	return int(d.btree[3*sym+1]&0xF)<<8 | int(d.btree[3*sym])
+}
+
+func (d *pairs) right(sym int) int {
+	return int(d.btree[3*sym+2])<<4 | int(d.btree[3*sym+1]>>4)
+}
+
+func (d *pairs) blockLen(block int) int {
+	return int(binary.LittleEndian.Uint16(d.blockLength[2*block:]))
+}
+
+// table is a WDL or DTZ table file. It is opened and read when first probed.
+type table struct {
+	kind kind
+	path string
+	// key is the material of the table with its first side white, and key2
+	// with its first side black, like KRvKN and KNvKR.
+	key, key2       string
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	// pawnCount is the number of pawns of the leading color and the other.
+	pawnCount [2]int
+
+	once  sync.Once
+	err   error
+	r     io.ReaderAt
+	file  *os.File
+	items [2][4]pairs
+	// dtzMap maps stored DTZ values to real ones.
+	dtzMap []byte
+}
+
+func newTable(k kind, path, key string) *table {
+	sides := strings.Split(key, "v")
+	t := &table{kind: k, path: path, key: key, key2: sides[1] + "v" + sides[0]}
+	t.pieceCount = len(sides[0]) + len(sides[1])
+	for _, side := range sides {
+		for _, letter := range []string{"Q", "R", "B", "N", "P"} {
+			if strings.Count(side, letter) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+	white, black := strings.Count(sides[0], "P"), strings.Count(sides[1], "P")
+	t.hasPawns = white+black > 0
+	// The leading color is the one with fewer pawns, but at least one.
+	if black == 0 || white > 0 && black >= white {
+		t.pawnCount = [2]int{white, black}
+	} else {
+		t.pawnCount = [2]int{black, white}
+	}
+	return t
+}
+
+// sides is the number of sides stored: DTZ tables, and tables with the
+// same material on both sides, only store one.
+func (t *table) sides() int {
+	if t.kind == dtz || t.key == t.key2 {
+		return 1
+	}
+	return 2
+}
+
+func (t *table) get(stm, file int) *pairs {
+	if t.kind == dtz {
+		stm = 0
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return &t.items[stm][file]
+}
+
+// open reads the table file the first time it is called.
+func (t *table) open() error {
+	t.once.Do(func() {
+		if t.r == nil {
+			if t.file, t.err = os.Open(t.path); t.err != nil {
+				return
+			}
+			t.r = t.file
+		}
+		t.err = t.read()
+	})
+	return t.err
+}
+
+func (t *table) close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
+}
+
+// reader reads a table file from the start, remembering the first error.
+type reader struct {
+	r   io.ReaderAt
+	off int64
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	b := make([]byte, n)
+	if r.err == nil && n > 0 {
+		if read, err := r.r.ReadAt(b, r.off); read < n {
+			r.err = err
+			if err == io.EOF {
+				r.err = ErrCorrupt
+			}
+		}
+	}
+	r.off += int64(n)
+	return b
+}
+
+func (r *reader) byte() int {
+	return int(r.bytes(1)[0])
+}
+
+func (r *reader) uint16() int {
+	return int(binary.LittleEndian.Uint16(r.bytes(2)))
+}
+
+func (r *reader) uint32() int {
+	return int(binary.LittleEndian.Uint32(r.bytes(4)))
+}
+
+func (r *reader) align(n int64) {
+	r.off = (r.off + n - 1) &^ (n - 1)
+}
+
+// read reads everything but the compressed values from the table file.
+func (t *table) read() error {
+	r := &reader{r: t.r}
+	if magic := r.bytes(4); r.err == nil && string(magic) != string(magics[t.kind][:]) {
+		return ErrCorrupt
+	}
+	if flags := r.byte(); r.err == nil && flags&2 != 0 != t.hasPawns {
+		return ErrCorrupt
+	}
+	files := 1
+	if t.hasPawns {
+		files = 4
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	for f := 0; f < files; f++ {
+		b, o := r.byte(), 0xFF
+		if pp {
+			o = r.byte()
+		}
+		order := [2][2]int{{b & 0xF, o & 0xF}, {b >> 4, o >> 4}}
+		for k := 0; k < t.pieceCount; k++ {
+			b := r.byte()
+			for i := 0; i < t.sides(); i++ {
+				t.get(i, f).pieces[k] = b >> (4 * i) & 0xF
+			}
+		}
+		for i := 0; i < t.sides(); i++ {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+	r.align(2)
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides(); i++ {
+			if err := r.sizes(t.get(i, f)); err != nil {
+				return err
+			}
+		}
+	}
+	if t.kind == dtz {
+		t.readMap(r, files)
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides(); i++ {
+			d := t.get(i, f)
+			d.sparseIndex = r.bytes(6 * d.sparseIndexSize)
+		}
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides(); i++ {
+			d := t.get(i, f)
+			d.blockLength = r.bytes(2 * d.blockLengthSize)
+		}
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < t.sides(); i++ {
+			d := t.get(i, f)
+			r.align(64)
+			d.data = r.off
+			r.off += int64(d.numBlocks) * int64(d.blockSize)
+		}
+	}
+	return r.err
+}
+
+// groups returns the number of groups.
+func (d *pairs) groups() int {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	return n
+}
+
+// setGroups splits the pieces into groups and works out the factor of each
+// group in the index. The first group is the leading pawns, or two or three
+// pieces including the kings, and the rest are pieces of the same kind.
+// order holds where the first group, and the other pawns, are in the index.
+func (t *table) setGroups(d *pairs, order [2]int, file int) {
+	n, firstLen := 0, 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next, free := 1, 64-d.groupLen[0]
+	if pp {
+		next, free = 2, free-d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][free]
+			free -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// sizes reads the sizes of the compressed data and the Huffman code.
+func (r *reader) sizes(d *pairs) error {
+	d.flags = r.byte()
+	if d.flags&flagSingleValue != 0 {
+		d.value = r.byte()
+		return r.err
+	}
+	d.blockSize = 1 << uint(r.byte())
+	d.span = 1 << uint(r.byte())
+	d.sparseIndexSize = int((d.groupIdx[d.groups()] + d.span - 1) / d.span)
+	padding := r.byte()
+	d.numBlocks = r.uint32()
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = r.byte()
+	d.minSymLen = r.byte()
+	if r.err != nil {
+		return r.err
+	}
+	if d.minSymLen < 1 || d.maxSymLen < d.minSymLen || d.maxSymLen > 32 {
+		return ErrCorrupt
+	}
+	n := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = make([]uint16, n)
+	raw := r.bytes(2 * n)
+	for i := range d.lowestSym {
+		d.lowestSym[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+	d.setCode()
+	symbols := r.uint16()
+	d.btree = r.bytes(3 * symbols)
+	r.off += int64(symbols & 1)
+	if r.err != nil {
+		return r.err
+	}
+	return d.setSymlen()
+}
+
+// setCode works out base64, where base64[l] is the lowest code of length
+// minSymLen + l padded to 64 bits. Longer codes have lower values.
+func (d *pairs) setCode() {
+	n := len(d.lowestSym)
+	d.base64 = make([]uint64, n)
+	for i := n - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowestSym[i]) - uint64(d.lowestSym[i+1])) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+}
+
+// setSymlen works out how many values each symbol expands to.
+func (d *pairs) setSymlen() error {
+	symbols := len(d.btree) / 3
+	d.symlen = make([]int, symbols)
+	visited := make([]bool, symbols)
+	var set func(sym int) error
+	set = func(sym int) error {
+		visited[sym] = true
+		right := d.right(sym)
+		if right == 0xFFF {
+			return nil
+		}
+		left := d.left(sym)
+		if left >= symbols || right >= symbols {
+			return ErrCorrupt
+		}
+		for _, s := range []int{left, right} {
+			if !visited[s] {
+				if err := set(s); err != nil {
+					return err
+				}
+			}
+		}
+		d.symlen[sym] = d.symlen[left] + d.symlen[right] + 1
+		return nil
+	}
+	for sym := range d.symlen {
+		if !visited[sym] {
+			if err := set(sym); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readMap reads the maps from stored DTZ values to real ones.
+func (t *table) readMap(r *reader, files int) {
+	start := r.off
+	for f := 0; f < files; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		if d.flags&flagWide != 0 {
+			r.align(2)
+			for i := range d.mapIdx {
+				d.mapIdx[i] = int(r.off-start)/2 + 1
+				r.off += 2 * int64(r.uint16())
+			}
+		} else {
+			for i := range d.mapIdx {
+				d.mapIdx[i] = int(r.off-start) + 1
+				r.off += int64(r.byte())
+			}
+		}
+	}
+	r.align(2)
+	end := r.off
+	r.off = start
+	t.dtzMap = r.bytes(int(end - start))
+}
+
+// decompress returns the value stored at idx.
+func (t *table) decompress(d *pairs, idx uint64) (int, error) {
+	if d.flags&flagSingleValue != 0 {
+		return d.value, nil
+	}
+	k := idx / d.span
+	if int(6*k+6) > len(d.sparseIndex) {
+		return 0, ErrCorrupt
+	}
+	block := int(binary.LittleEndian.Uint32(d.sparseIndex[6*k:]))
+	offset := int(binary.LittleEndian.Uint16(d.sparseIndex[6*k+4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+	blocks := len(d.blockLength) / 2
+	for offset < 0 && block > 0 {
+		block--
+		offset += d.blockLen(block) + 1
+	}
+	for block < blocks && offset > d.blockLen(block) {
+		offset -= d.blockLen(block) + 1
+		block++
+	}
+	if offset < 0 || block >= blocks {
+		return 0, ErrCorrupt
+	}
+
+	buf := make([]byte, d.blockSize+8)
+	if n, err := t.r.ReadAt(buf[:d.blockSize], d.data+int64(block)*int64(d.blockSize)); n == 0 && err != nil {
+		return 0, err
+	}
+	buf64, next, size := binary.BigEndian.Uint64(buf), 8, 64
+	var sym int
+	for {
+		l := 0
+		for l < len(d.base64)-1 && buf64 < d.base64[l] {
+			l++
+		}
+		sym = int(uint16((buf64-d.base64[l])>>uint(64-l-d.minSymLen)) + d.lowestSym[l])
+		if sym >= len(d.symlen) {
+			return 0, ErrCorrupt
+		}
+		if offset <= d.symlen[sym] {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf64 <<= uint(l)
+		size -= l
+		if size <= 32 {
+			if next+4 > len(buf) {
+				return 0, ErrCorrupt
+			}
+			size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(buf[next:])) << uint(64-size)
+			next += 4
+		}
+	}
+	for d.symlen[sym] != 0 {
+		left := d.left(sym)
+		if offset <= d.symlen[left] {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = d.right(sym)
+		}
+	}
+	return d.left(sym), nil
+}
+
+// encode returns the index of the pieces on the squares. The leading pawns
+// come first. The squares are flipped so that white is the stronger side.
+func (t *table) encode(d *pairs, squares, pieces []int, leadPawns int) uint64 {
+	// Order the pieces as the table does.
+	for i := leadPawns; i < len(pieces)-1; i++ {
+		for j := i + 1; j < len(pieces); j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+	// Mirror the board to have the leading piece on files a-d.
+	if squares[0]&7 > 3 {
+		for i := range squares {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawns][squares[0]]
+		others := squares[1:leadPawns]
+		sort.SliceStable(others, func(i, j int) bool { return mapPawns[others[i]] < mapPawns[others[j]] })
+		for i := 1; i < leadPawns; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		// Without pawns the board is also flipped to have the leading piece
+		// on ranks 1-4, and below the a1-h8 diagonal.
+		if squares[0]>>3 > 3 {
+			for i := range squares {
+				squares[i] ^= 56
+			}
+		}
+		for i := 0; i < d.groupLen[0]; i++ {
+			if off := offDiagonal(squares[i]); off != 0 {
+				if off > 0 {
+					for j := i; j < len(squares); j++ {
+						squares[j] = (squares[j]>>3 | squares[j]<<3) & 63
+					}
+				}
+				break
+			}
+		}
+		idx = encodeLeaders(t.hasUniquePieces, squares)
+	}
+	idx *= d.groupIdx[0]
+
+	// The other groups are encoded in ascending order of squares, skipping
+	// the squares taken by earlier groups.
+	start := d.groupLen[0]
+	otherPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+		var n uint64
+		for i, s := range group {
+			adjust := 0
+			for _, taken := range squares[:start] {
+				if s > taken {
+					adjust++
+				}
+			}
+			if otherPawns {
+				adjust += 8
+			}
+			n += binomial[i+1][s-adjust]
+		}
+		otherPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+	return idx
+}
+
+// encodeLeaders encodes the leading pieces of a table without pawns: the
+// kings, and a third piece when the table has unique pieces.
+func encodeLeaders(unique bool, sq []int) uint64 {
+	if !unique {
+		return uint64(mapKK[mapA1D1D4[sq[0]]][sq[1]])
+	}
+	adjust1, adjust2 := 0, 0
+	if sq[1] > sq[0] {
+		adjust1 = 1
+	}
+	if sq[2] > sq[0] {
+		adjust2++
+	}
+	if sq[2] > sq[1] {
+		adjust2++
+	}
+	var idx int
+	switch {
+	case offDiagonal(sq[0]) != 0:
+		idx = (mapA1D1D4[sq[0]]*63+sq[1]-adjust1)*62 + sq[2] - adjust2
+	case offDiagonal(sq[1]) != 0:
+		idx = (6*63+(sq[0]>>3)*28+mapB1H1H7[sq[1]])*62 + sq[2] - adjust2
+	case offDiagonal(sq[2]) != 0:
+		idx = 6*63*62 + 4*28*62 + (sq[0]>>3)*7*28 + (sq[1]>>3-adjust1)*28 + mapB1H1H7[sq[2]]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + (sq[0]>>3)*7*6 + (sq[1]>>3-adjust1)*6 + sq[2]>>3 - adjust2
+	}
+	return uint64(idx)
+}
+
+// mapDTZ turns a value stored in a DTZ table into plies to a zeroing move,
+// for a position known to have the given result.
+func (t *table) mapDTZ(file, value int, w WDL) int {
+	d := t.get(0, file)
+	if d.flags&flagMapped != 0 {
+		i := d.mapIdx[[...]int{1, 3, 0, 2, 0}[w+2]] + value
+		if d.flags&flagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(t.dtzMap[2*i:]))
+		} else {
+			value = int(t.dtzMap[i])
+		}
+	}
+	if w == Win && d.flags&flagWinPlies == 0 || w == Loss && d.flags&flagLossPlies == 0 || w == CursedWin || w == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
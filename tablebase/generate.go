@@ -0,0 +1,370 @@
+package tablebase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/board"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+// broken marks the entries of a table that are not positions, because
+// pieces share a square, a pawn is on the first or last rank, the side not
+// to move is in check, or a symmetry of the position is stored instead.
+// Other entries hold zero for a draw, or one more than the number of plies
+// to mate, which is even when the side to move gets mated.
+const broken = 255
+
+// table holds the distance to mate of every position of some material, for
+// both sides to move.
+type table struct {
+	key string
+	// pieces are in the order of the squares of an index: the kings first,
+	// then the other white pieces, then the other black ones.
+	pieces []piece.Piece
+	// symmetries map a square to its image on a reflected board. Positions
+	// are stored once for all of their reflections.
+	symmetries []func(square.Square) square.Square
+	// kings are the squares the white king can be on in a stored position.
+	kings []square.Square
+	// slots are the other way around, and -1 for the rest of the squares.
+	slots [64]int
+	dtm   [2][]byte
+}
+
+// newTable returns an empty table of the material key.
+func newTable(key string) *table {
+	t := &table{key: key}
+	sides := strings.Split(key, "v")
+	t.pieces = []piece.Piece{piece.New(piece.White, piece.King), piece.New(piece.Black, piece.King)}
+	for c, side := range sides {
+		for _, letter := range side[1:] {
+			for _, pt := range pieceTypes {
+				if strings.ToUpper(pt.String()) == string(letter) {
+					t.pieces = append(t.pieces, piece.New(piece.Color(c), pt))
+				}
+			}
+		}
+	}
+
+	// Pawns only move one way, so boards with them can only be mirrored
+	// from side to side.
+	flips := []square.Square{0, 7, 56, 63}
+	if strings.Contains(key, "P") {
+		flips = flips[:2]
+	}
+	for _, diagonal := range []bool{false, true} {
+		for _, flip := range flips {
+			diagonal, flip := diagonal, flip
+			t.symmetries = append(t.symmetries, func(s square.Square) square.Square {
+				if diagonal {
+					s = (s&7)<<3 | s>>3
+				}
+				return s ^ flip
+			})
+		}
+		if strings.Contains(key, "P") {
+			break
+		}
+	}
+
+	for s := square.Square(0); s < 64; s++ {
+		t.slots[s] = -1
+		lowest := s
+		for _, sym := range t.symmetries {
+			if sym(s) < lowest {
+				lowest = sym(s)
+			}
+		}
+		if lowest == s {
+			t.slots[s] = len(t.kings)
+			t.kings = append(t.kings, s)
+		}
+	}
+	return t
+}
+
+// size returns the number of entries for each side to move.
+func (t *table) size() int {
+	return len(t.kings) << (6 * (len(t.pieces) - 1))
+}
+
+// squares returns the squares of the pieces of an index.
+func (t *table) squares(i int) []square.Square {
+	sq := make([]square.Square, len(t.pieces))
+	for k := len(sq) - 1; k > 0; k-- {
+		sq[k] = square.Square(i & 63)
+		i >>= 6
+	}
+	sq[0] = t.kings[i]
+	return sq
+}
+
+// index returns where the position with the pieces on the squares is stored:
+// at the reflection, with pieces of the same kind sorted, that has the
+// lowest squares.
+func (t *table) index(sq []square.Square) int {
+	n := len(sq)
+	var best, reflected [maxPieces]square.Square
+	for i, sym := range t.symmetries {
+		for k, s := range sq {
+			reflected[k] = sym(s)
+			for j := k; j > 0 && t.pieces[j] == t.pieces[j-1] && reflected[j] < reflected[j-1]; j-- {
+				reflected[j], reflected[j-1] = reflected[j-1], reflected[j]
+			}
+		}
+		if i == 0 || lower(reflected[:n], best[:n]) {
+			best = reflected
+		}
+	}
+	i := t.slots[best[0]]
+	for _, s := range best[1:n] {
+		i = i<<6 | int(s)
+	}
+	return i
+}
+
+// squaresOf returns the squares of the pieces of a position that has the
+// material of the table. When flip is set the colors are swapped, and the
+// board turned upside down.
+func (t *table) squaresOf(p *position.Position, flip bool) []square.Square {
+	sq := make([]square.Square, 0, len(t.pieces))
+	for k, pc := range t.pieces {
+		if k > 0 && pc == t.pieces[k-1] {
+			continue
+		}
+		c := pc.Color
+		if flip {
+			c = other(c)
+		}
+		for _, s := range p.Pieces(c, pc.Type).Squares() {
+			if flip {
+				s ^= 56
+			}
+			sq = append(sq, s)
+		}
+	}
+	return sq
+}
+
+// position sets the pieces up on an empty board.
+func (t *table) position(sq []square.Square, c piece.Color) *position.Position {
+	p := position.New()
+	p.Clear()
+	p.CastlingRights = map[piece.Color]map[board.Side]bool{piece.White: {}, piece.Black: {}}
+	for k, pc := range t.pieces {
+		p.QuickPut(pc, sq[k])
+	}
+	p.ActiveColor = c
+	return p
+}
+
+// valid reports whether the squares are those of a stored position, without
+// looking at check.
+func (t *table) valid(i int, sq []square.Square) bool {
+	var occupied position.BitBoard
+	for k, s := range sq {
+		if occupied.Has(s) || t.pieces[k].Type == piece.Pawn && (s < 8 || s >= 56) {
+			return false
+		}
+		occupied = occupied.Set(s)
+	}
+	return t.index(sq) == i
+}
+
+// generate fills the table in by retrograde analysis. Mates are found
+// first, and then the positions one ply further from mate each time round:
+// a position is won when one of its moves leads to a lost position, and lost
+// once all of its moves lead to won ones. Whatever is left is drawn.
+func (tb *Tablebase) generate(t *table) error {
+	size := t.size()
+	var counts, floors [2][]byte
+	for _, c := range piece.Colors {
+		t.dtm[c] = make([]byte, size)
+		counts[c] = make([]byte, size)
+		floors[c] = make([]byte, size)
+	}
+	// pending holds the positions found at each distance to mate, coded as
+	// twice the index plus the side to move.
+	var pending [][]int
+	push := func(plies int, c piece.Color, i int) {
+		for len(pending) <= plies {
+			pending = append(pending, nil)
+		}
+		pending[plies] = append(pending[plies], i<<1|int(c))
+	}
+
+	for i := 0; i < size; i++ {
+		sq := t.squares(i)
+		if !t.valid(i, sq) {
+			t.dtm[piece.White][i], t.dtm[piece.Black][i] = broken, broken
+			continue
+		}
+		for _, c := range piece.Colors {
+			p := t.position(sq, c)
+			if p.Check(other(c)) {
+				t.dtm[c][i] = broken
+				continue
+			}
+			moves := p.LegalMoves()
+			if len(moves) == 0 && p.Check(c) {
+				push(0, c, i)
+			}
+			// Moves that capture or promote leave the table, and are
+			// looked up in the tables of the new material. A move to a
+			// drawn or lost position there means this one is not lost.
+			var next []int
+			for m := range moves {
+				if p.OnSquare(m.To()).Type != piece.None || m.Promote != piece.None {
+					w, plies, err := tb.probe(p.MakeMove(m))
+					if err != nil {
+						return err
+					}
+					switch w {
+					case Loss:
+						push(plies+1, c, i)
+						counts[c][i] = 1
+					case Draw:
+						counts[c][i] = 1
+					case Win:
+						if plies+1 > int(floors[c][i]) {
+							floors[c][i] = byte(plies + 1)
+						}
+					}
+					continue
+				}
+				j := t.index(t.moved(sq, m))
+				if !contains(next, j) {
+					next = append(next, j)
+				}
+			}
+			counts[c][i] += byte(len(next))
+			if len(moves) > 0 && counts[c][i] == 0 {
+				push(int(floors[c][i]), c, i)
+			}
+		}
+	}
+
+	for plies := 0; plies < len(pending); plies++ {
+		if plies+1 >= broken {
+			return fmt.Errorf("tablebase: %s has mates too long to store", t.key)
+		}
+		for _, code := range pending[plies] {
+			c, i := piece.Color(code&1), code>>1
+			if t.dtm[c][i] != 0 {
+				continue
+			}
+			t.dtm[c][i] = byte(plies + 1)
+			o := other(c)
+			for _, j := range t.predecessors(i, c) {
+				switch {
+				case t.dtm[o][j] != 0:
+				case plies%2 == 0:
+					push(plies+1, o, j)
+				case counts[o][j] == 0:
+					f, _ := fen.Encode(t.position(t.squares(j), o))
+					return fmt.Errorf("tablebase: the legal moves of %s are missing a move", f)
+				default:
+					counts[o][j]--
+					if counts[o][j] == 0 {
+						push(max(plies+1, int(floors[o][j])), o, j)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// moved returns the squares of the pieces after a move that neither captures
+// nor promotes.
+func (t *table) moved(sq []square.Square, m move.Move) []square.Square {
+	after := append([]square.Square(nil), sq...)
+	for k, s := range sq {
+		if s == m.From() {
+			after[k] = m.To()
+		}
+	}
+	return after
+}
+
+// predecessors returns the stored positions, with the other side to move,
+// that have a move to the position of the index that does not capture or
+// promote. Pieces move back to the empty squares they attack, and pawns
+// step back.
+func (t *table) predecessors(i int, c piece.Color) []int {
+	sq := t.squares(i)
+	p := t.position(sq, c)
+	empty := ^p.Occupied(piece.BothColors)
+	mover := other(c)
+	var found []int
+	for k, pc := range t.pieces {
+		if pc.Color != mover {
+			continue
+		}
+		var from position.BitBoard
+		if pc.Type == piece.Pawn {
+			from = pawnRetreats(sq[k], mover, empty)
+		} else {
+			from = p.Attacks(sq[k]) & empty
+		}
+		for _, s := range from.Squares() {
+			before := append([]square.Square(nil), sq...)
+			before[k] = s
+			j := t.index(before)
+			if t.dtm[mover][j] != broken && !contains(found, j) {
+				found = append(found, j)
+			}
+		}
+	}
+	return found
+}
+
+// pawnRetreats returns the squares a pawn of color c could have stepped to s
+// from.
+func pawnRetreats(s square.Square, c piece.Color, empty position.BitBoard) position.BitBoard {
+	b := position.NewBitBoard(s)
+	var from position.BitBoard
+	if c == piece.White {
+		from = b.South() & empty &^ position.RankMask(square.H1)
+		if s/8 == 3 {
+			from |= from.South() & empty
+		}
+	} else {
+		from = b.North() & empty &^ position.RankMask(square.H8)
+		if s/8 == 4 {
+			from |= from.North() & empty
+		}
+	}
+	return from
+}
+
+// lower reports whether the squares come first in index order.
+func lower(a, b []square.Square) bool {
+	for k := range a {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
+
+func contains(indexes []int, i int) bool {
+	for _, j := range indexes {
+		if j == i {
+			return true
+		}
+	}
+	return false
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
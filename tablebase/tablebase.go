@@ -0,0 +1,409 @@
+// Package tablebase generates distance to mate tables for endgames with few
+// pieces, by retrograde analysis with the moves of the position package, and
+// probes them for perfect play:
+//
+//	tb, err := tablebase.Open("/path/to/tables")
+//	...
+//	err = tb.Generate("KRvK")
+//	w, plies, err := tb.Probe(p)
+//	best, err := tb.BestMove(p)
+//
+// Generating a table also generates the tables its captures and promotions
+// lead to. Tables are kept in memory, and written to the directory as .dtm
+// files so that they only have to be generated once.
+//
+// The analysis doubles as a check of the move generator: every position is
+// reached backwards from the squares its pieces attack, and forwards from
+// its legal moves, and Generate fails when the two disagree.
+package tablebase
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+var (
+	// ErrNoTable is returned when the table for a position has not been
+	// generated.
+	ErrNoTable = errors.New("tablebase: no table for the position")
+	// ErrMaterial is returned when asked to generate a table for material
+	// that is not like KQvK, has more than four pieces, or has pawns on both
+	// sides, which would need en passant to be tracked.
+	ErrMaterial = errors.New("tablebase: can not generate a table for the material")
+	// ErrCastling is returned for positions with castling rights, which are
+	// not in the tables.
+	ErrCastling = errors.New("tablebase: the tables have no positions with castling rights")
+	// ErrIllegal is returned for positions where the side to move can
+	// capture the king.
+	ErrIllegal = errors.New("tablebase: the side not to move is in check")
+	// ErrNoMoves is returned when looking for the best move of a position
+	// that is checkmate or stalemate.
+	ErrNoMoves = errors.New("tablebase: the position has no legal moves")
+	// ErrCorrupt is returned when a table file can not be read.
+	ErrCorrupt = errors.New("tablebase: corrupt table")
+)
+
+// maxPieces is the most pieces, kings included, of a table.
+const maxPieces = 4
+
+// WDL is the result of a position for the side to move.
+type WDL int
+
+// Possible results.
+const (
+	Loss WDL = iota - 1
+	Draw
+	Win
+)
+
+func (w WDL) String() string {
+	return map[WDL]string{
+		Loss: "loss",
+		Draw: "draw",
+		Win:  "win",
+	}[w]
+}
+
+// Tablebase is a set of distance to mate tables.
+type Tablebase struct {
+	dir string
+	mu  sync.Mutex
+	// tables are keyed on their material, with the stronger side first.
+	tables map[string]*table
+}
+
+// Open returns a tablebase that keeps its tables in a directory. Tables that
+// are already there are read when a position needs them. With an empty dir
+// the tables are only kept in memory.
+func Open(dir string) (*Tablebase, error) {
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: dir, Err: errors.New("not a directory")}
+		}
+	}
+	return &Tablebase{dir: dir, tables: map[string]*table{}}, nil
+}
+
+// Generate makes the table for the material, written like KBNvK or KBNK, and
+// every table that it needs and the tablebase does not have yet. Material
+// where neither side can mate needs no table.
+func (tb *Tablebase) Generate(material string) error {
+	key, err := parseMaterial(material)
+	if err != nil {
+		return err
+	}
+	if key, _ = tableKey(key); dead(key) {
+		return nil
+	}
+	if _, err := tb.lookup(key); err != ErrNoTable {
+		return err
+	}
+	for _, sub := range subMaterials(key) {
+		if err := tb.Generate(sub); err != nil {
+			return err
+		}
+	}
+	t := newTable(key)
+	if err := tb.generate(t); err != nil {
+		return err
+	}
+	if tb.dir != "" {
+		if err := t.save(tb.path(key)); err != nil {
+			return err
+		}
+	}
+	tb.mu.Lock()
+	tb.tables[key] = t
+	tb.mu.Unlock()
+	return nil
+}
+
+// Probe returns the result of the position for the side to move, and how
+// many plies it takes to mate with best play. The plies are zero for draws,
+// and for positions that are already checkmate.
+func (tb *Tablebase) Probe(p *position.Position) (WDL, int, error) {
+	if err := probeable(p); err != nil {
+		return Draw, 0, err
+	}
+	return tb.probe(p)
+}
+
+// Result is what the tables tell about a move.
+type Result struct {
+	Move move.Move
+	// WDL is the result of the move for the side making it, and DTM is how
+	// many plies, counting the move, it takes to mate.
+	WDL WDL
+	DTM int
+}
+
+// Moves returns every legal move of the position, best first: the quickest
+// wins, then the draws, then the slowest losses.
+func (tb *Tablebase) Moves(p *position.Position) ([]Result, error) {
+	if err := probeable(p); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for m := range p.LegalMoves() {
+		w, plies, err := tb.probe(p.MakeMove(m))
+		if err != nil {
+			return nil, err
+		}
+		r := Result{Move: m, WDL: -w}
+		if w != Draw {
+			r.DTM = plies + 1
+		}
+		results = append(results, r)
+	}
+	rank := func(r Result) int {
+		return int(r.WDL)*1000 - int(r.WDL)*r.DTM
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if rank(a) != rank(b) {
+			return rank(a) > rank(b)
+		}
+		return a.Move.String() < b.Move.String()
+	})
+	return results, nil
+}
+
+// BestMove returns the best move of the position: the quickest win, or else
+// a draw, or else the slowest loss.
+func (tb *Tablebase) BestMove(p *position.Position) (Result, error) {
+	results, err := tb.Moves(p)
+	if err == nil && len(results) == 0 {
+		err = ErrNoMoves
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+// probeable checks that the position can be in the tables.
+func probeable(p *position.Position) error {
+	for _, sides := range p.CastlingRights {
+		for _, ok := range sides {
+			if ok {
+				return ErrCastling
+			}
+		}
+	}
+	if p.Occupied(piece.BothColors).Count() > maxPieces {
+		return ErrNoTable
+	}
+	return nil
+}
+
+// probe looks the position up in its table.
+func (tb *Tablebase) probe(p *position.Position) (WDL, int, error) {
+	if p.DeadPosition() {
+		return Draw, 0, nil
+	}
+	key, flip := tableKey(materialKey(p))
+	t, err := tb.lookup(key)
+	if err != nil {
+		return Draw, 0, err
+	}
+	i := t.index(t.squaresOf(p, flip))
+	stm := p.ActiveColor
+	if flip {
+		stm = other(stm)
+	}
+	v := t.dtm[stm][i]
+	switch {
+	case v == broken:
+		return Draw, 0, ErrIllegal
+	case v == 0:
+		return Draw, 0, nil
+	case v%2 == 0:
+		return Win, int(v) - 1, nil
+	}
+	return Loss, int(v) - 1, nil
+}
+
+// lookup returns the table of the material, reading it from the directory
+// the first time.
+func (tb *Tablebase) lookup(key string) (*table, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if t := tb.tables[key]; t != nil {
+		return t, nil
+	}
+	if tb.dir == "" {
+		return nil, ErrNoTable
+	}
+	t := newTable(key)
+	if err := t.load(tb.path(key)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoTable
+		}
+		return nil, err
+	}
+	tb.tables[key] = t
+	return t, nil
+}
+
+func (tb *Tablebase) path(key string) string {
+	return filepath.Join(tb.dir, key+".dtm")
+}
+
+// pieceTypes are the pieces other than the king in the order they are
+// written in material keys.
+var pieceTypes = []piece.Type{piece.Queen, piece.Rook, piece.Bishop, piece.Knight, piece.Pawn}
+
+// materialKey returns the material of the position, like KRvKN.
+func materialKey(p *position.Position) string {
+	var sides [2]string
+	for _, c := range piece.Colors {
+		sides[c] = "K"
+		for _, t := range pieceTypes {
+			sides[c] += strings.Repeat(strings.ToUpper(t.String()), p.Pieces(c, t).Count())
+		}
+	}
+	return sides[0] + "v" + sides[1]
+}
+
+// parseMaterial turns material like KBNvK, KBNK or KvKNB into a material key
+// with the pieces of each side in order.
+func parseMaterial(material string) (string, error) {
+	material = strings.ToUpper(material)
+	sides := strings.Split(material, "V")
+	if len(sides) == 1 && strings.Count(material, "K") == 2 {
+		i := strings.LastIndex(material, "K")
+		sides = []string{material[:i], material[i:]}
+	}
+	if len(sides) != 2 || len(sides[0])+len(sides[1]) > maxPieces {
+		return "", ErrMaterial
+	}
+	for i, side := range sides {
+		if !strings.HasPrefix(side, "K") || strings.Trim(side[1:], "QRBNP") != "" {
+			return "", ErrMaterial
+		}
+		sorted := "K"
+		for _, t := range pieceTypes {
+			letter := strings.ToUpper(t.String())
+			sorted += strings.Repeat(letter, strings.Count(side, letter))
+		}
+		sides[i] = sorted
+	}
+	if strings.Contains(sides[0], "P") && strings.Contains(sides[1], "P") {
+		return "", ErrMaterial
+	}
+	return sides[0] + "v" + sides[1], nil
+}
+
+// tableKey returns the key of the table that holds the material, which has
+// the stronger side first, and whether the sides had to be swapped.
+func tableKey(key string) (string, bool) {
+	sides := strings.Split(key, "v")
+	if stronger(sides[1], sides[0]) {
+		return sides[1] + "v" + sides[0], true
+	}
+	return key, false
+}
+
+// stronger reports whether the pieces of side a are worth more than those of
+// side b, breaking ties by name.
+func stronger(a, b string) bool {
+	worth := func(side string) int {
+		n := 0
+		for _, letter := range side {
+			n += map[rune]int{'Q': 9, 'R': 5, 'B': 3, 'N': 3, 'P': 1}[letter]
+		}
+		return n
+	}
+	if worth(a) != worth(b) {
+		return worth(a) > worth(b)
+	}
+	return a > b
+}
+
+// dead reports whether neither side can ever mate with the material.
+func dead(key string) bool {
+	return strings.Trim(key, "KvBN") == "" && len(key) <= 4
+}
+
+// subMaterials returns the material that captures and promotions lead to.
+func subMaterials(key string) []string {
+	var subs []string
+	sides := strings.Split(key, "v")
+	for s, side := range sides {
+		for i := 1; i < len(side); i++ {
+			changed := []string{side[:i] + side[i+1:]}
+			if side[i] == 'P' {
+				for _, letter := range "QRBN" {
+					changed = append(changed, side[:i]+string(letter)+side[i+1:])
+				}
+			}
+			for _, c := range changed {
+				sub := []string{sides[0], sides[1]}
+				sub[s] = c
+				k, _ := parseMaterial(sub[0] + "v" + sub[1])
+				subs = append(subs, k)
+			}
+		}
+	}
+	return subs
+}
+
+// other returns the opponent of the color.
+func other(c piece.Color) piece.Color {
+	return []piece.Color{piece.Black, piece.White}[c]
+}
+
+// magic starts every table file, after it is uncompressed.
+var magic = []byte("DTM\x01")
+
+// save writes the table to a gzipped file.
+func (t *table) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	z := gzip.NewWriter(f)
+	z.Write(magic)
+	z.Write(t.dtm[piece.White])
+	z.Write(t.dtm[piece.Black])
+	if err := z.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// load reads the table from a file written by save.
+func (t *table) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		return ErrCorrupt
+	}
+	data, err := io.ReadAll(z)
+	if err != nil || !bytes.HasPrefix(data, magic) || len(data) != len(magic)+2*t.size() {
+		return ErrCorrupt
+	}
+	data = data[len(magic):]
+	t.dtm[piece.White], t.dtm[piece.Black] = data[:t.size()], data[t.size():]
+	return nil
+}
@@ -0,0 +1,252 @@
+package tablebase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/position/square"
+)
+
+func decode(t *testing.T, f string) *position.Position {
+	p, err := fen.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func generate(t *testing.T, dir, material string) *Tablebase {
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Generate(material); err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+// longest returns the most plies to mate with white to move.
+func longest(tb *Tablebase, key string) int {
+	most := 0
+	for _, v := range tb.tables[key].dtm[piece.White] {
+		if v != broken && int(v)-1 > most {
+			most = int(v) - 1
+		}
+	}
+	return most
+}
+
+type probe struct {
+	fen   string
+	wdl   WDL
+	plies int
+}
+
+func (want probe) check(t *testing.T, tb *Tablebase) {
+	w, plies, err := tb.Probe(decode(t, want.fen))
+	if err != nil || w != want.wdl || plies != want.plies {
+		t.Errorf("%s: got %v in %d (%v), want %v in %d", want.fen, w, plies, err, want.wdl, want.plies)
+	}
+}
+
+func TestMaterial(t *testing.T) {
+	for material, want := range map[string]string{
+		"KQvK":   "KQvK",
+		"KBNK":   "KBNvK",
+		"kvknb":  "KvKBN",
+		"KQRBvK": "",
+		"KPvKP":  "",
+		"KQ":     "",
+		"KXvK":   "",
+	} {
+		got, err := parseMaterial(material)
+		if got != want || (err == nil) != (want != "") {
+			t.Errorf("parseMaterial(%q) = %q, %v", material, got, err)
+		}
+	}
+	if key, flip := tableKey("KvKBN"); key != "KBNvK" || !flip {
+		t.Error("got", key, flip)
+	}
+	if key, flip := tableKey("KRvKB"); key != "KRvKB" || flip {
+		t.Error("got", key, flip)
+	}
+	subs := subMaterials("KRvKP")
+	want := []string{"KvKP", "KRvK", "KRvKQ", "KRvKR", "KRvKB", "KRvKN"}
+	if len(subs) != len(want) {
+		t.Fatal("got", subs)
+	}
+	for i := range want {
+		if subs[i] != want[i] {
+			t.Error("got", subs, "want", want)
+		}
+	}
+	if !dead("KBvK") || !dead("KvKN") || dead("KNvKN") || dead("KPvK") {
+		t.Error("dead material is wrong")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for key, kings := range map[string]int{"KQvK": 10, "KNNvK": 10, "KPvK": 32} {
+		tb := newTable(key)
+		if len(tb.kings) != kings {
+			t.Errorf("%s has %d king squares, want %d", key, len(tb.kings), kings)
+		}
+		sq := []square.Square{square.B2, square.G7, square.C6, square.E3}[:len(tb.pieces)]
+		i := tb.index(sq)
+		for _, sym := range tb.symmetries {
+			reflected := make([]square.Square, len(sq))
+			for k, s := range sq {
+				reflected[k] = sym(s)
+			}
+			if j := tb.index(reflected); j != i {
+				t.Errorf("%s: %v is stored at %d, and %v at %d", key, sq, i, reflected, j)
+			}
+			if got := tb.squares(i); tb.index(got) != i {
+				t.Errorf("%s: %d is not stored at itself", key, i)
+			}
+		}
+	}
+	tb := newTable("KNNvK")
+	a := tb.index([]square.Square{square.B2, square.G7, square.C6, square.E3})
+	b := tb.index([]square.Square{square.B2, square.G7, square.E3, square.C6})
+	if a != b {
+		t.Error("swapping the knights moves the position from", a, "to", b)
+	}
+}
+
+func TestKQvK(t *testing.T) {
+	tb := generate(t, "", "KQvK")
+	if got := longest(tb, "KQvK"); got != 19 {
+		t.Error("the longest mate takes", got, "plies, want 19")
+	}
+	for _, want := range []probe{
+		{"7k/8/6K1/8/8/8/8/1Q6 w - - 0 1", Win, 1},
+		{"7k/6Q1/5K2/8/8/8/8/8 b - - 0 1", Loss, 0},
+		{"7k/5K2/6Q1/8/8/8/8/8 b - - 0 1", Draw, 0},
+		{"k7/8/8/8/8/8/1q6/2K5 w - - 0 1", Draw, 0},
+	} {
+		want.check(t, tb)
+	}
+
+	// Playing the best move gets one ply closer to mate.
+	for _, f := range []string{"8/8/8/3k4/8/8/7q/K7 b - - 0 1", "8/8/8/3k4/8/8/7q/K7 w - - 0 1", "8/1Q6/8/8/3k4/8/8/K7 b - - 0 1"} {
+		p := decode(t, f)
+		w, plies, err := tb.Probe(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tb.BestMove(p)
+		if err != nil || r.WDL != w || r.DTM != plies {
+			t.Errorf("%s is a %v in %d, and %v is a %v in %d", f, w, plies, r.Move, r.WDL, r.DTM)
+		}
+		w2, plies2, err := tb.Probe(p.MakeMove(r.Move))
+		if err != nil || w2 != -w || plies2 != plies-1 {
+			t.Errorf("%s is a %v in %d, and after %v a %v in %d", f, w, plies, r.Move, w2, plies2)
+		}
+	}
+
+	results, err := tb.Moves(decode(t, "7k/8/6K1/8/8/8/8/1Q6 w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) < 2 || results[0] != (Result{move.Parse("b1b8"), Win, 1}) || results[1].DTM != 3 {
+		t.Error("got", results)
+	}
+	if r, err := tb.BestMove(decode(t, "k7/8/8/8/8/8/1q6/2K5 w - - 0 1")); err != nil || r != (Result{move.Parse("c1b2"), Draw, 0}) {
+		t.Error("got", r, err)
+	}
+	if _, err := tb.BestMove(decode(t, "7k/6Q1/5K2/8/8/8/8/8 b - - 0 1")); err != ErrNoMoves {
+		t.Error("got", err)
+	}
+
+	for f, want := range map[string]error{
+		"7k/8/6K1/8/8/8/8/R7 w - - 0 1":  ErrNoTable,
+		"7k/8/8/8/8/8/8/4K2R w K - 0 1":  ErrCastling,
+		"Q6k/8/6K1/8/8/8/8/8 w - - 0 1":  ErrIllegal,
+		"7k/8/6K1/8/8/8/8/1B6 w - - 0 1": nil,
+	} {
+		if _, _, err := tb.Probe(decode(t, f)); err != want {
+			t.Errorf("%s: got %v, want %v", f, err, want)
+		}
+	}
+}
+
+func TestKRvK(t *testing.T) {
+	dir := t.TempDir()
+	generate(t, dir, "KRK")
+	if _, err := os.Stat(filepath.Join(dir, "KRvK.dtm")); err != nil {
+		t.Fatal(err)
+	}
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe{"7k/8/6K1/8/8/8/8/R7 w - - 0 1", Win, 1}.check(t, tb)
+	probe{"7K/8/6k1/8/8/8/8/r7 b - - 0 1", Win, 1}.check(t, tb)
+	if got := longest(tb, "KRvK"); got != 31 {
+		t.Error("the longest mate takes", got, "plies, want 31")
+	}
+	if err := tb.Generate("KRvK"); err != nil {
+		t.Error(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "KQvK.dtm"), []byte("nonsense"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tb.Probe(decode(t, "7k/8/6K1/8/8/8/8/1Q6 w - - 0 1")); err != ErrCorrupt {
+		t.Error("got", err)
+	}
+	if _, err := Open(filepath.Join(dir, "KRvK.dtm")); err == nil {
+		t.Error("opened a file as a directory")
+	}
+}
+
+func TestKPvK(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generating KPvK is slow")
+	}
+	tb := generate(t, "", "KPvK")
+	for _, want := range []probe{
+		{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", Draw, 0},
+		{"k7/8/1K6/P7/8/8/8/8 w - - 0 1", Draw, 0},
+	} {
+		want.check(t, tb)
+	}
+	for f, want := range map[string]WDL{
+		"4k3/4P3/4K3/8/8/8/8/8 w - - 0 1": Win,
+		"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1": Win,
+		"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1": Loss,
+		"8/8/8/8/4p3/4k3/8/4K3 b - - 0 1": Win,
+		"8/8/8/8/4p3/4k3/8/4K3 w - - 0 1": Loss,
+	} {
+		if w, _, err := tb.Probe(decode(t, f)); w != want || err != nil {
+			t.Errorf("%s: got %v (%v), want %v", f, w, err, want)
+		}
+	}
+	if r, err := tb.BestMove(decode(t, "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1")); err != nil || r.Move != move.Parse("e7e8q") || r.WDL != Win {
+		t.Error("got", r, err)
+	}
+}
+
+func TestKBNvK(t *testing.T) {
+	if strings.ToLower(os.Getenv("TEST_FULL_TABLEBASES")) != "true" || testing.Short() {
+		t.Skip("generating KBNvK takes minutes, set TEST_FULL_TABLEBASES=true to run it")
+	}
+	tb := generate(t, "", "KBNvK")
+	if got := longest(tb, "KBNvK"); got != 65 {
+		t.Error("the longest mate takes", got, "plies, want 65")
+	}
+	for _, want := range []probe{
+		{"7k/4N3/6K1/8/8/8/8/2B5 w - - 0 1", Win, 1},
+		{"8/8/8/8/8/8/1k6/BN2K3 b - - 0 1", Draw, 0},
+	} {
+		want.check(t, tb)
+	}
+}
@@ -0,0 +1,326 @@
+// Package uci serves chess engines written in Go over the UCI protocol, so
+// that they can be used from standard chess GUIs, or played against UCI
+// engines with the engines and match packages.
+//
+// Any type with a Search method like the one of search.Engine can be served:
+//
+//	s := &uci.Server{Name: "My Engine", Author: "Me", Engine: search.New(16)}
+//	s.Run()
+package uci
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/search"
+)
+
+// Searcher finds the best move in a position. It should keep searching until
+// a limit is reached or the context is done, and return the best move found
+// so far either way. With no limits set it should search until the context
+// is done. While pondering the limits to play with arrive on l.PonderHit.
+// Lines of analysis passed to info are sent to the GUI as they are.
+type Searcher interface {
+	Search(ctx context.Context, p *position.Position, l search.Limits, info func(map[string]string)) (*engines.SearchInfo, error)
+}
+
+// Configurable is implemented by searchers that have options the GUI can set.
+type Configurable interface {
+	Options() []engines.UCIOption
+	SetOption(name, value string) error
+}
+
+// Clearer is implemented by searchers that learn between searches. Clear is
+// called when the GUI starts a new game.
+type Clearer interface {
+	Clear()
+}
+
+// Server reads UCI commands and answers them by driving its engine.
+type Server struct {
+	// Name and Author identify the engine to the GUI.
+	Name, Author string
+	Engine       Searcher
+
+	out      *bufio.Writer
+	outMu    sync.Mutex
+	position *position.Position
+	running  *run
+}
+
+// run is a search that was started with "go".
+type run struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// held is closed when the best move no longer has to be held back.
+	held chan struct{}
+	// hit is set while pondering, and limits are what the search goes on
+	// with once the GUI sends ponderhit.
+	hit    chan<- search.Limits
+	limits search.Limits
+}
+
+// Run serves the engine on standard input and output.
+func (s *Server) Run() error {
+	return s.Serve(os.Stdin, os.Stdout)
+}
+
+// Serve reads commands from r and writes the engine's answers to w until it
+// reads "quit" or the end of r. A search still running is stopped before
+// Serve returns.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = bufio.NewWriter(w)
+	s.position = position.New()
+	defer s.stop()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "uci":
+			s.identify()
+		case "isready":
+			s.send("readyok")
+		case "setoption":
+			s.setOption(words[1:])
+		case "ucinewgame":
+			s.stop()
+			if c, ok := s.Engine.(Clearer); ok {
+				c.Clear()
+			}
+			s.position = position.New()
+		case "position":
+			s.stop()
+			s.setPosition(words[1:])
+		case "go":
+			s.stop()
+			s.goSearch(words[1:])
+		case "stop":
+			s.stop()
+		case "ponderhit":
+			s.ponderHit()
+		case "quit":
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// send writes a line to the GUI.
+func (s *Server) send(line string) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.out.WriteString(line + "\n")
+	s.out.Flush()
+}
+
+func (s *Server) identify() {
+	s.send("id name " + s.Name)
+	if s.Author != "" {
+		s.send("id author " + s.Author)
+	}
+	if c, ok := s.Engine.(Configurable); ok {
+		for _, o := range c.Options() {
+			s.send(o.String())
+		}
+	}
+	s.send("uciok")
+}
+
+// setOption handles "setoption name <name> [value <value>]", where both the
+// name and the value may contain spaces.
+func (s *Server) setOption(words []string) {
+	var name, value []string
+	var field *[]string
+	for _, w := range words {
+		switch {
+		case w == "name" && field == nil:
+			field = &name
+		case w == "value" && field == &name:
+			field = &value
+		case field != nil:
+			*field = append(*field, w)
+		}
+	}
+	c, ok := s.Engine.(Configurable)
+	if !ok || len(name) == 0 {
+		return
+	}
+	if err := c.SetOption(strings.Join(name, " "), strings.Join(value, " ")); err != nil {
+		s.send("info string " + err.Error())
+	}
+}
+
+// setPosition handles "position [startpos | fen <fen>] [moves <moves>]".
+func (s *Server) setPosition(words []string) {
+	if len(words) == 0 {
+		return
+	}
+	p := position.New()
+	rest := words[1:]
+	if words[0] == "fen" {
+		end := len(rest)
+		for i, w := range rest {
+			if w == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		if p, err = fen.Decode(strings.Join(rest[:end], " ")); err != nil {
+			s.send("info string " + err.Error())
+			return
+		}
+		rest = rest[end:]
+	}
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, pcn := range rest[1:] {
+			m := move.Parse(pcn)
+			if _, legal := p.LegalMoves()[m]; !legal {
+				s.send("info string illegal move " + pcn)
+				break
+			}
+			p = p.MakeMove(m)
+		}
+	}
+	s.position = p
+}
+
+// goSearch handles "go" and its limits.
+func (s *Server) goSearch(words []string) {
+	var l search.Limits
+	var wtime, btime, winc, binc int
+	var infinite, ponder bool
+	for i := 0; i < len(words); i++ {
+		arg := func() int {
+			if i+1 >= len(words) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(words[i])
+			return n
+		}
+		switch words[i] {
+		case "wtime":
+			wtime = arg()
+		case "btime":
+			btime = arg()
+		case "winc":
+			winc = arg()
+		case "binc":
+			binc = arg()
+		case "movestogo":
+			l.MovesToGo = arg()
+		case "depth":
+			l.Depth = arg()
+		case "nodes":
+			l.Nodes = uint64(arg())
+		case "movetime":
+			l.MoveTime = time.Duration(arg()) * time.Millisecond
+		case "infinite":
+			infinite = true
+		case "ponder":
+			ponder = true
+		}
+	}
+	if s.position.ActiveColor == piece.White {
+		l.Clock, l.Control.Increment = time.Duration(wtime)*time.Millisecond, time.Duration(winc)*time.Millisecond
+	} else {
+		l.Clock, l.Control.Increment = time.Duration(btime)*time.Millisecond, time.Duration(binc)*time.Millisecond
+	}
+	if ponder {
+		hit := make(chan search.Limits, 1)
+		r := s.start(search.Limits{PonderHit: hit}, true)
+		r.hit, r.limits = hit, l
+		return
+	}
+	s.start(l, infinite)
+}
+
+// start searches the current position in the background. When hold is set,
+// as for infinite searches and pondering, the best move is held back until
+// the search is stopped or, when pondering, until the GUI sends ponderhit.
+func (s *Server) start(l search.Limits, hold bool) *run {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &run{cancel: cancel, done: make(chan struct{}), held: make(chan struct{})}
+	if !hold {
+		close(r.held)
+	}
+	s.running = r
+	p := s.position
+	go func() {
+		defer close(r.done)
+		si, err := s.Engine.Search(ctx, p, l, s.info)
+		select {
+		case <-r.held:
+		case <-ctx.Done():
+		}
+		switch {
+		case err != nil:
+			s.send("info string " + err.Error())
+			s.send("bestmove 0000")
+		case si.Ponder != "":
+			s.send("bestmove " + si.BestMove + " ponder " + si.Ponder)
+		default:
+			s.send("bestmove " + si.BestMove)
+		}
+	}()
+	return r
+}
+
+// stop stops the running search, if there is one, and waits for it to send
+// its best move.
+func (s *Server) stop() {
+	if s.running == nil {
+		return
+	}
+	s.running.cancel()
+	<-s.running.done
+	s.running = nil
+}
+
+// ponderHit turns a search on the move the engine expected the opponent to
+// play into a search for real. The search goes on with what it found so far,
+// bounded from now on by the limits given with "go ponder".
+func (s *Server) ponderHit() {
+	r := s.running
+	if r == nil || r.hit == nil {
+		return
+	}
+	r.hit <- r.limits
+	r.hit = nil
+	close(r.held)
+}
+
+// infoKeys are the keys of a line of analysis in the order UCI sends them.
+// Any string goes last since it takes the rest of the line.
+var infoKeys = []string{"depth", "seldepth", "multipv", "score", "lowerbound", "upperbound", "nodes", "nps", "hashfull", "tbhits", "time", "currmove", "currmovenumber", "cpuload", "pv", "string"}
+
+// info sends a line of analysis to the GUI.
+func (s *Server) info(analysis map[string]string) {
+	line := "info"
+	for _, k := range infoKeys {
+		v, ok := analysis[k]
+		if !ok {
+			continue
+		}
+		line += " " + k
+		if v != "" {
+			line += " " + v
+		}
+	}
+	s.send(line)
+}
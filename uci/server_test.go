@@ -0,0 +1,195 @@
+package uci
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrewbackes/chess/engines"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/search"
+)
+
+// serve serves a search engine on conn until it is told to quit.
+func serve(conn net.Conn) {
+	defer conn.Close()
+	(&Server{Name: "test", Author: "someone", Engine: search.New(1)}).Serve(conn, conn)
+}
+
+func attach(t *testing.T) *engines.UCIEngine {
+	client, server := net.Pipe()
+	go serve(server)
+	e, err := engines.AttachUCIEngine("pipe", client, engines.Options{Init: []string{"setoption name Hash value 2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestBestMove(t *testing.T) {
+	e := attach(t)
+	defer e.Close()
+	if err := e.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+	g := game.NewTimedGame(map[piece.Color]game.TimeControl{
+		piece.White: game.NewTimeControl(2*time.Second, 40, 0, true),
+		piece.Black: game.NewTimeControl(2*time.Second, 40, 0, true),
+	})
+	g.MakeMove(move.Parse("e2e4"))
+	si, err := e.BestMove(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Position().LegalMoves()[move.Parse(si.BestMove)]; !ok {
+		t.Errorf("best move %q is not legal", si.BestMove)
+	}
+	if len(si.Analysis) == 0 || si.Analysis[0]["depth"] == "" {
+		t.Errorf("got analysis %v", si.Analysis)
+	}
+}
+
+func TestThink(t *testing.T) {
+	e := attach(t)
+	defer e.Close()
+	output, err := e.Think(position.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for line := range output {
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "info depth 1 ") {
+		t.Errorf("got %q", lines)
+	}
+}
+
+// gui talks to a server line by line.
+type gui struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Scanner
+}
+
+func newGUI(t *testing.T) *gui {
+	client, server := net.Pipe()
+	go serve(server)
+	return &gui{t: t, conn: client, r: bufio.NewScanner(client)}
+}
+
+func (g *gui) send(line string) {
+	if _, err := g.conn.Write([]byte(line + "\n")); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// until returns the lines the server sends up to and including the first one
+// starting with prefix.
+func (g *gui) until(prefix string) []string {
+	var lines []string
+	for g.r.Scan() {
+		lines = append(lines, g.r.Text())
+		if strings.HasPrefix(g.r.Text(), prefix) {
+			return lines
+		}
+	}
+	g.t.Fatalf("no %q in %q", prefix, lines)
+	return nil
+}
+
+func last(lines []string) string {
+	return lines[len(lines)-1]
+}
+
+func TestIdentify(t *testing.T) {
+	g := newGUI(t)
+	defer g.send("quit")
+	g.send("uci")
+	want := []string{
+		"id name test",
+		"id author someone",
+		"option name Hash type spin default 1 min 1 max 65536",
+		"option name Clear Hash type button",
+		"uciok",
+	}
+	if got := g.until("uciok"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	g.send("setoption name Hash value 4")
+	g.send("setoption name Clear Hash")
+	g.send("setoption name Threads value 2")
+	if got := g.until("info string"); last(got) != "info string search: unknown option" {
+		t.Errorf("got %q", got)
+	}
+	g.send("isready")
+	if got := g.until("readyok"); len(got) != 1 {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := newGUI(t)
+	defer g.send("quit")
+	g.send("position fen 6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1 moves g1f1 g8f8 f1e1 f8e8")
+	g.send("go depth 1")
+	if got := last(g.until("bestmove")); got == "bestmove 0000" {
+		t.Errorf("got %q", got)
+	}
+	g.send("position fen 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	g.send("go depth 2")
+	if got := last(g.until("bestmove")); got != "bestmove a1a8" {
+		t.Errorf("got %q", got)
+	}
+	g.send("position startpos moves e2e4 e7e5 e1e3")
+	if got := last(g.until("info string")); got != "info string illegal move e1e3" {
+		t.Errorf("got %q", got)
+	}
+	g.send("position fen R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1")
+	g.send("go depth 1")
+	if got := last(g.until("bestmove")); got != "bestmove 0000" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPonder(t *testing.T) {
+	g := newGUI(t)
+	defer g.send("quit")
+	g.send("position startpos moves e2e4 e7e5")
+	g.send("go ponder movetime 100")
+	time.Sleep(50 * time.Millisecond)
+	g.send("ponderhit")
+	lines := g.until("bestmove")
+	depth := 0
+	for _, line := range lines[:len(lines)-1] {
+		if !strings.HasPrefix(line, "info ") {
+			t.Errorf("got %q before the best move", line)
+		}
+		// The search goes on after ponderhit instead of starting over:
+		if words := strings.Fields(line); len(words) > 2 && words[1] == "depth" {
+			d, _ := strconv.Atoi(words[2])
+			if d <= depth {
+				t.Errorf("got depth %d after depth %d", d, depth)
+			}
+			depth = d
+		}
+	}
+
+	// A search that is stopped while pondering still sends its best move.
+	g.send("go ponder movetime 100")
+	time.Sleep(200 * time.Millisecond)
+	g.send("stop")
+	if got := strings.Fields(last(g.until("bestmove"))); len(got) < 2 || got[1] == "0000" {
+		t.Errorf("got %q", got)
+	}
+}